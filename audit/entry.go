@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+
+	// ActorSystem is recorded for transfers requested without a principal.
+	ActorSystem = "system"
+)
+
+// GenesisHash is the PrevHash of the first entry.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+type Request struct {
+	ID                 string `json:"id"`
+	SourceAddress      string `json:"source_address"`
+	DestinationAddress string `json:"destination_address"`
+	Amount             string `json:"amount"`
+	NetworkCurrencyID  string `json:"network_currency_id"`
+	QuoteID            string `json:"quote_id,omitempty"`
+	FeeTier            string `json:"fee_tier,omitempty"`
+	Mode               string `json:"mode,omitempty"`
+}
+
+// Entry is one record of the audit log. Hash covers every other field,
+// including PrevHash, so changing, removing or reordering entries breaks the
+// chain from that point on.
+type Entry struct {
+	Sequence        uint64                   `json:"sequence"`
+	Timestamp       time.Time                `json:"timestamp"`
+	Actor           string                   `json:"actor"`
+	ActorName       string                   `json:"actor_name,omitempty"`
+	Action          string                   `json:"action"`
+	Request         *Request                 `json:"request,omitempty"`
+	Call            *transaction.DecodedCall `json:"call,omitempty"`
+	ContractAddress string                   `json:"contract_address,omitempty"`
+	TransactionID   string                   `json:"transaction_id,omitempty"`
+	PayloadHash     string                   `json:"payload_hash,omitempty"`
+	Result          string                   `json:"result"`
+	Error           string                   `json:"error,omitempty"`
+	PrevHash        string                   `json:"prev_hash"`
+	Hash            string                   `json:"hash"`
+}
+
+// ComputeHash returns the hash of the entry over its JSON encoding with an
+// empty Hash field.
+func (e *Entry) ComputeHash() (string, error) {
+	unhashed := *e
+	unhashed.Hash = ""
+
+	content, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshall audit entry %d: %w", e.Sequence, err)
+	}
+
+	digest := sha256.Sum256(content)
+
+	return hex.EncodeToString(digest[:]), nil
+}
@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+var _ transaction.Auditor = (*Log)(nil)
+
+// Log appends hash-chained entries to a Store. A Log must be the only writer
+// of its store, as it keeps the head of the chain in memory.
+type Log struct {
+	store Store
+
+	mu   sync.Mutex
+	last *Entry
+}
+
+func NewLog(store Store) *Log {
+	return &Log{
+		store: store,
+	}
+}
+
+func (log *Log) Audit(ctx context.Context, record *transaction.AuditRecord) error {
+	entry := &Entry{
+		Timestamp: time.Now().UTC(),
+		Actor:     ActorSystem,
+		Action:    string(record.Action),
+		Result:    ResultOK,
+	}
+
+	if req := record.Req; req != nil {
+		entry.Request = &Request{
+			ID:                 req.ID,
+			SourceAddress:      req.SourceAddress,
+			DestinationAddress: req.DestinationAddress,
+			Amount:             req.Amount.String(),
+			NetworkCurrencyID:  req.NetworkCurrencyID,
+			QuoteID:            req.QuoteID,
+			FeeTier:            string(req.FeeTier),
+			Mode:               string(req.Mode),
+		}
+
+		if principal := req.Principal; principal != nil {
+			entry.Actor = principal.KeyID.String()
+			entry.ActorName = principal.Name
+		}
+	}
+
+	if payload := record.Payload; payload != nil {
+		entry.TransactionID = payload.ID
+		entry.Call = payload.Call
+		entry.ContractAddress = payload.ContractAddress
+		entry.PayloadHash = payloadHash(payload)
+	}
+
+	if record.Err != nil {
+		entry.Result = ResultError
+		entry.Error = record.Err.Error()
+	}
+
+	return log.Append(ctx, entry)
+}
+
+// Append links the entry to the head of the chain and stores it. Sequence,
+// PrevHash and Hash are set by Append.
+func (log *Log) Append(ctx context.Context, entry *Entry) error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.last == nil {
+		last, err := log.store.Last(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read audit log head: %w", err)
+		}
+
+		log.last = last
+	}
+
+	entry.Sequence = 1
+	entry.PrevHash = GenesisHash
+
+	if log.last != nil {
+		entry.Sequence = log.last.Sequence + 1
+		entry.PrevHash = log.last.Hash
+	}
+
+	hash, err := entry.ComputeHash()
+	if err != nil {
+
+		return err
+	}
+
+	entry.Hash = hash
+
+	if err := log.store.Append(ctx, entry); err != nil {
+		return fmt.Errorf("failed to append audit entry %d: %w", entry.Sequence, err)
+	}
+
+	log.last = entry
+
+	return nil
+}
+
+// payloadHash identifies the exact bytes that were signed or broadcast.
+func payloadHash(payload *transaction.TransferPayload) string {
+	content := payload.Signed
+	if content == nil {
+		content = payload.Raw
+	}
+
+	if content == nil {
+		return ""
+	}
+
+	digest := sha256.Sum256(content)
+
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,14 @@
+package audit
+
+import "context"
+
+// Store persists entries in sequence order. Implementations only ever append;
+// the chain is maintained by Log.
+type Store interface {
+	Append(ctx context.Context, entry *Entry) error
+	// Last returns the most recent entry, or nil when the store is empty.
+	Last(ctx context.Context) (*Entry, error)
+	// Walk calls fn with every entry in sequence order and stops at the first
+	// error fn returns.
+	Walk(ctx context.Context, fn func(*Entry) error) error
+}
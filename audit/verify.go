@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type ChainBrokenError struct {
+	Sequence uint64
+	Reason   string
+}
+
+func (e ChainBrokenError) Error() string {
+	return fmt.Sprintf("audit chain broken at entry %d: %s", e.Sequence, e.Reason)
+}
+
+type Report struct {
+	Entries  uint64 `json:"entries"`
+	LastHash string `json:"last_hash"`
+}
+
+// Verify walks the whole log and checks that every entry follows its
+// predecessor and that its hash matches its content. It returns a
+// ChainBrokenError for the first entry that does not, together with the
+// report of the entries before it. Dropping entries from the end keeps the
+// chain intact, so compare Report.LastHash with a previously recorded head to
+// detect truncation.
+func Verify(ctx context.Context, store Store) (*Report, error) {
+	report := &Report{LastHash: GenesisHash}
+
+	err := store.Walk(ctx, func(entry *Entry) error {
+		expectedSequence := report.Entries + 1
+
+		switch {
+		case entry.Sequence != expectedSequence:
+			return ChainBrokenError{
+				Sequence: expectedSequence,
+				Reason:   fmt.Sprintf("found sequence %d", entry.Sequence),
+			}
+		case entry.PrevHash != report.LastHash:
+			return ChainBrokenError{Sequence: entry.Sequence, Reason: "previous hash does not match"}
+		}
+
+		hash, err := entry.ComputeHash()
+		if err != nil {
+
+			return err
+		}
+
+		if hash != entry.Hash {
+			return ChainBrokenError{Sequence: entry.Sequence, Reason: "hash does not match content"}
+		}
+
+		report.Entries = entry.Sequence
+		report.LastHash = entry.Hash
+
+		return nil
+	})
+
+	var brokenErr ChainBrokenError
+	if errors.As(err, &brokenErr) {
+		return report, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify audit log: %w", err)
+	}
+
+	return report, nil
+}
+
+// Export writes every entry as one line of JSON.
+func Export(ctx context.Context, store Store, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+
+	err := store.Walk(ctx, func(entry *Entry) error {
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export audit log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const (
+	DefaultMaxClockSkew = 5 * time.Minute
+
+	// maxSignedBodySize bounds the body read for signature verification.
+	maxSignedBodySize = 1 << 20
+
+	queryAccessToken = "access_token"
+)
+
+type UnauthenticatedError struct {
+	Reason string
+}
+
+func (e UnauthenticatedError) Error() string {
+	return "unauthenticated: " + e.Reason
+}
+
+type RevokedKeyError struct {
+	KeyID uuid.UUID
+}
+
+func (e RevokedKeyError) Error() string {
+	return fmt.Sprintf("api key %s is revoked", e.KeyID)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *domain.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal, or nil when the
+// request was not authenticated.
+func PrincipalFromContext(ctx context.Context) *domain.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*domain.Principal)
+
+	return principal
+}
+
+// Authenticator accepts either a bearer token ("Authorization: Bearer
+// <key id>.<secret>") or an HMAC signed request (HeaderKeyID, HeaderTimestamp,
+// HeaderNonce and HeaderSignature, see Sign). The nonce of a signed request is
+// accepted once per key.
+type Authenticator struct {
+	repo   domain.APIKeyRepo
+	cipher *KeyCipher
+	nonces *nonceCache
+
+	MaxClockSkew time.Duration
+}
+
+// NewAuthenticator returns an authenticator whose signed requests are checked
+// with the signing keys in repo, decrypted by cipher.
+func NewAuthenticator(repo domain.APIKeyRepo, cipher *KeyCipher) *Authenticator {
+	return &Authenticator{
+		repo:         repo,
+		cipher:       cipher,
+		nonces:       newNonceCache(),
+		MaxClockSkew: DefaultMaxClockSkew,
+	}
+}
+
+// Require authenticates the request and checks that the key grants scope
+// before calling next with the principal attached to the request context.
+func (authenticator *Authenticator) Require(scope domain.Scope, next http.Handler) http.HandlerFunc {
+	return authenticator.require(scope, false, next)
+}
+
+// RequireStream is Require for endpoints consumed by EventSource, which
+// cannot set headers: the token may also be passed as the "access_token"
+// query parameter.
+func (authenticator *Authenticator) RequireStream(scope domain.Scope, next http.Handler) http.HandlerFunc {
+	return authenticator.require(scope, true, next)
+}
+
+func (authenticator *Authenticator) require(scope domain.Scope, allowQuery bool, next http.Handler) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		principal, err := authenticator.Authenticate(req, allowQuery)
+
+		var unauthenticatedErr UnauthenticatedError
+		if err != nil && !errors.As(err, &unauthenticatedErr) {
+			slog.Log(req.Context(), slog.LevelError, "failed to authenticate request:", "err", err)
+			http.Error(resp, "failed to authenticate request", http.StatusInternalServerError)
+
+			return
+		}
+
+		if err != nil {
+			resp.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			http.Error(resp, err.Error(), http.StatusUnauthorized)
+
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			http.Error(resp, domain.PermissionDeniedError{
+				KeyID:  principal.KeyID,
+				Reason: "use scope " + string(scope),
+			}.Error(), http.StatusForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(resp, req.WithContext(WithPrincipal(req.Context(), principal)))
+	}
+}
+
+func (authenticator *Authenticator) Authenticate(req *http.Request, allowQuery bool) (*domain.Principal, error) {
+	if req.Header.Get(HeaderSignature) != "" {
+		return authenticator.authenticateSignature(req)
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get(HeaderAuthorization), bearerPrefix)
+	if !ok && allowQuery {
+		token = req.URL.Query().Get(queryAccessToken)
+	}
+
+	if token == "" {
+		return nil, UnauthenticatedError{Reason: "missing credentials"}
+	}
+
+	keyID, secret, err := ParseToken(token)
+	if err != nil {
+		return nil, UnauthenticatedError{Reason: err.Error()}
+	}
+
+	key, err := authenticator.activeKey(req.Context(), keyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(HashSecret(secret), key.SecretHash) != 1 {
+		return nil, UnauthenticatedError{Reason: "invalid api key"}
+	}
+
+	return key.Principal(), nil
+}
+
+func (authenticator *Authenticator) authenticateSignature(req *http.Request) (*domain.Principal, error) {
+	keyID, err := uuid.Parse(req.Header.Get(HeaderKeyID))
+	if err != nil {
+		return nil, UnauthenticatedError{Reason: "invalid " + HeaderKeyID}
+	}
+
+	timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return nil, UnauthenticatedError{Reason: "invalid " + HeaderTimestamp}
+	}
+
+	now := time.Now()
+
+	skew := now.Sub(time.Unix(timestamp, 0)).Abs()
+	if skew > authenticator.MaxClockSkew {
+		return nil, UnauthenticatedError{Reason: "request timestamp outside the allowed clock skew"}
+	}
+
+	nonce := req.Header.Get(HeaderNonce)
+	if nonce == "" || len(nonce) > maxNonceLength {
+		return nil, UnauthenticatedError{Reason: "invalid " + HeaderNonce}
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, UnauthenticatedError{Reason: err.Error()}
+	}
+
+	key, err := authenticator.activeKey(req.Context(), keyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	if len(key.SigningKey) == 0 {
+		return nil, UnauthenticatedError{Reason: "api key has no signing key; rotate it to sign requests"}
+	}
+
+	signingKey, err := authenticator.cipher.Open(keyID, key.SigningKey)
+	if err != nil {
+
+		return nil, err
+	}
+
+	signature := req.Header.Get(HeaderSignature)
+	if !Verify(signingKey, timestamp, nonce, req.Method, req.URL.RequestURI(), body, signature) {
+		return nil, UnauthenticatedError{Reason: "invalid signature"}
+	}
+
+	// the nonce is kept while the timestamp is still accepted
+	expiry := time.Unix(timestamp, 0).Add(authenticator.MaxClockSkew)
+	if !authenticator.nonces.add(keyID, nonce, expiry, now) {
+		return nil, UnauthenticatedError{Reason: "request nonce already used"}
+	}
+
+	return key.Principal(), nil
+}
+
+func (authenticator *Authenticator) activeKey(ctx context.Context, keyID uuid.UUID) (*domain.APIKey, error) {
+	key, err := authenticator.repo.GetAPIKey(ctx, keyID)
+
+	var notFoundErr domain.APIKeyNotFoundError
+	if errors.As(err, &notFoundErr) {
+		// the same answer as a wrong secret, so that key IDs cannot be probed
+		return nil, UnauthenticatedError{Reason: "invalid api key"}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve api key (%s): %w", keyID, err)
+	}
+
+	if key.Revoked() {
+		return nil, UnauthenticatedError{Reason: RevokedKeyError{KeyID: keyID}.Error()}
+	}
+
+	return key, nil
+}
+
+// readBody reads the body for verification and puts it back for the handler.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxSignedBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	if len(body) > maxSignedBodySize {
+		return nil, errors.New("request body too large")
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MasterKeySize is the size of the AES-256 key that encrypts signing keys.
+const MasterKeySize = 32
+
+// KeyCipher encrypts the signing keys of API keys at rest with AES-256-GCM
+// under a master key kept out of the store, so that reading the stored keys
+// does not allow forging signed requests. The key ID is authenticated along
+// with each signing key, so a stored signing key cannot be moved to another
+// key.
+type KeyCipher struct {
+	aead cipher.AEAD
+}
+
+func NewKeyCipher(masterKey []byte) (*KeyCipher, error) {
+	if len(masterKey) != MasterKeySize {
+		return nil, fmt.Errorf("master key of %d bytes, want %d", len(masterKey), MasterKeySize)
+	}
+
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return &KeyCipher{
+		aead: aead,
+	}, nil
+}
+
+// Seal encrypts the signing key of keyID. The result starts with its nonce.
+func (keyCipher *KeyCipher) Seal(keyID uuid.UUID, signingKey []byte) []byte {
+	nonce := make([]byte, keyCipher.aead.NonceSize())
+
+	// crypto/rand.Read never fails on supported platforms
+	_, _ = rand.Read(nonce)
+
+	return keyCipher.aead.Seal(nonce, nonce, signingKey, keyID[:])
+}
+
+// Open decrypts a signing key sealed for keyID.
+func (keyCipher *KeyCipher) Open(keyID uuid.UUID, sealed []byte) ([]byte, error) {
+	nonceSize := keyCipher.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("sealed signing key too short")
+	}
+
+	signingKey, err := keyCipher.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], keyID[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing key of api key %s: %w", keyID, err)
+	}
+
+	return signingKey, nil
+}
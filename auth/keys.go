@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+// KeyService creates, rotates and revokes API keys. Secrets are returned to
+// the caller exactly once, inside the token; only their hashes and the
+// signing keys derived from them, encrypted, are stored.
+type KeyService struct {
+	repo   domain.APIKeyRepo
+	cipher *KeyCipher
+}
+
+// NewKeyService returns a key service that encrypts signing keys with cipher.
+// Without a cipher, the keys it issues accept bearer tokens only.
+func NewKeyService(repo domain.APIKeyRepo, cipher *KeyCipher) *KeyService {
+	return &KeyService{
+		repo:   repo,
+		cipher: cipher,
+	}
+}
+
+// Create stores a new key and returns it with its token. An empty
+// CreateAPIKeyPayload.SecretHash gets a freshly generated secret; otherwise
+// the caller already holds the token, the payload carries its SigningKey too,
+// and an empty token is returned.
+func (service *KeyService) Create(
+	ctx context.Context,
+	cakp *domain.CreateAPIKeyPayload,
+) (*domain.APIKey, string, error) {
+	if len(cakp.Scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+
+	for _, scope := range cakp.Scopes {
+		if _, err := domain.ParseScope(string(scope)); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var secret string
+
+	payload := *cakp
+	if len(payload.SecretHash) == 0 {
+		if payload.ID == uuid.Nil {
+			// the signing key is sealed for the ID of its key
+			payload.ID = uuid.Must(uuid.NewV7())
+		}
+
+		secret = GenerateSecret()
+		payload.SecretHash = HashSecret(secret)
+		payload.SigningKey = service.SealSigningKey(payload.ID, secret)
+	}
+
+	key, err := service.repo.CreateAPIKey(ctx, &payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	if secret == "" {
+		return key, "", nil
+	}
+
+	return key, FormatToken(key.ID, secret), nil
+}
+
+// Rotate replaces the secret of a key, invalidating its previous token.
+func (service *KeyService) Rotate(ctx context.Context, keyID uuid.UUID) (*domain.APIKey, string, error) {
+	key, err := service.repo.GetAPIKey(ctx, keyID)
+	if err != nil {
+
+		return nil, "", err
+	}
+
+	if key.Revoked() {
+		return nil, "", RevokedKeyError{KeyID: keyID}
+	}
+
+	secret := GenerateSecret()
+
+	key, err = service.repo.RotateAPIKey(ctx, keyID, HashSecret(secret), service.SealSigningKey(keyID, secret))
+	if err != nil {
+
+		return nil, "", err
+	}
+
+	return key, FormatToken(key.ID, secret), nil
+}
+
+// SealSigningKey returns the signing key derived from secret as stored for
+// keyID: encrypted, or nil without a cipher.
+func (service *KeyService) SealSigningKey(keyID uuid.UUID, secret string) []byte {
+	if service.cipher == nil {
+		return nil
+	}
+
+	return service.cipher.Seal(keyID, SigningKey(secret))
+}
+
+// HoldsSecret reports whether key stores the hash of secret and, with a
+// cipher, the signing key derived from it.
+func (service *KeyService) HoldsSecret(key *domain.APIKey, secret string) bool {
+	if subtle.ConstantTimeCompare(key.SecretHash, HashSecret(secret)) != 1 {
+		return false
+	}
+
+	if service.cipher == nil {
+		return true
+	}
+
+	signingKey, err := service.cipher.Open(key.ID, key.SigningKey)
+
+	return err == nil && hmac.Equal(signingKey, SigningKey(secret))
+}
+
+func (service *KeyService) Get(ctx context.Context, keyID uuid.UUID) (*domain.APIKey, error) {
+	return service.repo.GetAPIKey(ctx, keyID)
+}
+
+// Revoke permanently disables a key. Revoking a revoked key is a no-op.
+func (service *KeyService) Revoke(ctx context.Context, keyID uuid.UUID) (*domain.APIKey, error) {
+	return service.repo.RevokeAPIKey(ctx, keyID)
+}
+
+func (service *KeyService) List(ctx context.Context) ([]*domain.APIKey, error) {
+	return service.repo.GetAPIKeys(ctx)
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxNonceLength bounds the HeaderNonce kept for each signed request.
+const maxNonceLength = 128
+
+type nonceKey struct {
+	keyID uuid.UUID
+	nonce string
+}
+
+// nonceCache remembers the nonces of the signed requests of each key until
+// their timestamps fall outside the allowed clock skew, after which the
+// requests are rejected anyway. It is held in memory, so several instances
+// behind one load balancer each accept a nonce once.
+type nonceCache struct {
+	mutex sync.Mutex
+	seen  map[nonceKey]time.Time
+	// sweepAt is when the expired nonces are dropped next.
+	sweepAt time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{
+		seen: make(map[nonceKey]time.Time),
+	}
+}
+
+// add records the nonce of keyID until expiry and reports whether it was not
+// seen before.
+func (cache *nonceCache) add(keyID uuid.UUID, nonce string, expiry time.Time, now time.Time) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if now.After(cache.sweepAt) {
+		for key, keyExpiry := range cache.seen {
+			if now.After(keyExpiry) {
+				delete(cache.seen, key)
+			}
+		}
+
+		cache.sweepAt = now.Add(time.Minute)
+	}
+
+	key := nonceKey{keyID: keyID, nonce: nonce}
+	if keyExpiry, ok := cache.seen[key]; ok && !now.After(keyExpiry) {
+		return false
+	}
+
+	cache.seen[key] = expiry
+
+	return true
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderKeyID         = "X-Api-Key-Id"
+	HeaderTimestamp     = "X-Api-Timestamp"
+	HeaderNonce         = "X-Api-Nonce"
+	HeaderSignature     = "X-Api-Signature"
+
+	bearerPrefix    = "Bearer "
+	signaturePrefix = "sha256="
+
+	// signingKeyLabel separates the signing key from the stored secret hash,
+	// which is derived from the same secret.
+	signingKeyLabel = "demo-blockchain api key signing"
+)
+
+// SigningKey derives the HMAC key that signs requests from the secret of an
+// API key. Clients derive it the same way. The server keeps it encrypted at
+// rest (see KeyCipher).
+func SigningKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingKeyLabel))
+
+	return mac.Sum(nil)
+}
+
+// Sign computes the HeaderSignature of a request. The timestamp and the nonce
+// are signed so that a captured request cannot be replayed: the nonce is
+// accepted once, and the timestamp bounds how long it has to be remembered.
+func Sign(
+	signingKey []byte,
+	timestamp int64,
+	nonce string,
+	method string,
+	requestURI string,
+	body []byte,
+) string {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write(signedContent(timestamp, nonce, method, requestURI, body))
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(
+	signingKey []byte,
+	timestamp int64,
+	nonce string,
+	method string,
+	requestURI string,
+	body []byte,
+	signature string,
+) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+
+	expected := Sign(signingKey, timestamp, nonce, method, requestURI, body)
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func signedContent(timestamp int64, nonce string, method string, requestURI string, body []byte) []byte {
+	bodyDigest := sha256.Sum256(body)
+
+	return []byte(strings.Join([]string{
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+		method,
+		requestURI,
+		hex.EncodeToString(bodyDigest[:]),
+	}, "\n"))
+}
@@ -1,6 +1,7 @@
 package blockchain
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -19,3 +20,154 @@ type TransactionError struct {
 func (e TransactionError) Error() string {
 	return e.Message
 }
+
+// RetryableError is implemented by errors that know whether repeating the
+// failed operation, possibly after rebuilding the transaction, can succeed.
+type RetryableError interface {
+	error
+	Retryable() bool
+}
+
+// IsRetryable reports whether err, or any error it wraps, is retryable.
+func IsRetryable(err error) bool {
+	var retryableErr RetryableError
+	if errors.As(err, &retryableErr) {
+		return retryableErr.Retryable()
+	}
+
+	return false
+}
+
+// NonceTooLowError means the nonce was already used; rebuilding with a fresh nonce can succeed.
+type NonceTooLowError struct {
+	Err error
+}
+
+func (e NonceTooLowError) Error() string {
+	return "nonce too low: " + e.Err.Error()
+}
+
+func (e NonceTooLowError) Unwrap() error {
+	return e.Err
+}
+
+func (e NonceTooLowError) Retryable() bool {
+	return true
+}
+
+// ReplacementUnderpricedError means a transaction with the same nonce is pending
+// and the new one does not bump its fees enough.
+type ReplacementUnderpricedError struct {
+	Err error
+}
+
+func (e ReplacementUnderpricedError) Error() string {
+	return "replacement underpriced: " + e.Err.Error()
+}
+
+func (e ReplacementUnderpricedError) Unwrap() error {
+	return e.Err
+}
+
+func (e ReplacementUnderpricedError) Retryable() bool {
+	return true
+}
+
+type InsufficientFundsError struct {
+	Err error
+}
+
+func (e InsufficientFundsError) Error() string {
+	return "insufficient funds: " + e.Err.Error()
+}
+
+func (e InsufficientFundsError) Unwrap() error {
+	return e.Err
+}
+
+func (e InsufficientFundsError) Retryable() bool {
+	return false
+}
+
+type IntrinsicGasTooLowError struct {
+	Err error
+}
+
+func (e IntrinsicGasTooLowError) Error() string {
+	return "intrinsic gas too low: " + e.Err.Error()
+}
+
+func (e IntrinsicGasTooLowError) Unwrap() error {
+	return e.Err
+}
+
+func (e IntrinsicGasTooLowError) Retryable() bool {
+	return false
+}
+
+// FeeCapTooLowError means the fee cap is below the current base fee; rebuilding
+// with freshly estimated fees can succeed.
+type FeeCapTooLowError struct {
+	Err error
+}
+
+func (e FeeCapTooLowError) Error() string {
+	return "fee cap below base fee: " + e.Err.Error()
+}
+
+func (e FeeCapTooLowError) Unwrap() error {
+	return e.Err
+}
+
+func (e FeeCapTooLowError) Retryable() bool {
+	return true
+}
+
+// AlreadyKnownError means the node already has the exact signed transaction,
+// so the broadcast has effectively succeeded.
+type AlreadyKnownError struct {
+	Err error
+}
+
+func (e AlreadyKnownError) Error() string {
+	return "transaction already known: " + e.Err.Error()
+}
+
+func (e AlreadyKnownError) Unwrap() error {
+	return e.Err
+}
+
+func (e AlreadyKnownError) Retryable() bool {
+	return false
+}
+
+// RevertedError means the transaction was included in a block, using its
+// nonce and paying its fee, but its execution failed.
+type RevertedError struct {
+	ID string
+}
+
+func (e RevertedError) Error() string {
+	return "transaction reverted: " + e.ID
+}
+
+func (e RevertedError) Retryable() bool {
+	return false
+}
+
+// TransientNetworkError means the node could not be reached or was temporarily unavailable.
+type TransientNetworkError struct {
+	Err error
+}
+
+func (e TransientNetworkError) Error() string {
+	return "transient network error: " + e.Err.Error()
+}
+
+func (e TransientNetworkError) Unwrap() error {
+	return e.Err
+}
+
+func (e TransientNetworkError) Retryable() bool {
+	return true
+}
@@ -0,0 +1,66 @@
+package transaction
+
+import (
+	"context"
+	"log/slog"
+)
+
+type AuditAction string
+
+const (
+	AuditRequested  AuditAction = "requested"
+	AuditAuthorized AuditAction = "authorized"
+	AuditRejected   AuditAction = "rejected"
+	AuditBuilt      AuditAction = "built"
+	AuditSigned     AuditAction = "signed"
+	AuditBroadcast  AuditAction = "broadcast"
+	AuditMined      AuditAction = "mined"
+	AuditFailed     AuditAction = "failed"
+)
+
+// AuditRecord describes one step of a transfer. Payload is nil for steps
+// taken before a transaction was built.
+type AuditRecord struct {
+	Action  AuditAction
+	Req     *TransferRequest
+	Payload *TransferPayload
+	Err     error
+}
+
+// Auditor durably records the steps of every transfer. Steps that precede a
+// fund-moving action are recorded before the action and abort the transfer
+// when they cannot be recorded, so that nothing is signed or broadcast
+// without a trace.
+type Auditor interface {
+	Audit(ctx context.Context, record *AuditRecord) error
+}
+
+func audit(
+	ctx context.Context,
+	auditor Auditor,
+	action AuditAction,
+	param *TransferRequest,
+	payload *TransferPayload,
+	err error,
+) error {
+	if auditor == nil {
+		return nil
+	}
+
+	return auditor.Audit(ctx, &AuditRecord{Action: action, Req: param, Payload: payload, Err: err})
+}
+
+// auditOutcome records a step that has already happened, where failing to
+// record it must not change the result reported to the caller.
+func auditOutcome(
+	ctx context.Context,
+	auditor Auditor,
+	action AuditAction,
+	param *TransferRequest,
+	payload *TransferPayload,
+	err error,
+) {
+	if errA := audit(ctx, auditor, action, param, payload, err); errA != nil {
+		slog.Log(ctx, slog.LevelError, "failed to record audit entry:", "action", action, "err", errA)
+	}
+}
@@ -0,0 +1,138 @@
+package transaction
+
+import (
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+// ContractCallRequest calls a contract method from a managed address.
+type ContractCallRequest struct {
+	ID              string
+	SourceAddress   string
+	NetworkCode     string
+	ContractAddress string
+	// ABI describes the contract, or at least Method, in the JSON format of
+	// the chain. It is also used to decode raw Data for the audit log.
+	ABI    json.RawMessage
+	Method string
+	// Args are the arguments of Method as decoded from JSON. Integers should
+	// be strings, as JSON numbers lose precision beyond 2^53.
+	Args []any
+	// Data is encoded calldata, sent instead of Method and Args.
+	Data []byte
+	// Value is sent along with the call in the native currency of the network.
+	Value   decimal.Decimal
+	QuoteID string
+	FeeTier FeeTier
+	// Principal is the caller that requested the call, nil for internal
+	// callers.
+	Principal *domain.Principal
+}
+
+// ContractDeployRequest deploys a contract from a managed address.
+type ContractDeployRequest struct {
+	ID            string
+	SourceAddress string
+	NetworkCode   string
+	// Bytecode is the creation code of the contract.
+	Bytecode []byte
+	// ABI describes the constructor in the JSON format of the chain. It may be
+	// empty if the constructor takes no arguments.
+	ABI json.RawMessage
+	// Args are the constructor arguments, in the same form as those of
+	// ContractCallRequest.
+	Args []any
+	// Value is sent to the constructor in the native currency of the network.
+	Value   decimal.Decimal
+	QuoteID string
+	FeeTier FeeTier
+	// Principal is the caller that requested the deployment, nil for internal
+	// callers.
+	Principal *domain.Principal
+}
+
+// ContractCall is the call a transfer makes instead of moving its amount to
+// the destination address.
+type ContractCall struct {
+	ABI    json.RawMessage
+	Method string
+	Args   []any
+	Data   []byte
+}
+
+// ContractDeployment is the contract a transfer creates. The transfer has no
+// destination address and its amount is sent to the constructor.
+type ContractDeployment struct {
+	Bytecode []byte
+	ABI      json.RawMessage
+	Args     []any
+}
+
+// DecodedCall is the method and arguments of built calldata, recorded for
+// auditing.
+type DecodedCall struct {
+	Method    string       `json:"method"`
+	Signature string       `json:"signature,omitempty"`
+	Args      []DecodedArg `json:"args,omitempty"`
+}
+
+type DecodedArg struct {
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// TransferRequest returns the transfer that makes the call: the value moves
+// to the contract in the native currency, together with the call.
+func (req *ContractCallRequest) TransferRequest() (*TransferRequest, error) {
+	network, err := domain.GetNetwork(req.NetworkCode)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &TransferRequest{
+		ID:                 req.ID,
+		SourceAddress:      req.SourceAddress,
+		DestinationAddress: req.ContractAddress,
+		Amount:             req.Value,
+		NetworkCurrencyID:  network.NativeToken,
+		QuoteID:            req.QuoteID,
+		FeeTier:            req.FeeTier,
+		Call: &ContractCall{
+			ABI:    req.ABI,
+			Method: req.Method,
+			Args:   req.Args,
+			Data:   req.Data,
+		},
+		Principal: req.Principal,
+	}, nil
+}
+
+// TransferRequest returns the transfer that deploys the contract: the value
+// moves to the new contract in the native currency.
+func (req *ContractDeployRequest) TransferRequest() (*TransferRequest, error) {
+	network, err := domain.GetNetwork(req.NetworkCode)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &TransferRequest{
+		ID:                req.ID,
+		SourceAddress:     req.SourceAddress,
+		Amount:            req.Value,
+		NetworkCurrencyID: network.NativeToken,
+		QuoteID:           req.QuoteID,
+		FeeTier:           req.FeeTier,
+		Deploy: &ContractDeployment{
+			Bytecode: req.Bytecode,
+			ABI:      req.ABI,
+			Args:     req.Args,
+		},
+		Principal: req.Principal,
+	}, nil
+}
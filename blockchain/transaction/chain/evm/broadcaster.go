@@ -2,9 +2,16 @@ package evm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/telemetry"
 )
 
 type TransactionBroadcaster struct {
@@ -23,15 +30,162 @@ func (broadcaster *TransactionBroadcaster) Broadcast(
 	ctx context.Context,
 	payload *transaction.TransferPayload,
 ) error {
+	ctx, span := telemetry.Start(ctx, "evm.broadcast", transaction.TransferAttributes(payload.Req)...)
+	defer span.End()
+
+	span.SetAttributes(telemetry.String("transaction.hash", payload.ID))
+
 	txn, err := Unmarshal(payload.Signed)
 	if err != nil {
+		span.RecordError(err)
+
 		return err
 	}
 
-	err = broadcaster.client.Delegate.SendTransaction(ctx, txn)
+	err = callNoResult(ctx, broadcaster.client, "eth_sendRawTransaction", func(ctx context.Context) error {
+		return broadcaster.client.Delegate.SendTransaction(ctx, txn)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to broadcast transaction (%s): %w", payload.ID, err)
+		span.RecordError(err)
+
+		return fmt.Errorf("failed to broadcast transaction (%s): %w", payload.ID, ClassifyError(err))
 	}
 
 	return nil
 }
+
+// IsKnown reports whether the node has the signed transaction of the payload,
+// either pending or mined.
+func (broadcaster *TransactionBroadcaster) IsKnown(
+	ctx context.Context,
+	payload *transaction.TransferPayload,
+) (bool, error) {
+	txn, err := Unmarshal(payload.Signed)
+	if err != nil {
+		return false, err
+	}
+
+	_, _, err = broadcaster.transactionByHash(ctx, txn)
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to retrieve transaction (%s): %w", payload.ID, ClassifyError(err))
+	}
+
+	return true, nil
+}
+
+var _ transaction.OutboxChecker = (*TransactionBroadcaster)(nil)
+
+// Status reports whether the transaction of the payload is pending or
+// included, and then whether it succeeded, or whether it is unseen or
+// superseded when the node does not have it.
+func (broadcaster *TransactionBroadcaster) Status(
+	ctx context.Context,
+	payload *transaction.TransferPayload,
+) (transaction.OutboxStatus, error) {
+	txn, err := Unmarshal(payload.Signed)
+	if err != nil {
+		return "", err
+	}
+
+	_, pending, err := broadcaster.transactionByHash(ctx, txn)
+
+	switch {
+	case err == nil && pending:
+		return transaction.OutboxStatusPending, nil
+	case err == nil:
+		receipt, err := call(ctx, broadcaster.client, "eth_getTransactionReceipt",
+			func(ctx context.Context) (*types.Receipt, error) {
+				return broadcaster.client.Delegate.TransactionReceipt(ctx, txn.Hash())
+			})
+		if err != nil {
+			return "", fmt.Errorf("failed to retrieve receipt (%s): %w", payload.ID, ClassifyError(err))
+		}
+
+		broadcaster.recordReceipt(payload, txn, receipt)
+
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			return transaction.OutboxStatusReverted, nil
+		}
+
+		return transaction.OutboxStatusMined, nil
+	case !errors.Is(err, ethereum.NotFound):
+		return "", fmt.Errorf("failed to retrieve transaction (%s): %w", payload.ID, ClassifyError(err))
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(txn.ChainId()), txn)
+	if err != nil {
+		return "", fmt.Errorf("failed to recover sender of transaction (%s): %w", payload.ID, err)
+	}
+
+	nonce, err := call(ctx, broadcaster.client, "eth_getTransactionCount", func(ctx context.Context) (uint64, error) {
+		return broadcaster.client.Delegate.NonceAt(ctx, from, nil)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve nonce for address (%s): %w", from.Hex(), ClassifyError(err))
+	}
+
+	if nonce > txn.Nonce() {
+		return transaction.OutboxStatusSuperseded, nil
+	}
+
+	return transaction.OutboxStatusUnseen, nil
+}
+
+func (broadcaster *TransactionBroadcaster) transactionByHash(
+	ctx context.Context,
+	txn *types.Transaction,
+) (*types.Transaction, bool, error) {
+	var pending bool
+
+	lookup := func(ctx context.Context) (*types.Transaction, error) {
+		found, isPending, err := broadcaster.client.Delegate.TransactionByHash(ctx, txn.Hash())
+		pending = isPending
+
+		return found, err
+	}
+
+	found, err := call(ctx, broadcaster.client, "eth_getTransactionByHash", lookup)
+
+	return found, pending, err
+}
+
+// recordReceipt observes the fee a mined transaction paid and sets the address
+// of the contract a deployment created on the payload. The Rebroadcaster asks
+// for the status of each entry until it is included, so every transaction
+// that went through the outbox is observed once.
+func (broadcaster *TransactionBroadcaster) recordReceipt(
+	payload *transaction.TransferPayload,
+	txn *types.Transaction,
+	receipt *types.Receipt,
+) {
+	networkCurrency, err := domain.NewNetworkCurrency(payload.Req.NetworkCurrencyID)
+	if err != nil {
+		return
+	}
+
+	nativeCurrency, err := nativeNetworkCurrency(networkCurrency.Network.Code)
+	if err != nil {
+		return
+	}
+
+	if txn.To() == nil {
+		// a reverted deployment creates no contract
+		payload.ContractAddress = ""
+
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			payload.ContractAddress = receipt.ContractAddress.Hex()
+		}
+	}
+
+	if receipt.EffectiveGasPrice == nil {
+		return
+	}
+
+	fee := new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+
+	feePaid.Observe(ToDecimal(fee, nativeCurrency.Scale).InexactFloat64(), networkCurrency.Network.Code)
+}
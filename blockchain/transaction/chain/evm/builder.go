@@ -8,11 +8,12 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/shopspring/decimal"
+	"github.com/ethereum/go-ethereum/crypto"
 	"golang.org/x/crypto/sha3"
 
 	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
 	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/telemetry"
 )
 
 const (
@@ -23,6 +24,12 @@ const (
 
 type TransactionBuilder struct {
 	client *Client
+	// Relayer sends and pays for relayed transfers. Without Relayer and
+	// Authorizer only direct transfers are built.
+	Relayer string
+	// Authorizer signs the permits and authorizations of relayed transfers
+	// with the key of their source address.
+	Authorizer transaction.MessageSigner
 }
 
 var _ transaction.Builder = (*TransactionBuilder)(nil)
@@ -37,8 +44,41 @@ func (builder *TransactionBuilder) Build(
 	ctx context.Context,
 	params *transaction.TransferRequest,
 ) (*transaction.TransferPayload, error) {
+	ctx, span := telemetry.Start(ctx, "evm.build", transaction.TransferAttributes(params)...)
+	defer span.End()
+
+	switch mode := params.TransferMode(); {
+	case mode.Relayed() && params.Call != nil:
+		err := transaction.TransferModeNotSupportedError{Mode: mode, Reason: "contract calls cannot be relayed"}
+		span.RecordError(err)
+
+		return nil, err
+	case mode.Relayed() && params.Deploy != nil:
+		err := transaction.TransferModeNotSupportedError{Mode: mode, Reason: "deployments cannot be relayed"}
+		span.RecordError(err)
+
+		return nil, err
+	case mode.Relayed() && params.NFT != nil:
+		err := transaction.TransferModeNotSupportedError{Mode: mode, Reason: "NFT transfers cannot be relayed"}
+		span.RecordError(err)
+
+		return nil, err
+	case mode.Relayed():
+		payload, err := builder.buildRelayed(ctx, params)
+		span.RecordError(err)
+
+		return payload, err
+	case mode != transaction.TransferModeDirect:
+		err := transaction.TransferModeNotSupportedError{Mode: mode, Reason: "unknown mode"}
+		span.RecordError(err)
+
+		return nil, err
+	}
+
 	txData, err := builder.build(ctx, params)
 	if err != nil {
+		span.RecordError(err)
+
 		return nil, err
 	}
 
@@ -49,10 +89,42 @@ func (builder *TransactionBuilder) Build(
 		return nil, err
 	}
 
-	return &transaction.TransferPayload{
+	payload := &transaction.TransferPayload{
 		Req: params,
 		Raw: bytes,
-	}, nil
+	}
+
+	switch {
+	case params.Call != nil:
+		payload.Call, err = decodeCall(params.Call, txData.Data)
+		if err != nil {
+
+			return nil, err
+		}
+	case params.Deploy != nil:
+		payload.Call, err = decodeDeployment(params.Deploy, txData.Data)
+		if err != nil {
+
+			return nil, err
+		}
+
+		from := common.HexToAddress(params.SourceAddress)
+		payload.ContractAddress = crypto.CreateAddress(from, txData.Nonce).Hex()
+	case params.NFT != nil:
+		definition, _, err := nftDefinition(params.NFT.Asset.Standard)
+		if err != nil {
+
+			return nil, err
+		}
+
+		payload.Call, err = decodeCall(&transaction.ContractCall{ABI: []byte(definition)}, txData.Data)
+		if err != nil {
+
+			return nil, err
+		}
+	}
+
+	return payload, nil
 }
 
 func (builder *TransactionBuilder) build(
@@ -62,22 +134,28 @@ func (builder *TransactionBuilder) build(
 	fromAddr := common.HexToAddress(param.SourceAddress)
 	toAddr := common.HexToAddress(param.DestinationAddress)
 
-	chainID, err := builder.client.Delegate.ChainID(ctx)
+	client := builder.client
+
+	chainID, err := call(ctx, client, "eth_chainId", client.Delegate.ChainID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve chain ID: %w", err)
+		return nil, fmt.Errorf("failed to retrieve chain ID: %w", ClassifyError(err))
 	}
 
-	nonce, err := builder.client.Delegate.PendingNonceAt(ctx, fromAddr)
+	nonce, err := call(ctx, client, "eth_getTransactionCount", func(ctx context.Context) (uint64, error) {
+		return client.Delegate.PendingNonceAt(ctx, fromAddr)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve nonce for address (%s): %w", param.SourceAddress, err)
+		return nil, fmt.Errorf("failed to retrieve nonce for address (%s): %w", param.SourceAddress, ClassifyError(err))
 	}
 
-	gasPrice, err := builder.client.Delegate.SuggestGasPrice(ctx)
+	gasTipCap, gasFeeCap, err := builder.fees(ctx, param)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve gas price: %w", err)
+
+		return nil, err
 	}
 
-	var txToAddr common.Address
+	// txToAddr stays nil for deployments
+	var txToAddr *common.Address
 
 	var transferAmount *big.Int
 
@@ -91,54 +169,275 @@ func (builder *TransactionBuilder) build(
 		return nil, err
 	}
 
-	convertedAmount := param.Amount.Mul(decimal.NewFromInt(Base10).
-		Pow(decimal.NewFromInt(int64(networkCurrency.Scale)))).BigInt()
+	convertedAmount := ToBaseUnits(param.Amount, networkCurrency.Scale)
+
+	switch {
+	case param.Call != nil:
+		if param.NetworkCurrencyID != networkCurrency.Network.NativeToken {
+			return nil, fmt.Errorf(
+				"contract calls send %s, not %s", networkCurrency.Network.NativeToken, param.NetworkCurrencyID,
+			)
+		}
 
-	if param.NetworkCurrencyID == networkCurrency.Network.NativeToken {
-		txToAddr = toAddr
+		txToAddr = &toAddr
 		transferAmount = convertedAmount
 
-		gasLimit = uint64(EthGasLimit)
-	} else {
-		txToAddr = common.HexToAddress(networkCurrency.Address)
-		transferAmount = big.NewInt(0)
+		data, err = EncodeCall(param.Call)
+		if err != nil {
+
+			return nil, err
+		}
+
+		if param.Fee == nil {
+			gasLimit, err = builder.estimateCall(ctx, fromAddr, txToAddr, transferAmount, data)
+			if err != nil {
 
-		transferFnSignature := []byte("transfer(address,uint256)")
-		hash := sha3.NewLegacyKeccak256()
-		hash.Write(transferFnSignature)
-		methodID := hash.Sum(nil)[:4]
+				return nil, err
+			}
+		}
+	case param.Deploy != nil:
+		switch {
+		case param.NetworkCurrencyID != networkCurrency.Network.NativeToken:
+			return nil, fmt.Errorf(
+				"deployments send %s, not %s", networkCurrency.Network.NativeToken, param.NetworkCurrencyID,
+			)
+		case param.DestinationAddress != "":
+			return nil, fmt.Errorf("deployments have no destination address, got %s", param.DestinationAddress)
+		}
 
-		paddedAddress := common.LeftPadBytes(toAddr.Bytes(), PaddingSize)
-		paddedAmount := common.LeftPadBytes(convertedAmount.Bytes(), PaddingSize)
+		transferAmount = convertedAmount
 
-		data = append(data, methodID...)
-		data = append(data, paddedAddress...)
-		data = append(data, paddedAmount...)
+		data, err = EncodeDeployment(param.Deploy)
+		if err != nil {
 
-		estimatedGas, err2 := builder.client.Delegate.EstimateGas(ctx, ethereum.CallMsg{
-			From: fromAddr,
-			To:   &txToAddr,
-			Data: data,
-		})
+			return nil, err
+		}
+
+		if param.Fee == nil {
+			gasLimit, err = builder.estimateCall(ctx, fromAddr, nil, transferAmount, data)
+			if err != nil {
 
-		if err2 != nil {
+				return nil, err
+			}
+		}
+	case param.NFT != nil:
+		switch {
+		case param.NetworkCurrencyID != networkCurrency.Network.NativeToken:
+			return nil, fmt.Errorf(
+				"NFT transfers pay fees in %s, not %s", networkCurrency.Network.NativeToken, param.NetworkCurrencyID,
+			)
+		case param.NFT.Asset.NetworkCode != networkCurrency.Network.Code:
 			return nil, fmt.Errorf(
-				"failed to estimate gas for currency(%s), from(%s) and to(%s): %w",
-				param.NetworkCurrencyID, param.SourceAddress, txToAddr, err2,
+				"%s is on network %s, not %s", &param.NFT.Asset, param.NFT.Asset.NetworkCode, networkCurrency.Network.Code,
 			)
+		case !param.Amount.IsZero():
+			return nil, fmt.Errorf("NFT transfers move no %s, got %s", param.NetworkCurrencyID, param.Amount)
+		}
+
+		owner := common.HexToAddress(param.NFT.OwnerAddress(param))
+		nftContract := common.HexToAddress(param.NFT.Asset.Contract)
+
+		txToAddr = &nftContract
+		transferAmount = big.NewInt(0)
+
+		data, err = EncodeNFTTransfer(param.NFT, owner, toAddr)
+		if err != nil {
+
+			return nil, err
+		}
+
+		if err := builder.checkNFT(ctx, param.NFT, owner, fromAddr); err != nil {
+			return nil, err
+		}
+
+		if param.Fee == nil {
+			gasLimit, err = builder.estimateCall(ctx, fromAddr, txToAddr, transferAmount, data)
+			if err != nil {
+
+				return nil, err
+			}
 		}
+	case param.NetworkCurrencyID == networkCurrency.Network.NativeToken:
+		txToAddr = &toAddr
+		transferAmount = convertedAmount
 
-		gasLimit = estimatedGas
+		gasLimit = uint64(EthGasLimit)
+	default:
+		tokenAddr := common.HexToAddress(networkCurrency.Address)
+		txToAddr = &tokenAddr
+		transferAmount = big.NewInt(0)
+
+		data = encodeTokenTransfer(toAddr, convertedAmount)
+
+		if param.Fee == nil {
+			estimatedGas, err2 := call(ctx, client, "eth_estimateGas", func(ctx context.Context) (uint64, error) {
+				return client.Delegate.EstimateGas(ctx, ethereum.CallMsg{
+					From: fromAddr,
+					To:   txToAddr,
+					Data: data,
+				})
+			})
+
+			if err2 != nil {
+				return nil, fmt.Errorf(
+					"failed to estimate gas for currency(%s), from(%s) and to(%s): %w",
+					param.NetworkCurrencyID, param.SourceAddress, txToAddr, ClassifyError(err2),
+				)
+			}
+
+			gasLimit = estimatedGas
+		}
+	}
+
+	if param.Fee != nil {
+		gasLimit = param.Fee.GasLimit
 	}
 
 	return &types.DynamicFeeTx{
 		ChainID:   chainID,
 		Nonce:     nonce,
-		To:        &txToAddr,
+		To:        txToAddr,
 		Value:     transferAmount,
 		Gas:       gasLimit,
-		GasTipCap: gasPrice,
-		GasFeeCap: gasPrice,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
 		Data:      data,
 	}, nil
 }
+
+// encodeTokenTransfer returns the calldata of an ERC-20 transfer.
+func encodeTokenTransfer(toAddr common.Address, amount *big.Int) []byte {
+	transferFnSignature := []byte("transfer(address,uint256)")
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(transferFnSignature)
+	methodID := hash.Sum(nil)[:4]
+
+	paddedAddress := common.LeftPadBytes(toAddr.Bytes(), PaddingSize)
+	paddedAmount := common.LeftPadBytes(amount.Bytes(), PaddingSize)
+
+	var data []byte
+
+	data = append(data, methodID...)
+	data = append(data, paddedAddress...)
+	data = append(data, paddedAmount...)
+
+	return data
+}
+
+// accountCall returns the call that a smart account makes to transfer: a
+// native transfer, an ERC-20 transfer, a contract call or an NFT transfer
+// with the account as operator.
+func (builder *TransactionBuilder) accountCall(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+	networkCurrency *domain.NetworkCurrency,
+	account common.Address,
+) (common.Address, *big.Int, []byte, error) {
+	toAddr := common.HexToAddress(param.DestinationAddress)
+	amount := ToBaseUnits(param.Amount, networkCurrency.Scale)
+
+	switch {
+	case param.Call != nil:
+		if param.NetworkCurrencyID != networkCurrency.Network.NativeToken {
+			return common.Address{}, nil, nil, fmt.Errorf(
+				"contract calls send %s, not %s", networkCurrency.Network.NativeToken, param.NetworkCurrencyID,
+			)
+		}
+
+		data, err := EncodeCall(param.Call)
+		if err != nil {
+
+			return common.Address{}, nil, nil, err
+		}
+
+		return toAddr, amount, data, nil
+	case param.NFT != nil:
+		if param.NFT.Asset.NetworkCode != networkCurrency.Network.Code || !param.Amount.IsZero() {
+			return common.Address{}, nil, nil, fmt.Errorf(
+				"%s cannot be transferred with %s %s", &param.NFT.Asset, param.Amount, param.NetworkCurrencyID,
+			)
+		}
+
+		owner := common.HexToAddress(param.NFT.OwnerAddress(param))
+
+		data, err := EncodeNFTTransfer(param.NFT, owner, toAddr)
+		if err != nil {
+
+			return common.Address{}, nil, nil, err
+		}
+
+		if err := builder.checkNFT(ctx, param.NFT, owner, account); err != nil {
+			return common.Address{}, nil, nil, err
+		}
+
+		return common.HexToAddress(param.NFT.Asset.Contract), big.NewInt(0), data, nil
+	case param.NetworkCurrencyID != networkCurrency.Network.NativeToken:
+		return common.HexToAddress(networkCurrency.Address), big.NewInt(0), encodeTokenTransfer(toAddr, amount), nil
+	}
+
+	return toAddr, amount, nil, nil
+}
+
+func (builder *TransactionBuilder) estimateCall(
+	ctx context.Context,
+	fromAddr common.Address,
+	toAddr *common.Address,
+	value *big.Int,
+	data []byte,
+) (uint64, error) {
+	client := builder.client
+
+	gasLimit, err := call(ctx, client, "eth_estimateGas", func(ctx context.Context) (uint64, error) {
+		return client.Delegate.EstimateGas(ctx, ethereum.CallMsg{
+			From:  fromAddr,
+			To:    toAddr,
+			Value: value,
+			Data:  data,
+		})
+	})
+	if err != nil {
+		if toAddr == nil {
+			return 0, fmt.Errorf("failed to estimate gas for deployment from (%s): %w", fromAddr, ClassifyError(err))
+		}
+
+		return 0, fmt.Errorf(
+			"failed to estimate gas for call of (%s) from (%s): %w", toAddr, fromAddr, ClassifyError(err),
+		)
+	}
+
+	return gasLimit, nil
+}
+
+// fees returns the tip and fee caps of the transaction, either pinned by a
+// quote or suggested by the node. The suggested cap leaves room for twice the
+// latest base fee on top of the tip, so that the transaction stays valid while
+// the base fee rises over a few full blocks.
+func (builder *TransactionBuilder) fees(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*big.Int, *big.Int, error) {
+	if param.Fee != nil {
+		return param.Fee.MaxPriorityFeePerGas, param.Fee.MaxFeePerGas, nil
+	}
+
+	gasTipCap, err := call(ctx, builder.client, "eth_maxPriorityFeePerGas", builder.client.Delegate.SuggestGasTipCap)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve priority fee: %w", ClassifyError(err))
+	}
+
+	header, err := call(ctx, builder.client, "eth_getBlockByNumber", func(ctx context.Context) (*types.Header, error) {
+		return builder.client.Delegate.HeaderByNumber(ctx, nil)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve base fee: %w", ClassifyError(err))
+	}
+
+	baseFee := new(big.Int)
+	if header.BaseFee != nil {
+		baseFee.Set(header.BaseFee)
+	}
+
+	gasFeeCap := new(big.Int).Add(baseFee.Mul(baseFee, big.NewInt(2)), gasTipCap)
+
+	return gasTipCap, gasFeeCap, nil
+}
@@ -0,0 +1,142 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// UserOperationGas is the gas of a user operation estimated by a bundler.
+type UserOperationGas struct {
+	PreVerificationGas   *hexutil.Big `json:"preVerificationGas"`
+	VerificationGasLimit *hexutil.Big `json:"verificationGasLimit"`
+	CallGasLimit         *hexutil.Big `json:"callGasLimit"`
+}
+
+// UserOperationReceipt is the outcome of a user operation included in a
+// bundle transaction.
+type UserOperationReceipt struct {
+	UserOpHash    common.Hash    `json:"userOpHash"`
+	Sender        common.Address `json:"sender"`
+	Nonce         *hexutil.Big   `json:"nonce"`
+	Paymaster     common.Address `json:"paymaster"`
+	ActualGasCost *hexutil.Big   `json:"actualGasCost"`
+	ActualGasUsed *hexutil.Big   `json:"actualGasUsed"`
+	// Success is false when the call of the account reverted. The operation
+	// is included, and its nonce used, either way.
+	Success bool   `json:"success"`
+	Reason  string `json:"reason"`
+	Receipt struct {
+		TransactionHash common.Hash  `json:"transactionHash"`
+		BlockNumber     *hexutil.Big `json:"blockNumber"`
+	} `json:"receipt"`
+}
+
+// Bundler is a client of the JSON-RPC API of an ERC-4337 bundler, which
+// submits user operations to the EntryPoint in bundle transactions.
+type Bundler struct {
+	rpc *rpc.Client
+	// Endpoint identifies the bundler in metrics and spans without exposing
+	// credentials embedded in its URL.
+	Endpoint string
+}
+
+func NewBundler(ctx context.Context, url string) (*Bundler, error) {
+	clnt, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundler client (%s): %w", url, err)
+	}
+
+	return &Bundler{
+		rpc:      clnt,
+		Endpoint: endpointLabel(url),
+	}, nil
+}
+
+func (bundler *Bundler) Close() {
+	bundler.rpc.Close()
+}
+
+// EstimateUserOperationGas estimates the gas of an operation, which must carry
+// a signature of the right length.
+func (bundler *Bundler) EstimateUserOperationGas(
+	ctx context.Context,
+	op *UserOperation,
+	entryPoint common.Address,
+) (*UserOperationGas, error) {
+	gas, err := callEndpoint(ctx, bundler.Endpoint, "eth_estimateUserOperationGas",
+		func(ctx context.Context) (*UserOperationGas, error) {
+			var gas *UserOperationGas
+			err := bundler.rpc.CallContext(ctx, &gas, "eth_estimateUserOperationGas", op, entryPoint)
+
+			return gas, err
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas of user operation of (%s): %w", op.Sender.Hex(), ClassifyError(err))
+	}
+
+	if gas == nil || gas.PreVerificationGas == nil || gas.VerificationGasLimit == nil || gas.CallGasLimit == nil {
+		return nil, fmt.Errorf("bundler returned incomplete gas estimate for user operation of (%s)", op.Sender.Hex())
+	}
+
+	return gas, nil
+}
+
+// SendUserOperation submits a signed operation and returns its hash.
+func (bundler *Bundler) SendUserOperation(
+	ctx context.Context,
+	op *UserOperation,
+	entryPoint common.Address,
+) (common.Hash, error) {
+	hash, err := callEndpoint(ctx, bundler.Endpoint, "eth_sendUserOperation",
+		func(ctx context.Context) (common.Hash, error) {
+			var hash common.Hash
+			err := bundler.rpc.CallContext(ctx, &hash, "eth_sendUserOperation", op, entryPoint)
+
+			return hash, err
+		})
+	if err != nil {
+
+		return common.Hash{}, ClassifyError(err)
+	}
+
+	return hash, nil
+}
+
+// UserOperationReceipt returns the receipt of an operation, or nil while it is
+// not included.
+func (bundler *Bundler) UserOperationReceipt(ctx context.Context, hash common.Hash) (*UserOperationReceipt, error) {
+	receipt, err := callEndpoint(ctx, bundler.Endpoint, "eth_getUserOperationReceipt",
+		func(ctx context.Context) (*UserOperationReceipt, error) {
+			var receipt *UserOperationReceipt
+			err := bundler.rpc.CallContext(ctx, &receipt, "eth_getUserOperationReceipt", hash)
+
+			return receipt, err
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user operation receipt (%s): %w", hash.Hex(), ClassifyError(err))
+	}
+
+	return receipt, nil
+}
+
+// HasUserOperation reports whether the bundler knows an operation, either in
+// its mempool or included.
+func (bundler *Bundler) HasUserOperation(ctx context.Context, hash common.Hash) (bool, error) {
+	found, err := callEndpoint(ctx, bundler.Endpoint, "eth_getUserOperationByHash",
+		func(ctx context.Context) (json.RawMessage, error) {
+			var found json.RawMessage
+			err := bundler.rpc.CallContext(ctx, &found, "eth_getUserOperationByHash", hash)
+
+			return found, err
+		})
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve user operation (%s): %w", hash.Hex(), ClassifyError(err))
+	}
+
+	return len(found) > 0 && string(found) != "null", nil
+}
@@ -0,0 +1,313 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+// entryPointOperation is a UserOperation in the form bind packs as the tuple
+// of handleOps.
+type entryPointOperation struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+// testBundler stands in for a bundler: it estimates fixed gas and sends every
+// operation at once in its own handleOps transaction, which it mines.
+type testBundler struct {
+	chain      *testChain
+	entryPoint *contract
+	address    common.Address
+	// beneficiary sends the bundles.
+	beneficiary *bind.TransactOpts
+
+	mutex    sync.Mutex
+	receipts map[common.Hash]*evm.UserOperationReceipt
+}
+
+func (bundler *testBundler) EstimateUserOperationGas(
+	_ *evm.UserOperation,
+	_ common.Address,
+) (*evm.UserOperationGas, error) {
+	return &evm.UserOperationGas{
+		PreVerificationGas:   (*hexutil.Big)(big.NewInt(50_000)),
+		VerificationGasLimit: (*hexutil.Big)(big.NewInt(100_000)),
+		CallGasLimit:         (*hexutil.Big)(big.NewInt(100_000)),
+	}, nil
+}
+
+func (bundler *testBundler) SendUserOperation(op *evm.UserOperation, entryPoint common.Address) (common.Hash, error) {
+	if entryPoint != bundler.address {
+		return common.Hash{}, errors.New("unsupported entry point")
+	}
+
+	bundler.mutex.Lock()
+	defer bundler.mutex.Unlock()
+
+	bound := bind.NewBoundContract(bundler.address, bundler.entryPoint.ABI, nil, bundler.chain.backend.Client(), nil)
+
+	txn, err := bound.Transact(bundler.beneficiary, "handleOps",
+		[]entryPointOperation{{
+			Sender:               op.Sender,
+			Nonce:                op.Nonce.ToInt(),
+			InitCode:             op.InitCode,
+			CallData:             op.CallData,
+			CallGasLimit:         op.CallGasLimit.ToInt(),
+			VerificationGasLimit: op.VerificationGasLimit.ToInt(),
+			PreVerificationGas:   op.PreVerificationGas.ToInt(),
+			MaxFeePerGas:         op.MaxFeePerGas.ToInt(),
+			MaxPriorityFeePerGas: op.MaxPriorityFeePerGas.ToInt(),
+			PaymasterAndData:     op.PaymasterAndData,
+			Signature:            op.Signature,
+		}}, bundler.beneficiary.From)
+	if err != nil {
+
+		return common.Hash{}, err
+	}
+
+	bundler.chain.backend.Commit()
+
+	receipt, err := bundler.chain.backend.Client().TransactionReceipt(context.Background(), txn.Hash())
+	if err != nil {
+
+		return common.Hash{}, err
+	}
+
+	return bundler.record(receipt)
+}
+
+// record keeps the operation receipt of the UserOperationEvent of a bundle.
+func (bundler *testBundler) record(receipt *types.Receipt) (common.Hash, error) {
+	event := bundler.entryPoint.ABI.Events["UserOperationEvent"]
+
+	for _, log := range receipt.Logs {
+		if len(log.Topics) != 4 || log.Topics[0] != event.ID {
+			continue
+		}
+
+		values, err := event.Inputs.NonIndexed().Unpack(log.Data)
+		if err != nil {
+
+			return common.Hash{}, err
+		}
+
+		opReceipt := &evm.UserOperationReceipt{
+			UserOpHash:    log.Topics[1],
+			Sender:        common.BytesToAddress(log.Topics[2].Bytes()),
+			Paymaster:     common.BytesToAddress(log.Topics[3].Bytes()),
+			Nonce:         (*hexutil.Big)(values[0].(*big.Int)),
+			Success:       values[1].(bool),
+			ActualGasCost: (*hexutil.Big)(values[2].(*big.Int)),
+			ActualGasUsed: (*hexutil.Big)(values[3].(*big.Int)),
+		}
+		opReceipt.Receipt.TransactionHash = receipt.TxHash
+		opReceipt.Receipt.BlockNumber = (*hexutil.Big)(receipt.BlockNumber)
+
+		bundler.receipts[opReceipt.UserOpHash] = opReceipt
+
+		return opReceipt.UserOpHash, nil
+	}
+
+	return common.Hash{}, errors.New("bundle emitted no UserOperationEvent")
+}
+
+func (bundler *testBundler) GetUserOperationReceipt(hash common.Hash) (*evm.UserOperationReceipt, error) {
+	bundler.mutex.Lock()
+	defer bundler.mutex.Unlock()
+
+	return bundler.receipts[hash], nil
+}
+
+func (bundler *testBundler) GetUserOperationByHash(hash common.Hash) (*common.Hash, error) {
+	bundler.mutex.Lock()
+	defer bundler.mutex.Unlock()
+
+	if bundler.receipts[hash] == nil {
+		return nil, nil
+	}
+
+	return &hash, nil
+}
+
+// newBundlerTest deploys an EntryPoint and an account of the first account
+// holding 10 ETH, and serves a bundler for them. The second account sends
+// the bundles, the third receives.
+func newBundlerTest(t *testing.T) (*testChain, *evm.Bundler, common.Address, common.Address, []common.Address) {
+	chain, accounts := newTestChain(t, 3)
+
+	bundler := &testBundler{
+		chain:       chain,
+		entryPoint:  loadContract(t, "TestEntryPoint"),
+		beneficiary: chain.transactOpts(t, accounts[1]),
+		receipts:    make(map[common.Hash]*evm.UserOperationReceipt),
+	}
+	bundler.address = chain.deploy(t, accounts[1], bundler.entryPoint)
+
+	account := loadContract(t, "TestAccount")
+	accountAddress := chain.deploy(t, accounts[0], account, accounts[0], bundler.address)
+
+	opts := chain.transactOpts(t, accounts[0])
+	opts.Value = evm.ToBaseUnits(decimal.NewFromInt(10), 18)
+
+	bound := bind.NewBoundContract(accountAddress, account.ABI, nil, chain.backend.Client(), nil)
+	if _, err := bound.Transfer(opts); err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	chain.backend.Commit()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", bundler); err != nil {
+		t.Fatalf("RegisterName: %v", err)
+	}
+
+	httpServer := httptest.NewServer(server)
+
+	client, err := evm.NewBundler(context.Background(), httpServer.URL)
+	if err != nil {
+		t.Fatalf("NewBundler: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		httpServer.Close()
+		server.Stop()
+	})
+
+	return chain, client, bundler.address, accountAddress, accounts
+}
+
+func TestUserOperationTransfer(t *testing.T) {
+	chain, bundler, entryPoint, account, accounts := newBundlerTest(t)
+	owner, recipient := accounts[0], accounts[2]
+
+	ownerSigner := evm.NewKeyStoreTransactionSigner(chain.keyStore)
+	broadcaster := evm.NewUserOperationBroadcaster(chain.client, bundler)
+	transferor := transaction.NewGenericTransferor(
+		evm.NewUserOperationBuilder(chain.client, bundler, entryPoint.Hex()),
+		evm.NewUserOperationSigner(ownerSigner),
+		broadcaster,
+	)
+
+	before, err := chain.backend.Client().BalanceAt(context.Background(), recipient, nil)
+	if err != nil {
+		t.Fatalf("BalanceAt: %v", err)
+	}
+
+	payload, err := transferor.Transfer(context.Background(), &transaction.TransferRequest{
+		SourceAddress:      account.Hex(),
+		DestinationAddress: recipient.Hex(),
+		Amount:             decimal.NewFromInt(1),
+		NetworkCurrencyID:  domain.TestETH,
+	})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	if payload.Sender != owner.Hex() {
+		t.Fatalf("user operation signed by %s, want the owner %s", payload.Sender, owner.Hex())
+	}
+
+	status, err := broadcaster.Status(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+
+	if status != transaction.OutboxStatusMined {
+		t.Fatalf("Status returned %s, want %s", status, transaction.OutboxStatusMined)
+	}
+
+	receipt, err := bundler.UserOperationReceipt(context.Background(), common.HexToHash(payload.ID))
+	if err != nil {
+		t.Fatalf("UserOperationReceipt: %v", err)
+	}
+
+	if !receipt.Success || receipt.Sender != account {
+		t.Fatalf("UserOperationReceipt returned %+v, want a successful operation of %s", receipt, account.Hex())
+	}
+
+	after, err := chain.backend.Client().BalanceAt(context.Background(), recipient, nil)
+	if err != nil {
+		t.Fatalf("BalanceAt: %v", err)
+	}
+
+	received := new(big.Int).Sub(after, before)
+	if want := evm.ToBaseUnits(decimal.NewFromInt(1), 18); received.Cmp(want) != 0 {
+		t.Fatalf("recipient received %s, want %s", received, want)
+	}
+
+	// the next operation takes the next nonce of the EntryPoint
+	payload, err = transferor.Transfer(context.Background(), &transaction.TransferRequest{
+		SourceAddress:      account.Hex(),
+		DestinationAddress: recipient.Hex(),
+		Amount:             decimal.NewFromInt(1),
+		NetworkCurrencyID:  domain.TestETH,
+	})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	receipt, err = bundler.UserOperationReceipt(context.Background(), common.HexToHash(payload.ID))
+	if err != nil {
+		t.Fatalf("UserOperationReceipt: %v", err)
+	}
+
+	if receipt == nil || receipt.Nonce.ToInt().Int64() != 1 {
+		t.Fatalf("UserOperationReceipt returned %+v, want the operation with nonce 1", receipt)
+	}
+}
+
+func TestUserOperationSignedByAnotherKeyIsRejected(t *testing.T) {
+	chain, bundler, entryPoint, account, accounts := newBundlerTest(t)
+
+	transferor := transaction.NewGenericTransferor(
+		evm.NewUserOperationBuilder(chain.client, bundler, entryPoint.Hex()),
+		evm.NewUserOperationSigner(evm.NewKeyStoreTransactionSigner(chain.keyStore)),
+		evm.NewUserOperationBroadcaster(chain.client, bundler),
+	)
+
+	payload, err := transferor.Builder.Build(context.Background(), &transaction.TransferRequest{
+		SourceAddress:      account.Hex(),
+		DestinationAddress: accounts[2].Hex(),
+		Amount:             decimal.NewFromInt(1),
+		NetworkCurrencyID:  domain.TestETH,
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	// the builder takes the owner from the account; sign with another key
+	payload.Sender = accounts[2].Hex()
+
+	if err := transferor.Signer.Sign(context.Background(), payload); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	if err := transferor.Broadcaster.Broadcast(context.Background(), payload); err == nil {
+		t.Fatal("Broadcast accepted an operation not signed by the owner")
+	}
+}
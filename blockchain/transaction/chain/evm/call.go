@@ -0,0 +1,297 @@
+package evm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+const methodIDSize = 4
+
+// EncodeCall returns the calldata of a contract call, either its raw Data or
+// Method packed with Args according to its ABI.
+func EncodeCall(call *transaction.ContractCall) ([]byte, error) {
+	if len(call.Data) > 0 {
+		return call.Data, nil
+	}
+
+	contractABI, err := parseCallABI(call)
+	if err != nil {
+
+		return nil, err
+	}
+
+	method, ok := contractABI.Methods[call.Method]
+	if !ok {
+		return nil, fmt.Errorf("method %s not found in ABI", call.Method)
+	}
+
+	args, err := convertArgs(method.Sig, method.Inputs, call.Args)
+	if err != nil {
+
+		return nil, err
+	}
+
+	data, err := contractABI.Pack(method.Name, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode call of %s: %w", method.Sig, err)
+	}
+
+	return data, nil
+}
+
+// decodeCall describes calldata with the ABI of the call. Without an ABI, or
+// for a method it does not know, only the method ID is known.
+func decodeCall(call *transaction.ContractCall, data []byte) (*transaction.DecodedCall, error) {
+	if len(data) < methodIDSize {
+		return &transaction.DecodedCall{Method: hexutil.Encode(data)}, nil
+	}
+
+	unknown := &transaction.DecodedCall{Method: hexutil.Encode(data[:methodIDSize])}
+
+	if len(call.ABI) == 0 {
+		return unknown, nil
+	}
+
+	contractABI, err := parseCallABI(call)
+	if err != nil {
+
+		return nil, err
+	}
+
+	// calldata of methods outside the ABI is still sent
+	method, errM := contractABI.MethodById(data[:methodIDSize])
+	if errM != nil {
+		return unknown, nil
+	}
+
+	values, err := method.Inputs.Unpack(data[methodIDSize:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode arguments of %s: %w", method.Sig, err)
+	}
+
+	return &transaction.DecodedCall{
+		Method:    method.Name,
+		Signature: method.Sig,
+		Args:      decodeArgs(method.Inputs, values),
+	}, nil
+}
+
+// convertArgs converts the JSON arguments of the method with signature sig.
+func convertArgs(sig string, inputs abi.Arguments, values []any) ([]any, error) {
+	if len(values) != len(inputs) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", sig, len(inputs), len(values))
+	}
+
+	args := make([]any, len(values))
+
+	for index, input := range inputs {
+		var err error
+
+		args[index], err = convertArg(input.Type, values[index])
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s) of %s: %w", index, input.Name, sig, err)
+		}
+	}
+
+	return args, nil
+}
+
+func decodeArgs(inputs abi.Arguments, values []any) []transaction.DecodedArg {
+	args := make([]transaction.DecodedArg, len(values))
+
+	for index, value := range values {
+		args[index] = transaction.DecodedArg{
+			Name:  inputs[index].Name,
+			Type:  inputs[index].Type.String(),
+			Value: formatArg(inputs[index].Type, value),
+		}
+	}
+
+	return args
+}
+
+func parseCallABI(call *transaction.ContractCall) (*abi.ABI, error) {
+	if len(call.ABI) == 0 {
+		return nil, fmt.Errorf("ABI is required to call method %s", call.Method)
+	}
+
+	contractABI, err := abi.JSON(bytes.NewReader(call.ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	return &contractABI, nil
+}
+
+// convertArg converts a JSON value to the Go type that abi.Pack expects for
+// typ. Tuples and function types are not supported.
+func convertArg(typ abi.Type, value any) (any, error) {
+	switch typ.T {
+	case abi.AddressTy:
+		text, ok := value.(string)
+		if !ok || !common.IsHexAddress(text) {
+			return nil, fmt.Errorf("expected an address, got %v", value)
+		}
+
+		return common.HexToAddress(text), nil
+	case abi.IntTy, abi.UintTy:
+		return convertInteger(typ, value)
+	case abi.BoolTy:
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a bool, got %v", value)
+		}
+
+		return flag, nil
+	case abi.StringTy:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %v", value)
+		}
+
+		return text, nil
+	case abi.BytesTy:
+		return decodeHexArg(value)
+	case abi.FixedBytesTy:
+		content, err := decodeHexArg(value)
+		if err != nil {
+
+			return nil, err
+		}
+
+		if len(content) != typ.Size {
+			return nil, fmt.Errorf("expected %d bytes, got %d", typ.Size, len(content))
+		}
+
+		array := reflect.New(typ.GetType()).Elem()
+		reflect.Copy(array, reflect.ValueOf(content))
+
+		return array.Interface(), nil
+	case abi.SliceTy, abi.ArrayTy:
+		return convertList(typ, value)
+	}
+
+	return nil, fmt.Errorf("unsupported argument type %s", typ)
+}
+
+func convertInteger(typ abi.Type, value any) (any, error) {
+	integer := new(big.Int)
+
+	switch number := value.(type) {
+	case string:
+		if _, ok := integer.SetString(number, 0); !ok {
+			return nil, fmt.Errorf("expected an integer, got %q", number)
+		}
+	case float64:
+		if number != float64(int64(number)) {
+			return nil, fmt.Errorf("expected an integer, got %v", number)
+		}
+
+		integer.SetInt64(int64(number))
+	case json.Number:
+		if _, ok := integer.SetString(number.String(), 10); !ok {
+			return nil, fmt.Errorf("expected an integer, got %s", number)
+		}
+	default:
+		return nil, fmt.Errorf("expected an integer, got %v", value)
+	}
+
+	switch {
+	case typ.T == abi.UintTy && integer.Sign() < 0:
+		return nil, fmt.Errorf("%s cannot be negative", integer)
+	case typ.T == abi.UintTy && integer.BitLen() > typ.Size,
+		typ.T == abi.IntTy && integer.BitLen() >= typ.Size:
+		return nil, fmt.Errorf("%s overflows %s", integer, typ)
+	}
+
+	goType := typ.GetType()
+	if goType == reflect.TypeOf(integer) {
+		return integer, nil
+	}
+
+	if typ.T == abi.UintTy {
+		return reflect.ValueOf(integer.Uint64()).Convert(goType).Interface(), nil
+	}
+
+	return reflect.ValueOf(integer.Int64()).Convert(goType).Interface(), nil
+}
+
+func convertList(typ abi.Type, value any) (any, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %v", value)
+	}
+
+	var list reflect.Value
+
+	if typ.T == abi.ArrayTy {
+		if len(items) != typ.Size {
+			return nil, fmt.Errorf("expected %d items, got %d", typ.Size, len(items))
+		}
+
+		list = reflect.New(typ.GetType()).Elem()
+	} else {
+		list = reflect.MakeSlice(typ.GetType(), len(items), len(items))
+	}
+
+	for index, item := range items {
+		converted, err := convertArg(*typ.Elem, item)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", index, err)
+		}
+
+		list.Index(index).Set(reflect.ValueOf(converted))
+	}
+
+	return list.Interface(), nil
+}
+
+func decodeHexArg(value any) ([]byte, error) {
+	text, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a hex string, got %v", value)
+	}
+
+	content, err := hexutil.Decode(text)
+	if err != nil {
+		return nil, fmt.Errorf("expected a hex string: %w", err)
+	}
+
+	return content, nil
+}
+
+// formatArg renders a decoded argument of type typ for the audit log.
+func formatArg(typ abi.Type, value any) string {
+	reflected := reflect.ValueOf(value)
+
+	switch typ.T {
+	case abi.AddressTy:
+		return value.(common.Address).Hex()
+	case abi.BytesTy:
+		return hexutil.Encode(value.([]byte))
+	case abi.FixedBytesTy:
+		content := make([]byte, reflected.Len())
+		reflect.Copy(reflect.ValueOf(content), reflected)
+
+		return hexutil.Encode(content)
+	case abi.SliceTy, abi.ArrayTy:
+		items := make([]string, reflected.Len())
+		for index := range items {
+			items[index] = formatArg(*typ.Elem, reflected.Index(index).Interface())
+		}
+
+		return "[" + strings.Join(items, ",") + "]"
+	}
+
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,91 @@
+package evm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+// constructorMethod is the method name recorded for deployments.
+const constructorMethod = "constructor"
+
+// EncodeDeployment returns the data of a contract creation: the bytecode
+// followed by the constructor arguments packed according to the ABI.
+func EncodeDeployment(deploy *transaction.ContractDeployment) ([]byte, error) {
+	if len(deploy.Bytecode) == 0 {
+		return nil, fmt.Errorf("bytecode is required to deploy a contract")
+	}
+
+	inputs, err := parseConstructor(deploy)
+	if err != nil {
+
+		return nil, err
+	}
+
+	sig := constructorSignature(inputs)
+
+	args, err := convertArgs(sig, inputs, deploy.Args)
+	if err != nil {
+
+		return nil, err
+	}
+
+	packed, err := inputs.Pack(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments of %s: %w", sig, err)
+	}
+
+	return append(append([]byte{}, deploy.Bytecode...), packed...), nil
+}
+
+// decodeDeployment describes the constructor arguments that follow the
+// bytecode in data.
+func decodeDeployment(deploy *transaction.ContractDeployment, data []byte) (*transaction.DecodedCall, error) {
+	inputs, err := parseConstructor(deploy)
+	if err != nil {
+
+		return nil, err
+	}
+
+	sig := constructorSignature(inputs)
+
+	values, err := inputs.Unpack(data[len(deploy.Bytecode):])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode arguments of %s: %w", sig, err)
+	}
+
+	return &transaction.DecodedCall{
+		Method:    constructorMethod,
+		Signature: sig,
+		Args:      decodeArgs(inputs, values),
+	}, nil
+}
+
+// parseConstructor returns the constructor inputs of the ABI of the
+// deployment. A missing ABI or constructor takes no arguments.
+func parseConstructor(deploy *transaction.ContractDeployment) (abi.Arguments, error) {
+	if len(deploy.ABI) == 0 {
+		return nil, nil
+	}
+
+	contractABI, err := abi.JSON(bytes.NewReader(deploy.ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+
+	return contractABI.Constructor.Inputs, nil
+}
+
+func constructorSignature(inputs abi.Arguments) string {
+	types := make([]string, len(inputs))
+
+	for index, input := range inputs {
+		types[index] = input.Type.String()
+	}
+
+	return constructorMethod + "(" + strings.Join(types, ",") + ")"
+}
@@ -0,0 +1,181 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/repo"
+)
+
+func TestDeployAndCallContract(t *testing.T) {
+	chain, accounts := newTestChain(t, 2)
+	deployer, recipient := accounts[0], accounts[1]
+
+	token := loadContract(t, "PermitToken")
+
+	transferor := chain.transferor(evm.NewTransactionBuilder(chain.client))
+	broadcaster := evm.NewTransactionBroadcaster(chain.client)
+
+	payload, err := transferor.Transfer(context.Background(), &transaction.TransferRequest{
+		SourceAddress:     deployer.Hex(),
+		Amount:            decimal.Zero,
+		NetworkCurrencyID: domain.TestETH,
+		Deploy: &transaction.ContractDeployment{
+			Bytecode: token.Bytecode,
+			ABI:      token.JSON,
+			Args:     []any{"Deployed Token", "1000"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	predicted := payload.ContractAddress
+	if predicted == "" {
+		t.Fatal("Transfer did not predict the contract address")
+	}
+
+	if payload.Call == nil || payload.Call.Signature != "constructor(string,uint256)" {
+		t.Fatalf("Transfer recorded %+v, want the decoded constructor", payload.Call)
+	}
+
+	chain.mine(t, payload)
+
+	status, err := broadcaster.Status(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+
+	if status != transaction.OutboxStatusMined {
+		t.Fatalf("Status returned %s, want %s", status, transaction.OutboxStatusMined)
+	}
+
+	if payload.ContractAddress != predicted {
+		t.Fatalf("contract created at %s, predicted %s", payload.ContractAddress, predicted)
+	}
+
+	address := common.HexToAddress(payload.ContractAddress)
+
+	if name := chain.call(t, token, address, "name").(string); name != "Deployed Token" {
+		t.Fatalf("name of the deployed contract is %q", name)
+	}
+
+	payload, err = transferor.Transfer(context.Background(), &transaction.TransferRequest{
+		SourceAddress:      deployer.Hex(),
+		DestinationAddress: address.Hex(),
+		Amount:             decimal.Zero,
+		NetworkCurrencyID:  domain.TestETH,
+		Call: &transaction.ContractCall{
+			ABI:    token.JSON,
+			Method: "transfer",
+			Args:   []any{recipient.Hex(), "250"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	if payload.Call == nil || payload.Call.Signature != "transfer(address,uint256)" {
+		t.Fatalf("Transfer recorded %+v, want the decoded call", payload.Call)
+	}
+
+	chain.mine(t, payload)
+
+	if balance := chain.call(t, token, address, "balanceOf", recipient).(*big.Int); balance.Int64() != 250 {
+		t.Fatalf("recipient holds %s, want 250", balance)
+	}
+}
+
+// notifications collects the events of a transfer.
+type notifications struct {
+	events []*transaction.Event
+}
+
+func (collected *notifications) Notify(_ context.Context, event *transaction.Event) {
+	collected.events = append(collected.events, event)
+}
+
+func TestRevertedCallFails(t *testing.T) {
+	chain, accounts := newTestChain(t, 2)
+	deployer, holder := accounts[0], accounts[1]
+
+	token := loadContract(t, "PermitToken")
+	address := chain.deploy(t, deployer, token, "Token", big.NewInt(1000))
+
+	outbox, err := repo.NewFileOutbox(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileOutbox: %v", err)
+	}
+
+	broadcaster := evm.NewTransactionBroadcaster(chain.client)
+	transferor := chain.transferor(evm.NewTransactionBuilder(chain.client))
+	transferor.Outbox = outbox
+
+	// the pinned fee skips the estimate, which would fail, so the transfer of
+	// tokens the holder does not have is sent and reverts
+	payload, err := transferor.Transfer(context.Background(), &transaction.TransferRequest{
+		SourceAddress:      holder.Hex(),
+		DestinationAddress: address.Hex(),
+		Amount:             decimal.Zero,
+		NetworkCurrencyID:  domain.TestETH,
+		Fee: &transaction.PinnedFee{
+			GasLimit:             100_000,
+			MaxFeePerGas:         big.NewInt(params.GWei * 10),
+			MaxPriorityFeePerGas: big.NewInt(params.GWei),
+		},
+		Call: &transaction.ContractCall{
+			ABI:    token.JSON,
+			Method: "transfer",
+			Args:   []any{deployer.Hex(), "1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	chain.backend.Commit()
+
+	status, err := broadcaster.Status(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+
+	if status != transaction.OutboxStatusReverted {
+		t.Fatalf("Status returned %s, want %s", status, transaction.OutboxStatusReverted)
+	}
+
+	collected := &notifications{}
+
+	rebroadcaster := transaction.NewRebroadcaster(outbox, broadcaster, broadcaster, time.Minute)
+	rebroadcaster.Notifier = collected
+
+	if err := rebroadcaster.Sweep(context.Background()); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+
+	var revertedErr blockchain.RevertedError
+	if len(collected.events) != 1 || collected.events[0].Type != transaction.EventFailed ||
+		!errors.As(collected.events[0].Err, &revertedErr) {
+		t.Fatalf("Sweep notified %+v, want one failure", collected.events)
+	}
+
+	entries, err := outbox.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("outbox holds %d entries after the revert, want none", len(entries))
+	}
+}
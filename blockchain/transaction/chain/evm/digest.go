@@ -0,0 +1,131 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// DigestSigner signs 32 byte digests with keys that are never held whole,
+// e.g. keys shared among the parties of a threshold signing protocol or held
+// by an HSM. It returns the r and s values of the ECDSA signature, without a
+// recovery ID.
+type DigestSigner interface {
+	SignDigest(ctx context.Context, address common.Address, digest []byte) (*big.Int, *big.Int, error)
+}
+
+// DigestTransactionSigner signs transactions through a DigestSigner and
+// assembles the signed transaction, which is broadcast like any other.
+type DigestTransactionSigner struct {
+	digestSigner DigestSigner
+}
+
+var _ transaction.Signer = (*DigestTransactionSigner)(nil)
+
+func NewDigestTransactionSigner(digestSigner DigestSigner) *DigestTransactionSigner {
+	return &DigestTransactionSigner{
+		digestSigner: digestSigner,
+	}
+}
+
+func (signer *DigestTransactionSigner) Sign(
+	ctx context.Context,
+	payload *transaction.TransferPayload,
+) error {
+	ctx, span := startSignSpan(ctx, payload)
+	defer span.End()
+
+	err := signer.sign(ctx, payload)
+	span.RecordError(err)
+
+	return err
+}
+
+func (signer *DigestTransactionSigner) sign(ctx context.Context, payload *transaction.TransferPayload) error {
+	txn, err := Unmarshal(payload.Raw)
+	if err != nil {
+
+		return err
+	}
+
+	londonSigner := types.NewLondonSigner(txn.ChainId())
+	hash := londonSigner.Hash(txn)
+	address := common.HexToAddress(payload.SenderAddress())
+
+	r, s, err := signer.digestSigner.SignDigest(ctx, address, hash.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to sign transaction as %s: %w", address.Hex(), err)
+	}
+
+	signature, err := RecoverableSignature(hash.Bytes(), r, s, address)
+	if err != nil {
+
+		return err
+	}
+
+	signedTx, err := txn.WithSignature(londonSigner, signature)
+	if err != nil {
+		return fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	signedTxBytes, err := Marshal(signedTx)
+	if err != nil {
+
+		return err
+	}
+
+	if payload.ID == "" {
+		payload.ID = signedTx.Hash().Hex()
+	}
+
+	payload.Signed = signedTxBytes
+
+	return nil
+}
+
+// RecoverableSignature turns r and s into the 65 byte signature that
+// transactions carry: s is normalized to the lower half of the curve order,
+// as required since Homestead, and the recovery ID is the one that recovers
+// address.
+func RecoverableSignature(digest []byte, r, s *big.Int, address common.Address) ([]byte, error) {
+	if r.Sign() <= 0 || r.Cmp(secp256k1N) >= 0 || s.Sign() <= 0 || s.Cmp(secp256k1N) >= 0 {
+		return nil, fmt.Errorf("invalid signature values r %s, s %s", r, s)
+	}
+
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s = new(big.Int).Sub(secp256k1N, s)
+	}
+
+	signature := make([]byte, crypto.SignatureLength)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:64])
+
+	var recovered common.Address
+
+	for recoveryID := byte(0); recoveryID < 2; recoveryID++ {
+		signature[crypto.RecoveryIDOffset] = recoveryID
+
+		publicKey, err := crypto.SigToPub(digest, signature)
+		if err != nil {
+			continue
+		}
+
+		recovered = crypto.PubkeyToAddress(*publicKey)
+		if recovered == address {
+			return signature, nil
+		}
+	}
+
+	return nil, SignatureMismatchError{Expected: address.Hex(), Recovered: recovered.Hex()}
+}
@@ -0,0 +1,89 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+)
+
+// nodeErrorMessages maps fragments of geth and ganache error messages to the
+// typed errors in the blockchain package. Nodes only report these as JSON-RPC
+// error strings, so matching on the message is the only option.
+var nodeErrorMessages = []struct {
+	fragment string
+	wrap     func(error) error
+}{
+	{"already known", func(err error) error { return blockchain.AlreadyKnownError{Err: err} }},
+	{"known transaction", func(err error) error { return blockchain.AlreadyKnownError{Err: err} }},
+	{"already imported", func(err error) error { return blockchain.AlreadyKnownError{Err: err} }},
+	{"nonce too low", func(err error) error { return blockchain.NonceTooLowError{Err: err} }},
+	{"doesn't have the correct nonce", func(err error) error { return blockchain.NonceTooLowError{Err: err} }},
+	{"replacement transaction underpriced", func(err error) error {
+		return blockchain.ReplacementUnderpricedError{Err: err}
+	}},
+	{"insufficient funds", func(err error) error { return blockchain.InsufficientFundsError{Err: err} }},
+	{"intrinsic gas too low", func(err error) error { return blockchain.IntrinsicGasTooLowError{Err: err} }},
+	{"less than block base fee", func(err error) error { return blockchain.FeeCapTooLowError{Err: err} }},
+	{"fee cap too low", func(err error) error { return blockchain.FeeCapTooLowError{Err: err} }},
+}
+
+// ClassifyError converts an error returned by the node client into one of the
+// typed errors in the blockchain package. Unrecognized errors are returned as is.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var retryableErr blockchain.RetryableError
+	if errors.As(err, &retryableErr) {
+		return err
+	}
+
+	message := strings.ToLower(err.Error())
+
+	for _, nodeErrorMessage := range nodeErrorMessages {
+		if strings.Contains(message, nodeErrorMessage.fragment) {
+			return nodeErrorMessage.wrap(err)
+		}
+	}
+
+	if isTransient(err) {
+		return blockchain.TransientNetworkError{Err: err}
+	}
+
+	return err
+}
+
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return false
+}
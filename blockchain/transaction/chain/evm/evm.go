@@ -3,15 +3,38 @@ package evm
 import (
 	"context"
 	"fmt"
+	"math/big"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/shopspring/decimal"
 )
 
 type Chain struct{}
 
+// Backend is the node API used by this package. It is implemented by
+// *ethclient.Client and by the simulated backend of go-ethereum.
+type Backend interface {
+	ethereum.BlockNumberReader
+	ethereum.ChainIDReader
+	ethereum.ChainStateReader
+	ethereum.ContractCaller
+	ethereum.GasEstimator
+	ethereum.GasPricer
+	ethereum.GasPricer1559
+	ethereum.PendingStateReader
+	ethereum.TransactionReader
+	ethereum.TransactionSender
+	// HeaderByNumber returns the latest header when number is nil.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
 type Client struct {
-	Delegate *ethclient.Client
+	Delegate Backend
+	// Endpoint identifies the node in metrics and spans without exposing
+	// credentials embedded in its URL.
+	Endpoint string
 }
 
 func NewClient(ctx context.Context, url string) (*Client, error) {
@@ -22,13 +45,25 @@ func NewClient(ctx context.Context, url string) (*Client, error) {
 
 	client := &Client{
 		Delegate: clnt,
+		Endpoint: endpointLabel(url),
 	}
 
 	return client, nil
 }
 
+// NewBackendClient wraps a backend that is already connected, e.g. the
+// simulated backend of go-ethereum.
+func NewBackendClient(backend Backend, endpoint string) *Client {
+	return &Client{
+		Delegate: backend,
+		Endpoint: endpoint,
+	}
+}
+
 func (client *Client) Close() {
-	client.Delegate.Close()
+	if closer, ok := client.Delegate.(interface{ Close() }); ok {
+		closer.Close()
+	}
 }
 
 func Marshal(txn *types.Transaction) ([]byte, error) {
@@ -50,3 +85,13 @@ func Unmarshal(bytes []byte) (*types.Transaction, error) {
 
 	return txn, nil
 }
+
+// ToBaseUnits converts an amount of a currency into its smallest unit, e.g. ETH to wei.
+func ToBaseUnits(amount decimal.Decimal, scale int) *big.Int {
+	return amount.Mul(decimal.NewFromInt(Base10).Pow(decimal.NewFromInt(int64(scale)))).BigInt()
+}
+
+// ToDecimal converts an amount in the smallest unit of a currency back into the currency.
+func ToDecimal(amount *big.Int, scale int) decimal.Decimal {
+	return decimal.NewFromBigInt(amount, int32(-scale))
+}
@@ -0,0 +1,93 @@
+// Package hsm signs EVM transactions with secp256k1 keys held by a PKCS#11
+// token, e.g. an HSM. Keys never leave the token: it signs the digests of
+// transactions, which evm.DigestTransactionSigner turns into signed
+// transactions.
+//
+// The PKCS#11 binding needs cgo; built without it, Open fails.
+package hsm
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// secp256k1OID is the DER encoded object identifier of secp256k1, the
+// CKA_EC_PARAMS of its keys.
+var secp256k1OID = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+
+type Config struct {
+	// ModulePath is the PKCS#11 library of the token, e.g.
+	// /usr/lib/softhsm/libsofthsm2.so.
+	ModulePath string
+	TokenLabel string
+	PIN        string
+}
+
+type TokenNotFoundError struct {
+	Label string
+}
+
+func (e TokenNotFoundError) Error() string {
+	return "PKCS#11 token not found for label " + e.Label
+}
+
+type KeyLabelNotFoundError struct {
+	Label string
+}
+
+func (e KeyLabelNotFoundError) Error() string {
+	return "secp256k1 key pair not found for label " + e.Label
+}
+
+// parseSignature returns r and s of an ECDSA signature, either 64 bytes of r
+// and s as CKM_ECDSA returns them or a DER sequence as some tokens do.
+func parseSignature(signature []byte) (*big.Int, *big.Int, error) {
+	if len(signature) == 2*common.HashLength {
+		return new(big.Int).SetBytes(signature[:common.HashLength]),
+			new(big.Int).SetBytes(signature[common.HashLength:]), nil
+	}
+
+	var values struct {
+		R *big.Int
+		S *big.Int
+	}
+
+	rest, err := asn1.Unmarshal(signature, &values)
+	if err != nil || len(rest) > 0 {
+		return nil, nil, fmt.Errorf("invalid ECDSA signature of %d bytes: %v", len(signature), err)
+	}
+
+	return values.R, values.S, nil
+}
+
+// pointAddress returns the address of an uncompressed public key given as a
+// CKA_EC_POINT, which tokens return either DER encoded as an octet string or
+// raw.
+func pointAddress(params []byte, point []byte) (common.Address, error) {
+	if !bytes.Equal(params, secp256k1OID) {
+		return common.Address{}, fmt.Errorf("key is not on secp256k1, EC params %x", params)
+	}
+
+	if len(point) != 1+2*common.HashLength {
+		var unwrapped []byte
+
+		rest, err := asn1.Unmarshal(point, &unwrapped)
+		if err != nil || len(rest) > 0 {
+			return common.Address{}, fmt.Errorf("invalid EC point of %d bytes: %v", len(point), err)
+		}
+
+		point = unwrapped
+	}
+
+	publicKey, err := crypto.UnmarshalPubkey(point)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid EC point: %w", err)
+	}
+
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
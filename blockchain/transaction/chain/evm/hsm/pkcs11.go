@@ -0,0 +1,198 @@
+//go:build cgo
+
+package hsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/miekg/pkcs11"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/telemetry"
+)
+
+// DigestSigner signs with the keys of one token, through a single logged in
+// session. PKCS#11 sessions run one operation at a time, so signatures are
+// serialized.
+type DigestSigner struct {
+	mutex   sync.Mutex
+	module  *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	keys    map[common.Address]pkcs11.ObjectHandle
+}
+
+var _ evm.DigestSigner = (*DigestSigner)(nil)
+
+// Open loads the module and logs in to the token with the configured label.
+func Open(config Config) (*DigestSigner, error) {
+	module := pkcs11.New(config.ModulePath)
+	if module == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", config.ModulePath)
+	}
+
+	err := module.Initialize()
+	if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		module.Destroy()
+
+		return nil, fmt.Errorf("failed to initialize PKCS#11 module %s: %w", config.ModulePath, err)
+	}
+
+	signer := &DigestSigner{
+		module: module,
+		keys:   make(map[common.Address]pkcs11.ObjectHandle),
+	}
+
+	if err := signer.login(config); err != nil {
+		module.Finalize()
+		module.Destroy()
+
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+func (signer *DigestSigner) login(config Config) error {
+	slots, err := signer.module.GetSlotList(true)
+	if err != nil {
+		return fmt.Errorf("failed to list PKCS#11 slots: %w", err)
+	}
+
+	for _, slot := range slots {
+		info, err := signer.module.GetTokenInfo(slot)
+		if err != nil || info.Label != config.TokenLabel {
+			continue
+		}
+
+		session, err := signer.module.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+		if err != nil {
+			return fmt.Errorf("failed to open session on token %s: %w", config.TokenLabel, err)
+		}
+
+		err = signer.module.Login(session, pkcs11.CKU_USER, config.PIN)
+		if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+			signer.module.CloseSession(session)
+
+			return fmt.Errorf("failed to log in to token %s: %w", config.TokenLabel, err)
+		}
+
+		signer.session = session
+
+		return nil
+	}
+
+	return TokenNotFoundError{Label: config.TokenLabel}
+}
+
+// AddKey makes the secp256k1 key pair with the given label available for
+// signing and returns its address.
+func (signer *DigestSigner) AddKey(label string) (common.Address, error) {
+	signer.mutex.Lock()
+	defer signer.mutex.Unlock()
+
+	privateKey, err := signer.findObject(pkcs11.CKO_PRIVATE_KEY, label)
+	if err != nil {
+
+		return common.Address{}, err
+	}
+
+	publicKey, err := signer.findObject(pkcs11.CKO_PUBLIC_KEY, label)
+	if err != nil {
+
+		return common.Address{}, err
+	}
+
+	attributes, err := signer.module.GetAttributeValue(signer.session, publicKey, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read public key %s: %w", label, err)
+	}
+
+	address, err := pointAddress(attributes[0].Value, attributes[1].Value)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("public key %s: %w", label, err)
+	}
+
+	signer.keys[address] = privateKey
+
+	return address, nil
+}
+
+func (signer *DigestSigner) findObject(class uint, label string) (pkcs11.ObjectHandle, error) {
+	err := signer.module.FindObjectsInit(signer.session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to search key %s: %w", label, err)
+	}
+
+	objects, _, err := signer.module.FindObjects(signer.session, 2)
+	signer.module.FindObjectsFinal(signer.session)
+
+	switch {
+	case err != nil:
+		return 0, fmt.Errorf("failed to search key %s: %w", label, err)
+	case len(objects) == 0:
+		return 0, KeyLabelNotFoundError{Label: label}
+	case len(objects) > 1:
+		return 0, fmt.Errorf("label %s is not unique on the token", label)
+	}
+
+	return objects[0], nil
+}
+
+func (signer *DigestSigner) SignDigest(
+	ctx context.Context,
+	address common.Address,
+	digest []byte,
+) (*big.Int, *big.Int, error) {
+	_, span := telemetry.Start(ctx, "hsm.sign", telemetry.String("address", address.Hex()))
+	defer span.End()
+
+	r, s, err := signer.signDigest(address, digest)
+	span.RecordError(err)
+
+	return r, s, err
+}
+
+func (signer *DigestSigner) signDigest(address common.Address, digest []byte) (*big.Int, *big.Int, error) {
+	signer.mutex.Lock()
+	defer signer.mutex.Unlock()
+
+	privateKey, ok := signer.keys[address]
+	if !ok {
+		return nil, nil, evm.KeyNotFoundError{Address: address.Hex()}
+	}
+
+	err := signer.module.SignInit(signer.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)},
+		privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start signing with key of %s: %w", address.Hex(), err)
+	}
+
+	signature, err := signer.module.Sign(signer.session, digest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign with key of %s: %w", address.Hex(), err)
+	}
+
+	return parseSignature(signature)
+}
+
+func (signer *DigestSigner) Close() {
+	signer.mutex.Lock()
+	defer signer.mutex.Unlock()
+
+	signer.module.Logout(signer.session)
+	signer.module.CloseSession(signer.session)
+	signer.module.Finalize()
+	signer.module.Destroy()
+}
@@ -0,0 +1,34 @@
+//go:build !cgo
+
+package hsm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+)
+
+var errNoCgo = errors.New("PKCS#11 signing requires a build with cgo")
+
+// DigestSigner is unavailable without cgo.
+type DigestSigner struct{}
+
+var _ evm.DigestSigner = (*DigestSigner)(nil)
+
+func Open(_ Config) (*DigestSigner, error) {
+	return nil, errNoCgo
+}
+
+func (signer *DigestSigner) AddKey(_ string) (common.Address, error) {
+	return common.Address{}, errNoCgo
+}
+
+func (signer *DigestSigner) SignDigest(_ context.Context, _ common.Address, _ []byte) (*big.Int, *big.Int, error) {
+	return nil, nil, errNoCgo
+}
+
+func (signer *DigestSigner) Close() {}
@@ -0,0 +1,302 @@
+//go:build cgo
+
+package hsm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/miekg/pkcs11"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm/hsm"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const (
+	tokenLabel = "payouts"
+	userPIN    = "1234"
+	soPIN      = "5678"
+)
+
+// secp256k1Params is the DER encoded object identifier of secp256k1.
+var secp256k1Params = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+
+// softHSMModule returns the SoftHSM library named by SOFTHSM2_MODULE or
+// found at a usual path, and skips the test without one.
+func softHSMModule(t *testing.T) string {
+	t.Helper()
+
+	for _, path := range []string{
+		os.Getenv("SOFTHSM2_MODULE"),
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/lib/aarch64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+		"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+	} {
+		if path == "" {
+			continue
+		}
+
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	t.Skip("SoftHSM not found; set SOFTHSM2_MODULE to its library")
+
+	return ""
+}
+
+// newToken initializes a SoftHSM token in a temporary directory, holding a
+// secp256k1 key pair for each label, and returns the config to open it.
+func newToken(t *testing.T, labels ...string) hsm.Config {
+	t.Helper()
+
+	modulePath := softHSMModule(t)
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "softhsm2.conf")
+
+	if err := os.WriteFile(conf, []byte("directories.tokendir = "+dir+"\nobjectstore.backend = file\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	module := pkcs11.New(modulePath)
+	if module == nil {
+		t.Fatalf("failed to load %s", modulePath)
+	}
+
+	if err := module.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	defer func() {
+		module.Finalize()
+		module.Destroy()
+	}()
+
+	slots, err := module.GetSlotList(true)
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("GetSlotList returned %v, %v", slots, err)
+	}
+
+	if err := module.InitToken(slots[0], soPIN, tokenLabel); err != nil {
+		t.Fatalf("InitToken: %v", err)
+	}
+
+	session := openSession(t, module)
+	defer module.CloseSession(session)
+
+	if err := module.Login(session, pkcs11.CKU_SO, soPIN); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	if err := module.InitPIN(session, userPIN); err != nil {
+		t.Fatalf("InitPIN: %v", err)
+	}
+
+	if err := module.Logout(session); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	if err := module.Login(session, pkcs11.CKU_USER, userPIN); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	for _, label := range labels {
+		_, _, err := module.GenerateKeyPair(session,
+			[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)},
+			[]*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+				pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, secp256k1Params),
+				pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			},
+			[]*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+				pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+				pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			})
+		if err != nil {
+			t.Fatalf("GenerateKeyPair %s: %v", label, err)
+		}
+	}
+
+	return hsm.Config{ModulePath: modulePath, TokenLabel: tokenLabel, PIN: userPIN}
+}
+
+// openSession opens a read-write session on the token, which SoftHSM moves to
+// a new slot once it is initialized.
+func openSession(t *testing.T, module *pkcs11.Ctx) pkcs11.SessionHandle {
+	t.Helper()
+
+	slots, err := module.GetSlotList(true)
+	if err != nil {
+		t.Fatalf("GetSlotList: %v", err)
+	}
+
+	for _, slot := range slots {
+		info, err := module.GetTokenInfo(slot)
+		if err != nil || info.Label != tokenLabel {
+			continue
+		}
+
+		session, err := module.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+		if err != nil {
+			t.Fatalf("OpenSession: %v", err)
+		}
+
+		return session
+	}
+
+	t.Fatalf("token %s not found", tokenLabel)
+
+	return 0
+}
+
+func open(t *testing.T, config hsm.Config) *hsm.DigestSigner {
+	t.Helper()
+
+	signer, err := hsm.Open(config)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	t.Cleanup(signer.Close)
+
+	return signer
+}
+
+func TestSignDigest(t *testing.T) {
+	signer := open(t, newToken(t, "hot-1", "hot-2"))
+
+	first, err := signer.AddKey("hot-1")
+	if err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+
+	second, err := signer.AddKey("hot-2")
+	if err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+
+	if first == second {
+		t.Fatalf("both key pairs have the address %s", first.Hex())
+	}
+
+	digest := crypto.Keccak256([]byte("hsm"))
+
+	for _, address := range []common.Address{first, second} {
+		r, s, err := signer.SignDigest(context.Background(), address, digest)
+		if err != nil {
+			t.Fatalf("SignDigest: %v", err)
+		}
+
+		// recovers the address of the key, or fails
+		if _, err := evm.RecoverableSignature(digest, r, s, address); err != nil {
+			t.Fatalf("RecoverableSignature: %v", err)
+		}
+	}
+
+	_, _, err = signer.SignDigest(context.Background(), common.HexToAddress("0x01"), digest)
+
+	var notFoundErr evm.KeyNotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Fatalf("SignDigest returned %v, want KeyNotFoundError", err)
+	}
+}
+
+func TestTransferSignedByToken(t *testing.T) {
+	signer := open(t, newToken(t, "hot-1"))
+
+	source, err := signer.AddKey("hot-1")
+	if err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+
+	destination := common.HexToAddress("0x00000000000000000000000000000000000000d5")
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		source: {Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))},
+	})
+	t.Cleanup(func() {
+		backend.Close()
+	})
+
+	client := evm.NewBackendClient(backend.Client(), "simulated")
+	transferor := transaction.NewGenericTransferor(
+		evm.NewTransactionBuilder(client),
+		evm.NewDigestTransactionSigner(signer),
+		evm.NewTransactionBroadcaster(client),
+	)
+
+	payload, err := transferor.Transfer(context.Background(), &transaction.TransferRequest{
+		SourceAddress:      source.Hex(),
+		DestinationAddress: destination.Hex(),
+		Amount:             decimal.NewFromInt(1),
+		NetworkCurrencyID:  domain.TestETH,
+	})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	backend.Commit()
+
+	receipt, err := backend.Client().TransactionReceipt(context.Background(), common.HexToHash(payload.ID))
+	if err != nil {
+		t.Fatalf("TransactionReceipt: %v", err)
+	}
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction %s reverted", payload.ID)
+	}
+
+	balance, err := backend.Client().BalanceAt(context.Background(), destination, nil)
+	if err != nil {
+		t.Fatalf("BalanceAt: %v", err)
+	}
+
+	if want := evm.ToBaseUnits(decimal.NewFromInt(1), 18); balance.Cmp(want) != 0 {
+		t.Fatalf("destination holds %s, want %s", balance, want)
+	}
+}
+
+func TestAddKeyWithUnknownLabel(t *testing.T) {
+	signer := open(t, newToken(t, "hot-1"))
+
+	_, err := signer.AddKey("cold-1")
+
+	var notFoundErr hsm.KeyLabelNotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Fatalf("AddKey returned %v, want KeyLabelNotFoundError", err)
+	}
+}
+
+func TestOpenWithUnknownToken(t *testing.T) {
+	config := newToken(t)
+	config.TokenLabel = "missing"
+
+	_, err := hsm.Open(config)
+
+	var notFoundErr hsm.TokenNotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Fatalf("Open returned %v, want TokenNotFoundError", err)
+	}
+}
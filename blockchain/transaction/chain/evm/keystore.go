@@ -0,0 +1,206 @@
+package evm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+type KeyNotFoundError struct {
+	Address string
+}
+
+func (e KeyNotFoundError) Error() string {
+	return fmt.Sprintf("key not found for address %s", e.Address)
+}
+
+// KeyStoreNotPersistentError reports a key that would only live in memory.
+type KeyStoreNotPersistentError struct{}
+
+func (e KeyStoreNotPersistentError) Error() string {
+	return "key store has no directory to keep generated keys in"
+}
+
+// KeyStore holds private keys in memory, indexed by their address. A key store
+// opened on a directory also keeps the keys it generates there, encrypted.
+type KeyStore struct {
+	mutex sync.RWMutex
+	keys  map[common.Address]*ecdsa.PrivateKey
+
+	dir      string
+	password string
+}
+
+var _ domain.AddressGenerator = (*KeyStore)(nil)
+
+func NewKeyStore() *KeyStore {
+	return &KeyStore{
+		keys: make(map[common.Address]*ecdsa.PrivateKey),
+	}
+}
+
+// OpenKeyStore returns a key store that writes the keys it generates to dir as
+// Web3 Secret Storage files encrypted with password, and loads the keys of the
+// files already in dir.
+func OpenKeyStore(dir string, password string) (*KeyStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create key store (%s): %w", dir, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key store (%s): %w", dir, err)
+	}
+
+	keyStore := NewKeyStore()
+	keyStore.dir = dir
+	keyStore.password = password
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file (%s): %w", path, err)
+		}
+
+		key, err := keystore.DecryptKey(content, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key file (%s): %w", path, err)
+		}
+
+		keyStore.add(key.PrivateKey)
+	}
+
+	return keyStore, nil
+}
+
+// Import adds a hex encoded private key, with or without 0x prefix, and
+// returns its address.
+func (keyStore *KeyStore) Import(privateKey string) (common.Address, error) {
+	ecdsaPrivateKey, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return keyStore.add(ecdsaPrivateKey), nil
+}
+
+// GenerateAddress creates a new key, writes it to the directory of the key
+// store and returns its checksummed address. The same key is valid on every
+// EVM network. A key store without a directory generates nothing, as the key
+// would be lost with the process while funds may already be sent to it.
+func (keyStore *KeyStore) GenerateAddress(_ context.Context, _ string) (string, error) {
+	if keyStore.dir == "" {
+		return "", KeyStoreNotPersistentError{}
+	}
+
+	ecdsaPrivateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	if err := keyStore.store(ecdsaPrivateKey); err != nil {
+		return "", err
+	}
+
+	return keyStore.add(ecdsaPrivateKey).Hex(), nil
+}
+
+func (keyStore *KeyStore) Key(address common.Address) (*ecdsa.PrivateKey, error) {
+	keyStore.mutex.RLock()
+	defer keyStore.mutex.RUnlock()
+
+	key, ok := keyStore.keys[address]
+	if !ok {
+		return nil, KeyNotFoundError{Address: address.Hex()}
+	}
+
+	return key, nil
+}
+
+func (keyStore *KeyStore) add(key *ecdsa.PrivateKey) common.Address {
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	keyStore.mutex.Lock()
+	defer keyStore.mutex.Unlock()
+
+	keyStore.keys[address] = key
+
+	return address
+}
+
+// store writes the key to a new file, named as go-ethereum names its key
+// files, before the key is handed out.
+func (keyStore *KeyStore) store(privateKey *ecdsa.PrivateKey) error {
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	content, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    address,
+		PrivateKey: privateKey,
+	}, keyStore.password, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt key (%s): %w", address.Hex(), err)
+	}
+
+	name := fmt.Sprintf("UTC--%s--%x", time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), address)
+
+	// the file only appears under its name once it is complete
+	tmp, err := os.CreateTemp(keyStore.dir, "."+name+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to store key (%s): %w", address.Hex(), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to store key (%s): %w", address.Hex(), err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to store key (%s): %w", address.Hex(), err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to store key (%s): %w", address.Hex(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(keyStore.dir, name)); err != nil {
+		return fmt.Errorf("failed to store key (%s): %w", address.Hex(), err)
+	}
+
+	return nil
+}
+
+func parsePrivateKey(privateKey string) (*ecdsa.PrivateKey, error) {
+	if strings.HasPrefix(privateKey, "0x") || strings.HasPrefix(privateKey, "0X") {
+		privateKey = privateKey[2:]
+	}
+
+	ecdsaPrivateKey, err := crypto.HexToECDSA(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert key: %w", err)
+	}
+
+	return ecdsaPrivateKey, nil
+}
@@ -0,0 +1,54 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+func TestKeyStoreKeepsGeneratedKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	keyStore, err := evm.OpenKeyStore(dir, "password")
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+
+	address, err := keyStore.GenerateAddress(context.Background(), domain.TestEth)
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+
+	reopened, err := evm.OpenKeyStore(dir, "password")
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+
+	key, err := reopened.Key(common.HexToAddress(address))
+	if err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+
+	if derived := crypto.PubkeyToAddress(key.PublicKey).Hex(); derived != address {
+		t.Fatalf("Key returned the key of %s, want %s", derived, address)
+	}
+
+	if _, err := evm.OpenKeyStore(dir, "wrong"); err == nil {
+		t.Fatal("OpenKeyStore succeeded with the wrong password")
+	}
+}
+
+func TestKeyStoreWithoutDirGeneratesNothing(t *testing.T) {
+	_, err := evm.NewKeyStore().GenerateAddress(context.Background(), domain.TestEth)
+
+	var notPersistentErr evm.KeyStoreNotPersistentError
+	if !errors.As(err, &notPersistentErr) {
+		t.Fatalf("GenerateAddress returned %v, want KeyStoreNotPersistentError", err)
+	}
+}
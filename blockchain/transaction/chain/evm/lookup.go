@@ -0,0 +1,224 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const erc20TransferDataSize = 4 + 2*PaddingSize
+
+var erc20TransferMethodID = []byte{0xa9, 0x05, 0x9c, 0xbb}
+
+type TransactionNotFoundError struct {
+	Hash string
+}
+
+func (e TransactionNotFoundError) Error() string {
+	return fmt.Sprintf("transaction %s not found", e.Hash)
+}
+
+type TokenTransfer struct {
+	NetworkCurrencyID string          `json:"network_currency_id,omitempty"`
+	Contract          string          `json:"contract"`
+	To                string          `json:"to"`
+	Amount            decimal.Decimal `json:"amount"`
+	RawAmount         string          `json:"raw_amount"`
+}
+
+type Receipt struct {
+	Status            string          `json:"status"`
+	BlockNumber       uint64          `json:"block_number"`
+	Confirmations     uint64          `json:"confirmations"`
+	GasUsed           uint64          `json:"gas_used"`
+	EffectiveGasPrice string          `json:"effective_gas_price"`
+	Fee               decimal.Decimal `json:"fee"`
+	ContractAddress   string          `json:"contract_address,omitempty"`
+}
+
+type TransactionDetails struct {
+	Hash          string          `json:"hash"`
+	NetworkCode   string          `json:"network_code"`
+	ChainID       string          `json:"chain_id"`
+	Nonce         uint64          `json:"nonce"`
+	From          string          `json:"from"`
+	To            string          `json:"to,omitempty"`
+	Value         decimal.Decimal `json:"value"`
+	FeeCurrency   string          `json:"fee_currency"`
+	Gas           uint64          `json:"gas"`
+	GasFeeCap     string          `json:"gas_fee_cap"`
+	GasTipCap     string          `json:"gas_tip_cap"`
+	Pending       bool            `json:"pending"`
+	TokenTransfer *TokenTransfer  `json:"token_transfer,omitempty"`
+	NFTTransfer   *NFTTransfer    `json:"nft_transfer,omitempty"`
+	Receipt       *Receipt        `json:"receipt,omitempty"`
+}
+
+// GetTransaction looks up a transaction together with its receipt and decodes
+// the amounts into units of the network's currencies.
+func (client *Client) GetTransaction(
+	ctx context.Context,
+	networkCode string,
+	hash string,
+) (*TransactionDetails, error) {
+	nativeCurrency, err := nativeNetworkCurrency(networkCode)
+	if err != nil {
+
+		return nil, err
+	}
+
+	var pending bool
+
+	txn, err := call(ctx, client, "eth_getTransactionByHash", func(ctx context.Context) (*types.Transaction, error) {
+		txn, isPending, err := client.Delegate.TransactionByHash(ctx, common.HexToHash(hash))
+		pending = isPending
+
+		return txn, err
+	})
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return nil, TransactionNotFoundError{Hash: hash}
+		}
+
+		return nil, fmt.Errorf("failed to retrieve transaction (%s): %w", hash, err)
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(txn.ChainId()), txn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender of transaction (%s): %w", hash, err)
+	}
+
+	details := &TransactionDetails{
+		Hash:        txn.Hash().Hex(),
+		NetworkCode: networkCode,
+		ChainID:     txn.ChainId().String(),
+		Nonce:       txn.Nonce(),
+		From:        from.Hex(),
+		Value:       ToDecimal(txn.Value(), nativeCurrency.Scale),
+		FeeCurrency: nativeCurrency.ID,
+		Gas:         txn.Gas(),
+		GasFeeCap:   txn.GasFeeCap().String(),
+		GasTipCap:   txn.GasTipCap().String(),
+		Pending:     pending,
+	}
+
+	if txn.To() != nil {
+		details.To = txn.To().Hex()
+		details.TokenTransfer = decodeTokenTransfer(networkCode, *txn.To(), txn.Data())
+		details.NFTTransfer = decodeNFTTransfer(*txn.To(), txn.Data())
+	}
+
+	if pending {
+		return details, nil
+	}
+
+	receipt, err := call(ctx, client, "eth_getTransactionReceipt", func(ctx context.Context) (*types.Receipt, error) {
+		return client.Delegate.TransactionReceipt(ctx, txn.Hash())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve receipt of transaction (%s): %w", hash, err)
+	}
+
+	head, err := call(ctx, client, "eth_blockNumber", client.Delegate.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve block number: %w", err)
+	}
+
+	details.Receipt = newReceipt(receipt, head, nativeCurrency.Scale)
+
+	return details, nil
+}
+
+func newReceipt(receipt *types.Receipt, head uint64, scale int) *Receipt {
+	status := "failed"
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		status = "success"
+	}
+
+	blockNumber := receipt.BlockNumber.Uint64()
+
+	var confirmations uint64
+	if head >= blockNumber {
+		confirmations = head - blockNumber + 1
+	}
+
+	effectiveGasPrice := receipt.EffectiveGasPrice
+	if effectiveGasPrice == nil {
+		effectiveGasPrice = big.NewInt(0)
+	}
+
+	fee := new(big.Int).Mul(effectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+
+	result := &Receipt{
+		Status:            status,
+		BlockNumber:       blockNumber,
+		Confirmations:     confirmations,
+		GasUsed:           receipt.GasUsed,
+		EffectiveGasPrice: effectiveGasPrice.String(),
+		Fee:               ToDecimal(fee, scale),
+	}
+
+	if receipt.ContractAddress != (common.Address{}) {
+		result.ContractAddress = receipt.ContractAddress.Hex()
+	}
+
+	return result
+}
+
+func decodeTokenTransfer(networkCode string, contract common.Address, data []byte) *TokenTransfer {
+	if len(data) != erc20TransferDataSize || !bytes.Equal(data[:4], erc20TransferMethodID) {
+		return nil
+	}
+
+	to := common.BytesToAddress(data[4 : 4+PaddingSize])
+	rawAmount := new(big.Int).SetBytes(data[4+PaddingSize:])
+
+	transfer := &TokenTransfer{
+		Contract:  contract.Hex(),
+		To:        to.Hex(),
+		Amount:    decimal.NewFromBigInt(rawAmount, 0),
+		RawAmount: rawAmount.String(),
+	}
+
+	networkCurrencies, err := domain.GetNetworkCurrencies(networkCode)
+	if err != nil {
+		return transfer
+	}
+
+	for _, networkCurrency := range networkCurrencies {
+		if networkCurrency.Address != "" && strings.EqualFold(networkCurrency.Address, contract.Hex()) {
+			transfer.NetworkCurrencyID = networkCurrency.ID
+			transfer.Amount = ToDecimal(rawAmount, networkCurrency.Scale)
+
+			break
+		}
+	}
+
+	return transfer
+}
+
+func nativeNetworkCurrency(networkCode string) (*domain.NetworkCurrency, error) {
+	networkCurrencies, err := domain.GetNetworkCurrencies(networkCode)
+	if err != nil {
+
+		return nil, err
+	}
+
+	for _, networkCurrency := range networkCurrencies {
+		if networkCurrency.ID == networkCurrency.Network.NativeToken {
+			return networkCurrency, nil
+		}
+	}
+
+	return nil, fmt.Errorf("native currency not found for network %s", networkCode)
+}
@@ -0,0 +1,225 @@
+package evm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/telemetry"
+)
+
+// signatureV is added to the recovery ID of message signatures, following
+// the convention of eth_sign and EIP-712 wallets.
+const signatureV = 27
+
+type SignatureMismatchError struct {
+	Expected  string
+	Recovered string
+}
+
+func (e SignatureMismatchError) Error() string {
+	return fmt.Sprintf("message signed by %s instead of %s", e.Recovered, e.Expected)
+}
+
+var (
+	_ transaction.MessageSigner = (*PrivKeyTransactionSigner)(nil)
+	_ transaction.MessageSigner = (*KeyStoreTransactionSigner)(nil)
+)
+
+func (signer *PrivKeyTransactionSigner) SignPersonal(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+) (*transaction.MessageSignature, error) {
+	return signer.signMessage(ctx, req, PersonalHash(req.Message))
+}
+
+func (signer *PrivKeyTransactionSigner) SignTypedData(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+) (*transaction.MessageSignature, error) {
+	digest, err := TypedDataHash(req.TypedData)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return signer.signMessage(ctx, req, digest)
+}
+
+func (signer *PrivKeyTransactionSigner) signMessage(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+	digest []byte,
+) (*transaction.MessageSignature, error) {
+	ecdsaPrivateKey, err := parsePrivateKey(signer.privateKey)
+	if err != nil {
+
+		return nil, err
+	}
+
+	// the signer holds a single key, which must be the one asked for
+	if crypto.PubkeyToAddress(ecdsaPrivateKey.PublicKey) != common.HexToAddress(req.Address) {
+		return nil, KeyNotFoundError{Address: req.Address}
+	}
+
+	return signDigest(ctx, req, digest, ecdsaPrivateKey)
+}
+
+func (signer *KeyStoreTransactionSigner) SignPersonal(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+) (*transaction.MessageSignature, error) {
+	return signer.signMessage(ctx, req, PersonalHash(req.Message))
+}
+
+func (signer *KeyStoreTransactionSigner) SignTypedData(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+) (*transaction.MessageSignature, error) {
+	digest, err := TypedDataHash(req.TypedData)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return signer.signMessage(ctx, req, digest)
+}
+
+func (signer *KeyStoreTransactionSigner) signMessage(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+	digest []byte,
+) (*transaction.MessageSignature, error) {
+	ecdsaPrivateKey, err := signer.keyStore.Key(common.HexToAddress(req.Address))
+	if err != nil {
+
+		return nil, err
+	}
+
+	return signDigest(ctx, req, digest, ecdsaPrivateKey)
+}
+
+func signDigest(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+	digest []byte,
+	ecdsaPrivateKey *ecdsa.PrivateKey,
+) (*transaction.MessageSignature, error) {
+	_, span := telemetry.Start(ctx, "evm.sign_message",
+		telemetry.String("network", req.NetworkCode),
+		telemetry.String("message.id", req.ID),
+	)
+	defer span.End()
+
+	signature, err := crypto.Sign(digest, ecdsaPrivateKey)
+	if err != nil {
+		err = fmt.Errorf("failed to sign message: %w", err)
+		span.RecordError(err)
+
+		return nil, err
+	}
+
+	signature[crypto.RecoveryIDOffset] += signatureV
+
+	return &transaction.MessageSignature{
+		Req:       req,
+		Address:   crypto.PubkeyToAddress(ecdsaPrivateKey.PublicKey).Hex(),
+		Digest:    digest,
+		Signature: signature,
+	}, nil
+}
+
+// PersonalHash returns the EIP-191 digest of a message, as signed by
+// personal_sign.
+func PersonalHash(message []byte) []byte {
+	return accounts.TextHash(message)
+}
+
+// TypedDataHash returns the EIP-712 digest of a typed data document in the
+// JSON format of eth_signTypedData_v4: the domain separator and the struct
+// hash of the message, both derived from the types of the document.
+func TypedDataHash(document []byte) ([]byte, error) {
+	var typedData apitypes.TypedData
+
+	if err := json.Unmarshal(document, &typedData); err != nil {
+		return nil, fmt.Errorf("failed to parse typed data: %w", err)
+	}
+
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+
+	return digest, nil
+}
+
+// RecoverAddress returns the address whose key signed the digest. The
+// recovery ID of the signature may be 0/1 or 27/28.
+func RecoverAddress(digest []byte, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length %d", len(signature))
+	}
+
+	sig := common.CopyBytes(signature)
+	if sig[crypto.RecoveryIDOffset] >= signatureV {
+		sig[crypto.RecoveryIDOffset] -= signatureV
+	}
+
+	publicKey, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover public key: %w", err)
+	}
+
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+func RecoverPersonal(message []byte, signature []byte) (common.Address, error) {
+	return RecoverAddress(PersonalHash(message), signature)
+}
+
+func RecoverTypedData(document []byte, signature []byte) (common.Address, error) {
+	digest, err := TypedDataHash(document)
+	if err != nil {
+
+		return common.Address{}, err
+	}
+
+	return RecoverAddress(digest, signature)
+}
+
+// VerifyPersonal checks that address signed the message with personal_sign.
+func VerifyPersonal(address string, message []byte, signature []byte) error {
+	recovered, err := RecoverPersonal(message, signature)
+	if err != nil {
+
+		return err
+	}
+
+	return matchAddress(address, recovered)
+}
+
+// VerifyTypedData checks that address signed the typed data document.
+func VerifyTypedData(address string, document []byte, signature []byte) error {
+	recovered, err := RecoverTypedData(document, signature)
+	if err != nil {
+
+		return err
+	}
+
+	return matchAddress(address, recovered)
+}
+
+func matchAddress(address string, recovered common.Address) error {
+	if recovered != common.HexToAddress(address) {
+		return SignatureMismatchError{Expected: address, Recovered: recovered.Hex()}
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const erc721Definition = `[
+	{"type":"function","name":"ownerOf","stateMutability":"view",
+		"inputs":[{"name":"tokenId","type":"uint256"}],"outputs":[{"type":"address"}]},
+	{"type":"function","name":"getApproved","stateMutability":"view",
+		"inputs":[{"name":"tokenId","type":"uint256"}],"outputs":[{"type":"address"}]},
+	{"type":"function","name":"isApprovedForAll","stateMutability":"view",
+		"inputs":[{"name":"owner","type":"address"},{"name":"operator","type":"address"}],
+		"outputs":[{"type":"bool"}]},
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable",
+		"inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],
+		"outputs":[]}
+]`
+
+const erc1155Definition = `[
+	{"type":"function","name":"balanceOf","stateMutability":"view",
+		"inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"outputs":[{"type":"uint256"}]},
+	{"type":"function","name":"isApprovedForAll","stateMutability":"view",
+		"inputs":[{"name":"account","type":"address"},{"name":"operator","type":"address"}],
+		"outputs":[{"type":"bool"}]},
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable",
+		"inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"id","type":"uint256"},
+			{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}],
+		"outputs":[]}
+]`
+
+var (
+	erc721ABI  = mustParseABI(erc721Definition)
+	erc1155ABI = mustParseABI(erc1155Definition)
+)
+
+type NFTTransfer struct {
+	Standard domain.NFTStandard `json:"standard"`
+	Contract string             `json:"contract"`
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	TokenID  string             `json:"token_id"`
+	Amount   string             `json:"amount"`
+}
+
+func nftDefinition(standard domain.NFTStandard) (string, *abi.ABI, error) {
+	switch standard {
+	case domain.NFTStandardERC721:
+		return erc721Definition, &erc721ABI, nil
+	case domain.NFTStandardERC1155:
+		return erc1155Definition, &erc1155ABI, nil
+	}
+
+	return "", nil, fmt.Errorf("NFT standard %s not supported", standard)
+}
+
+// EncodeNFTTransfer returns the safeTransferFrom calldata that moves the NFT
+// from the address holding it to the destination.
+func EncodeNFTTransfer(transfer *transaction.NFTTransfer, from, to common.Address) ([]byte, error) {
+	_, contractABI, err := nftDefinition(transfer.Asset.Standard)
+	if err != nil {
+
+		return nil, err
+	}
+
+	asset := &transfer.Asset
+	quantity := transfer.Quantity()
+
+	switch {
+	case asset.TokenID == nil || asset.TokenID.Sign() < 0:
+		return nil, fmt.Errorf("invalid token ID %v", asset.TokenID)
+	case quantity.Sign() <= 0:
+		return nil, fmt.Errorf("amount of %s must be positive, got %s", asset, quantity)
+	case asset.Standard == domain.NFTStandardERC721 && quantity.Cmp(big.NewInt(1)) != 0:
+		return nil, fmt.Errorf("amount of %s must be 1, got %s", asset, quantity)
+	}
+
+	var data []byte
+
+	if asset.Standard == domain.NFTStandardERC721 {
+		data, err = contractABI.Pack("safeTransferFrom", from, to, asset.TokenID)
+	} else {
+		data, err = contractABI.Pack("safeTransferFrom", from, to, asset.TokenID, quantity, []byte{})
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transfer of %s: %w", asset, err)
+	}
+
+	return data, nil
+}
+
+// checkNFT makes sure that the owner holds the NFT and, if the operator
+// sends the transfer for the owner, that the owner approved the operator.
+// The transaction would revert otherwise, after spending its gas.
+func (builder *TransactionBuilder) checkNFT(
+	ctx context.Context,
+	transfer *transaction.NFTTransfer,
+	owner common.Address,
+	operator common.Address,
+) error {
+	asset := &transfer.Asset
+	contract := common.HexToAddress(asset.Contract)
+
+	_, contractABI, err := nftDefinition(asset.Standard)
+	if err != nil {
+
+		return err
+	}
+
+	if asset.Standard == domain.NFTStandardERC721 {
+		holder, err := callView[common.Address](ctx, builder, contractABI, contract, "ownerOf", asset.TokenID)
+		if err != nil {
+
+			return err
+		}
+
+		if holder != owner {
+			return transaction.NFTNotOwnedError{Asset: asset, Owner: owner.Hex()}
+		}
+	} else {
+		balance, err := callView[*big.Int](ctx, builder, contractABI, contract, "balanceOf", owner, asset.TokenID)
+		if err != nil {
+
+			return err
+		}
+
+		if balance.Cmp(transfer.Quantity()) < 0 {
+			return transaction.NFTNotOwnedError{Asset: asset, Owner: owner.Hex(), Balance: balance}
+		}
+	}
+
+	if operator == owner {
+		return nil
+	}
+
+	if asset.Standard == domain.NFTStandardERC721 {
+		approved, err := callView[common.Address](ctx, builder, contractABI, contract, "getApproved", asset.TokenID)
+		if err != nil {
+
+			return err
+		}
+
+		if approved == operator {
+			return nil
+		}
+	}
+
+	approvedForAll, err := callView[bool](ctx, builder, contractABI, contract, "isApprovedForAll", owner, operator)
+	if err != nil {
+
+		return err
+	}
+
+	if !approvedForAll {
+		return transaction.NFTNotApprovedError{Asset: asset, Owner: owner.Hex(), Operator: operator.Hex()}
+	}
+
+	return nil
+}
+
+// callView calls a view method that returns a single value of type T.
+func callView[T any](
+	ctx context.Context,
+	builder *TransactionBuilder,
+	contractABI *abi.ABI,
+	contract common.Address,
+	method string,
+	args ...interface{},
+) (T, error) {
+	var value T
+
+	result, err := builder.callContract(ctx, contractABI, contract, method, args...)
+	if err != nil {
+
+		return value, err
+	}
+
+	values, err := contractABI.Unpack(method, result)
+	if err != nil || len(values) != 1 {
+		return value, fmt.Errorf("failed to decode %s of contract %s: %v", method, contract.Hex(), err)
+	}
+
+	value, ok := values[0].(T)
+	if !ok {
+		return value, fmt.Errorf("unexpected result of %s of contract %s: %v", method, contract.Hex(), values[0])
+	}
+
+	return value, nil
+}
+
+// decodeNFTTransfer decodes a safeTransferFrom call of either standard.
+func decodeNFTTransfer(contract common.Address, data []byte) *NFTTransfer {
+	if len(data) < methodIDSize {
+		return nil
+	}
+
+	for _, standard := range []domain.NFTStandard{domain.NFTStandardERC721, domain.NFTStandardERC1155} {
+		_, contractABI, _ := nftDefinition(standard)
+		method := contractABI.Methods["safeTransferFrom"]
+
+		if !bytes.Equal(data[:methodIDSize], method.ID) {
+			continue
+		}
+
+		values, err := method.Inputs.Unpack(data[methodIDSize:])
+		if err != nil {
+			return nil
+		}
+
+		transfer := &NFTTransfer{
+			Standard: standard,
+			Contract: contract.Hex(),
+			From:     values[0].(common.Address).Hex(),
+			To:       values[1].(common.Address).Hex(),
+			TokenID:  values[2].(*big.Int).String(),
+			Amount:   "1",
+		}
+
+		if standard == domain.NFTStandardERC1155 {
+			transfer.Amount = values[3].(*big.Int).String()
+		}
+
+		return transfer
+	}
+
+	return nil
+}
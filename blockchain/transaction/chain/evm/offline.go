@@ -0,0 +1,192 @@
+package evm
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const gweiScale = 9
+
+var (
+	_ transaction.Describer         = (*TransactionBuilder)(nil)
+	_ transaction.SignatureVerifier = (*TransactionBuilder)(nil)
+)
+
+func (builder *TransactionBuilder) Describe(
+	payload *transaction.TransferPayload,
+) (*transaction.TransactionDescription, error) {
+	return Describe(payload)
+}
+
+func (builder *TransactionBuilder) VerifySigned(payload *transaction.TransferPayload) (string, error) {
+	return VerifySigned(payload)
+}
+
+// Describe decodes the unsigned transaction of the payload. The lines are
+// taken from the transaction itself rather than the request, so they show what
+// a signature would approve.
+func Describe(payload *transaction.TransferPayload) (*transaction.TransactionDescription, error) {
+	txn, err := Unmarshal(payload.Raw)
+	if err != nil {
+
+		return nil, err
+	}
+
+	networkCurrency, err := domain.NewNetworkCurrency(payload.Req.NetworkCurrencyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	nativeCurrency, err := domain.NewNetworkCurrency(networkCurrency.Network.NativeToken)
+	if err != nil {
+
+		return nil, err
+	}
+
+	sender := common.HexToAddress(payload.Req.SourceAddress)
+
+	lines := []string{
+		fmt.Sprintf("Network: %s (chain ID %s)", networkCurrency.Network.Code, txn.ChainId()),
+		"From: " + sender.Hex(),
+	}
+
+	if txn.To() == nil {
+		if networkCurrency.Address != "" {
+			return nil, fmt.Errorf("transaction creates a contract instead of transferring %s", networkCurrency.ID)
+		}
+
+		lines = append(lines,
+			"Creates contract: "+crypto.CreateAddress(sender, txn.Nonce()).Hex(),
+			fmt.Sprintf("Amount: %s %s", ToDecimal(txn.Value(), networkCurrency.Scale), networkCurrency.Currency.Code),
+			fmt.Sprintf("Contract creation: %d bytes of code and constructor arguments", len(txn.Data())),
+		)
+	} else {
+		recipient, amount, err := transferredAmount(txn, networkCurrency)
+		if err != nil {
+
+			return nil, err
+		}
+
+		lines = append(lines,
+			"To: "+recipient.Hex(),
+			fmt.Sprintf("Amount: %s %s", ToDecimal(amount, networkCurrency.Scale), networkCurrency.Currency.Code),
+		)
+	}
+
+	if networkCurrency.Address != "" {
+		lines = append(lines, "Token contract: "+txn.To().Hex())
+	}
+
+	// the calldata of a contract call is summarised by its method ID; the
+	// arguments are only known with the ABI of the contract
+	if data := txn.Data(); txn.To() != nil && networkCurrency.Address == "" && len(data) > 0 {
+		if nft := decodeNFTTransfer(*txn.To(), data); nft != nil {
+			lines = append(lines,
+				fmt.Sprintf("NFT: %s token %s of %s, amount %s", nft.Standard, nft.TokenID, nft.Contract, nft.Amount),
+				"NFT from: "+nft.From,
+				"NFT to: "+nft.To,
+			)
+		} else {
+			lines = append(lines, fmt.Sprintf("Contract call: method %s with %d bytes of arguments",
+				hexutil.Encode(data[:min(len(data), methodIDSize)]), max(len(data)-methodIDSize, 0)))
+		}
+	}
+
+	maxFee := new(big.Int).Mul(txn.GasFeeCap(), new(big.Int).SetUint64(txn.Gas()))
+
+	lines = append(lines,
+		fmt.Sprintf("Nonce: %d", txn.Nonce()),
+		fmt.Sprintf("Gas limit: %d", txn.Gas()),
+		fmt.Sprintf("Max fee per gas: %s gwei", ToDecimal(txn.GasFeeCap(), gweiScale)),
+		fmt.Sprintf("Max priority fee per gas: %s gwei", ToDecimal(txn.GasTipCap(), gweiScale)),
+		fmt.Sprintf("Max network fee: %s %s", ToDecimal(maxFee, nativeCurrency.Scale), nativeCurrency.Currency.Code),
+	)
+
+	return &transaction.TransactionDescription{
+		Chain:   domain.FamilyEvm,
+		ChainID: txn.ChainId().String(),
+		Lines:   lines,
+	}, nil
+}
+
+// VerifySigned checks that payload.Signed is payload.Raw with a signature of
+// the sender and returns the hash of the signed transaction.
+func VerifySigned(payload *transaction.TransferPayload) (string, error) {
+	unsignedTx, err := Unmarshal(payload.Raw)
+	if err != nil {
+
+		return "", err
+	}
+
+	signedTx, err := Unmarshal(payload.Signed)
+	if err != nil {
+
+		return "", err
+	}
+
+	signer := types.NewLondonSigner(unsignedTx.ChainId())
+
+	if signer.Hash(unsignedTx) != signer.Hash(signedTx) {
+		return "", transaction.BundleMismatchError{
+			TransferID: payload.Req.ID,
+			Reason:     "signed transaction differs from the built transaction",
+		}
+	}
+
+	sender, err := types.Sender(signer, signedTx)
+	if err != nil {
+		return "", transaction.BundleMismatchError{
+			TransferID: payload.Req.ID,
+			Reason:     fmt.Sprintf("invalid signature: %v", err),
+		}
+	}
+
+	if sender != common.HexToAddress(payload.SenderAddress()) {
+		return "", transaction.BundleMismatchError{
+			TransferID: payload.Req.ID,
+			Reason:     "signed by " + sender.Hex() + " instead of " + payload.SenderAddress(),
+		}
+	}
+
+	return signedTx.Hash().Hex(), nil
+}
+
+// transferredAmount returns the recipient and amount of a native transfer or
+// of an ERC-20 transfer call as built by TransactionBuilder.
+func transferredAmount(
+	txn *types.Transaction,
+	networkCurrency *domain.NetworkCurrency,
+) (common.Address, *big.Int, error) {
+	if networkCurrency.Address == "" {
+		return *txn.To(), txn.Value(), nil
+	}
+
+	if *txn.To() != common.HexToAddress(networkCurrency.Address) {
+		return common.Address{}, nil, fmt.Errorf(
+			"transaction calls %s instead of the %s contract", txn.To().Hex(), networkCurrency.ID)
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte("transfer(address,uint256)"))
+	methodID := hash.Sum(nil)[:4]
+
+	data := txn.Data()
+	if len(data) != len(methodID)+2*PaddingSize || !bytes.Equal(data[:len(methodID)], methodID) {
+		return common.Address{}, nil, fmt.Errorf("transaction data is not a %s transfer", networkCurrency.ID)
+	}
+
+	args := data[len(methodID):]
+
+	return common.BytesToAddress(args[:PaddingSize]), new(big.Int).SetBytes(args[PaddingSize:]), nil
+}
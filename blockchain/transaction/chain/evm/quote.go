@@ -0,0 +1,147 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const percent = 100
+
+// feeTiers scales the suggested priority fee; the standard tier matches what
+// Build uses when no fee is pinned.
+var feeTiers = []struct {
+	tier       transaction.FeeTier
+	percentage int64
+}{
+	{tier: transaction.FeeTierSlow, percentage: 90},
+	{tier: transaction.FeeTierStandard, percentage: 100},
+	{tier: transaction.FeeTierFast, percentage: 125},
+}
+
+var erc20BalanceOfMethodID = []byte{0x70, 0xa0, 0x82, 0x31}
+
+var _ transaction.Quoter = (*TransactionBuilder)(nil)
+
+func (builder *TransactionBuilder) Quote(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.Quote, error) {
+	// the relayer pays the fees of a relayed transfer with calls that are
+	// only known once the source has signed
+	if mode := param.TransferMode(); mode.Relayed() {
+		return nil, transaction.TransferModeNotSupportedError{Mode: mode, Reason: "relayed transfers cannot be quoted"}
+	}
+
+	unpinned := *param
+	unpinned.Fee = nil
+
+	txData, err := builder.build(ctx, &unpinned)
+	if err != nil {
+		return nil, err
+	}
+
+	networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	nativeCurrency, err := nativeNetworkCurrency(networkCurrency.Network.Code)
+	if err != nil {
+
+		return nil, err
+	}
+
+	fromAddr := common.HexToAddress(param.SourceAddress)
+
+	balance, err := call(ctx, builder.client, "eth_getBalance", func(ctx context.Context) (*big.Int, error) {
+		return builder.client.Delegate.BalanceAt(ctx, fromAddr, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve balance for address (%s): %w", param.SourceAddress, ClassifyError(err))
+	}
+
+	nativeBalance := ToDecimal(balance, nativeCurrency.Scale)
+
+	quote := &transaction.Quote{
+		GasLimit:      txData.Gas,
+		FeeCurrencyID: nativeCurrency.ID,
+		Balance:       nativeBalance,
+	}
+
+	// the tiers share the base fee allowance of the cap and differ in the tip
+	baseFeeAllowance := new(big.Int).Sub(txData.GasFeeCap, txData.GasTipCap)
+
+	for _, feeTier := range feeTiers {
+		gasTipCap := new(big.Int).Mul(txData.GasTipCap, big.NewInt(feeTier.percentage))
+		gasTipCap.Div(gasTipCap, big.NewInt(percent))
+
+		gasFeeCap := new(big.Int).Add(baseFeeAllowance, gasTipCap)
+
+		// the fee is what the transaction costs at most, at its fee cap
+		fee := ToDecimal(new(big.Int).Mul(gasFeeCap, new(big.Int).SetUint64(txData.Gas)), nativeCurrency.Scale)
+
+		totalCost := fee
+		if networkCurrency.ID == nativeCurrency.ID {
+			totalCost = totalCost.Add(param.Amount)
+		}
+
+		quote.FeeOptions = append(quote.FeeOptions, &transaction.FeeOption{
+			Tier:                 feeTier.tier,
+			MaxFeePerGas:         gasFeeCap,
+			MaxPriorityFeePerGas: gasTipCap,
+			Fee:                  fee,
+			TotalCost:            totalCost,
+			BalanceAfter:         nativeBalance.Sub(totalCost),
+		})
+	}
+
+	if networkCurrency.ID != nativeCurrency.ID {
+		tokenBalance, err := builder.tokenBalance(ctx, networkCurrency, fromAddr)
+		if err != nil {
+
+			return nil, err
+		}
+
+		tokenBalanceAfter := tokenBalance.Sub(param.Amount)
+
+		quote.CurrencyBalance = &tokenBalance
+		quote.CurrencyBalanceAfter = &tokenBalanceAfter
+	}
+
+	return quote, nil
+}
+
+func (builder *TransactionBuilder) tokenBalance(
+	ctx context.Context,
+	networkCurrency *domain.NetworkCurrency,
+	owner common.Address,
+) (decimal.Decimal, error) {
+	contract := common.HexToAddress(networkCurrency.Address)
+
+	data := append([]byte{}, erc20BalanceOfMethodID...)
+	data = append(data, common.LeftPadBytes(owner.Bytes(), PaddingSize)...)
+
+	result, err := call(ctx, builder.client, "eth_call", func(ctx context.Context) ([]byte, error) {
+		return builder.client.Delegate.CallContract(ctx, ethereum.CallMsg{
+			To:   &contract,
+			Data: data,
+		}, nil)
+	})
+	if err != nil {
+		return decimal.Zero, fmt.Errorf(
+			"failed to retrieve balance of currency(%s) for address (%s): %w",
+			networkCurrency.ID, owner.Hex(), ClassifyError(err),
+		)
+	}
+
+	return ToDecimal(new(big.Int).SetBytes(result), networkCurrency.Scale), nil
+}
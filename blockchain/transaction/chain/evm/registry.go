@@ -0,0 +1,48 @@
+package evm
+
+import (
+	"sync"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+)
+
+// ClientRegistry resolves the node client to use for a network code.
+type ClientRegistry struct {
+	mutex   sync.RWMutex
+	clients map[string]*Client
+}
+
+func NewClientRegistry() *ClientRegistry {
+	return &ClientRegistry{
+		clients: make(map[string]*Client),
+	}
+}
+
+func (registry *ClientRegistry) Register(networkCode string, client *Client) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registry.clients[networkCode] = client
+}
+
+func (registry *ClientRegistry) Get(networkCode string) (*Client, error) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	client, ok := registry.clients[networkCode]
+	if !ok {
+		return nil, &blockchain.NetworkNotSupportedError{NetworkCode: networkCode}
+	}
+
+	return client, nil
+}
+
+func (registry *ClientRegistry) Close() {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	for networkCode, client := range registry.clients {
+		client.Close()
+		delete(registry.clients, networkCode)
+	}
+}
@@ -0,0 +1,534 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const (
+	// RelayValidity bounds how long a permit or authorization can be used.
+	RelayValidity = time.Hour
+	// TransferFromGasLimit is used for the transferFrom that follows a permit,
+	// as its gas cannot be estimated before the permit is mined.
+	TransferFromGasLimit = 100_000
+
+	defaultDomainVersion = "1"
+)
+
+// relayABI covers the EIP-2612 and EIP-3009 extensions of ERC-20 used by
+// relayed transfers.
+var relayABI = mustParseABI(`[
+	{"type":"function","name":"name","stateMutability":"view","inputs":[],"outputs":[{"type":"string"}]},
+	{"type":"function","name":"version","stateMutability":"view","inputs":[],"outputs":[{"type":"string"}]},
+	{"type":"function","name":"DOMAIN_SEPARATOR","stateMutability":"view","inputs":[],"outputs":[{"type":"bytes32"}]},
+	{"type":"function","name":"nonces","stateMutability":"view",
+		"inputs":[{"name":"owner","type":"address"}],"outputs":[{"type":"uint256"}]},
+	{"type":"function","name":"allowance","stateMutability":"view",
+		"inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],
+		"outputs":[{"type":"uint256"}]},
+	{"type":"function","name":"permit","stateMutability":"nonpayable",
+		"inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},
+			{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},
+			{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],
+		"outputs":[]},
+	{"type":"function","name":"transferFrom","stateMutability":"nonpayable",
+		"inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}],
+		"outputs":[{"type":"bool"}]},
+	{"type":"function","name":"transferWithAuthorization","stateMutability":"nonpayable",
+		"inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},
+			{"name":"value","type":"uint256"},{"name":"validAfter","type":"uint256"},
+			{"name":"validBefore","type":"uint256"},{"name":"nonce","type":"bytes32"},
+			{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],
+		"outputs":[]}
+]`)
+
+var eip712DomainType = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+var permitTypes = apitypes.Types{
+	"EIP712Domain": eip712DomainType,
+	"Permit": {
+		{Name: "owner", Type: "address"},
+		{Name: "spender", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "deadline", Type: "uint256"},
+	},
+}
+
+var authorizationTypes = apitypes.Types{
+	"EIP712Domain": eip712DomainType,
+	"TransferWithAuthorization": {
+		{Name: "from", Type: "address"},
+		{Name: "to", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "validAfter", Type: "uint256"},
+		{Name: "validBefore", Type: "uint256"},
+		{Name: "nonce", Type: "bytes32"},
+	},
+}
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
+
+// relayedTransfer holds what every relayed transaction of a transfer shares.
+type relayedTransfer struct {
+	param    *transaction.TransferRequest
+	token    common.Address
+	owner    common.Address
+	to       common.Address
+	relayer  common.Address
+	value    *big.Int
+	deadline *big.Int
+	domain   apitypes.TypedDataDomain
+	chainID  *big.Int
+	nonce    uint64
+	tipCap   *big.Int
+	feeCap   *big.Int
+}
+
+// buildRelayed builds a transfer that the relayer sends and pays for, with the
+// permit or authorization of the source signed by the Authorizer.
+func (builder *TransactionBuilder) buildRelayed(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.TransferPayload, error) {
+	relayed, err := builder.prepareRelayed(ctx, param)
+	if err != nil {
+
+		return nil, err
+	}
+
+	if param.TransferMode() == transaction.TransferModeAuthorization {
+		return builder.buildAuthorization(ctx, relayed)
+	}
+
+	return builder.buildPermit(ctx, relayed)
+}
+
+func (builder *TransactionBuilder) prepareRelayed(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*relayedTransfer, error) {
+	mode := param.TransferMode()
+
+	if builder.Relayer == "" || builder.Authorizer == nil {
+		return nil, transaction.TransferModeNotSupportedError{Mode: mode, Reason: "no relayer configured"}
+	}
+
+	networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	if networkCurrency.Address == "" {
+		return nil, transaction.TransferModeNotSupportedError{
+			Mode:   mode,
+			Reason: networkCurrency.ID + " is not a token",
+		}
+	}
+
+	client := builder.client
+	relayer := common.HexToAddress(builder.Relayer)
+
+	chainID, err := call(ctx, client, "eth_chainId", client.Delegate.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve chain ID: %w", ClassifyError(err))
+	}
+
+	nonce, err := call(ctx, client, "eth_getTransactionCount", func(ctx context.Context) (uint64, error) {
+		return client.Delegate.PendingNonceAt(ctx, relayer)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve nonce for relayer (%s): %w", builder.Relayer, ClassifyError(err))
+	}
+
+	tipCap, feeCap, err := builder.fees(ctx, param)
+	if err != nil {
+
+		return nil, err
+	}
+
+	token := common.HexToAddress(networkCurrency.Address)
+
+	tokenDomain, err := builder.tokenDomain(ctx, token, chainID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &relayedTransfer{
+		param:    param,
+		token:    token,
+		owner:    common.HexToAddress(param.SourceAddress),
+		to:       common.HexToAddress(param.DestinationAddress),
+		relayer:  relayer,
+		value:    ToBaseUnits(param.Amount, networkCurrency.Scale),
+		deadline: big.NewInt(time.Now().Add(RelayValidity).Unix()),
+		domain:   tokenDomain,
+		chainID:  chainID,
+		nonce:    nonce,
+		tipCap:   tipCap,
+		feeCap:   feeCap,
+	}, nil
+}
+
+// buildPermit builds transferFrom by the relayer, preceded by the permit that
+// allows it unless the relayer already has the allowance.
+func (builder *TransactionBuilder) buildPermit(
+	ctx context.Context,
+	relayed *relayedTransfer,
+) (*transaction.TransferPayload, error) {
+	allowance, err := builder.callBigInt(ctx, relayed.token, "allowance", relayed.owner, relayed.relayer)
+	if err != nil {
+
+		return nil, err
+	}
+
+	transferData, err := relayABI.Pack("transferFrom", relayed.owner, relayed.to, relayed.value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transferFrom: %w", err)
+	}
+
+	if allowance.Cmp(relayed.value) >= 0 {
+		gasLimit, err := builder.estimateRelayed(ctx, relayed, transferData)
+		if err != nil {
+
+			return nil, err
+		}
+
+		return builder.relayedPayload(relayed, 0, transferData, gasLimit)
+	}
+
+	permitNonce, err := builder.callBigInt(ctx, relayed.token, "nonces", relayed.owner)
+	if err != nil {
+
+		return nil, err
+	}
+
+	signature, err := builder.authorize(ctx, relayed, "Permit", permitTypes, apitypes.TypedDataMessage{
+		"owner":    relayed.owner.Hex(),
+		"spender":  relayed.relayer.Hex(),
+		"value":    relayed.value.String(),
+		"nonce":    permitNonce.String(),
+		"deadline": relayed.deadline.String(),
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	v, r, s := splitSignature(signature)
+
+	permitData, err := relayABI.Pack("permit", relayed.owner, relayed.relayer, relayed.value, relayed.deadline, v, r, s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode permit: %w", err)
+	}
+
+	permitGas, err := builder.estimateRelayed(ctx, relayed, permitData)
+	if err != nil {
+
+		return nil, err
+	}
+
+	permit, err := builder.relayedPayload(relayed, 0, permitData, permitGas)
+	if err != nil {
+
+		return nil, err
+	}
+
+	payload, err := builder.relayedPayload(relayed, 1, transferData, TransferFromGasLimit)
+	if err != nil {
+
+		return nil, err
+	}
+
+	payload.Preceding = []*transaction.TransferPayload{permit}
+
+	return payload, nil
+}
+
+// buildAuthorization builds transferWithAuthorization by the relayer, which
+// needs no allowance and completes in a single transaction.
+func (builder *TransactionBuilder) buildAuthorization(
+	ctx context.Context,
+	relayed *relayedTransfer,
+) (*transaction.TransferPayload, error) {
+	// EIP-3009 nonces are random rather than sequential
+	var nonce [32]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate authorization nonce: %w", err)
+	}
+
+	validAfter := big.NewInt(0)
+
+	signature, err := builder.authorize(ctx, relayed, "TransferWithAuthorization", authorizationTypes,
+		apitypes.TypedDataMessage{
+			"from":        relayed.owner.Hex(),
+			"to":          relayed.to.Hex(),
+			"value":       relayed.value.String(),
+			"validAfter":  validAfter.String(),
+			"validBefore": relayed.deadline.String(),
+			"nonce":       hexutil.Encode(nonce[:]),
+		})
+	if err != nil {
+
+		return nil, err
+	}
+
+	v, r, s := splitSignature(signature)
+
+	data, err := relayABI.Pack("transferWithAuthorization",
+		relayed.owner, relayed.to, relayed.value, validAfter, relayed.deadline, nonce, v, r, s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transferWithAuthorization: %w", err)
+	}
+
+	gasLimit, err := builder.estimateRelayed(ctx, relayed, data)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return builder.relayedPayload(relayed, 0, data, gasLimit)
+}
+
+// authorize has the source sign typed data for the token's domain.
+func (builder *TransactionBuilder) authorize(
+	ctx context.Context,
+	relayed *relayedTransfer,
+	primaryType string,
+	types apitypes.Types,
+	message apitypes.TypedDataMessage,
+) ([]byte, error) {
+	document, err := json.Marshal(apitypes.TypedData{
+		Types:       types,
+		PrimaryType: primaryType,
+		Domain:      relayed.domain,
+		Message:     message,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal typed data: %w", err)
+	}
+
+	networkCurrency, err := domain.NewNetworkCurrency(relayed.param.NetworkCurrencyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	signature, err := builder.Authorizer.SignTypedData(ctx, &transaction.MessageRequest{
+		ID:          relayed.param.ID,
+		Address:     relayed.param.SourceAddress,
+		NetworkCode: networkCurrency.Network.Code,
+		TypedData:   document,
+		Principal:   relayed.param.Principal,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign %s: %w", primaryType, err)
+	}
+
+	return signature.Signature, nil
+}
+
+// tokenDomain returns the EIP-712 domain of a token. Tokens without version()
+// use version 1; if the token exposes DOMAIN_SEPARATOR, the domain must
+// reproduce it, as a signature for another domain would be rejected on chain.
+func (builder *TransactionBuilder) tokenDomain(
+	ctx context.Context,
+	token common.Address,
+	chainID *big.Int,
+) (apitypes.TypedDataDomain, error) {
+	name, err := builder.callString(ctx, token, "name")
+	if err != nil {
+
+		return apitypes.TypedDataDomain{}, err
+	}
+
+	version, err := builder.callString(ctx, token, "version")
+	if err != nil {
+		version = defaultDomainVersion
+	}
+
+	tokenDomain := apitypes.TypedDataDomain{
+		Name:              name,
+		Version:           version,
+		ChainId:           (*math.HexOrDecimal256)(chainID),
+		VerifyingContract: token.Hex(),
+	}
+
+	// DOMAIN_SEPARATOR is optional in EIP-3009
+	expected, errS := builder.callContract(ctx, &relayABI, token, "DOMAIN_SEPARATOR")
+	if errS != nil {
+		return tokenDomain, nil
+	}
+
+	typedData := apitypes.TypedData{
+		Types:  apitypes.Types{"EIP712Domain": eip712DomainType},
+		Domain: tokenDomain,
+	}
+
+	separator, err := typedData.HashStruct("EIP712Domain", tokenDomain.Map())
+	if err != nil {
+		return apitypes.TypedDataDomain{}, fmt.Errorf("failed to hash domain of token %s: %w", token.Hex(), err)
+	}
+
+	if !bytes.Equal(separator, expected) {
+		return apitypes.TypedDataDomain{}, fmt.Errorf(
+			"domain separator of token %s does not match name %q and version %q", token.Hex(), name, version)
+	}
+
+	return tokenDomain, nil
+}
+
+func (builder *TransactionBuilder) estimateRelayed(
+	ctx context.Context,
+	relayed *relayedTransfer,
+	data []byte,
+) (uint64, error) {
+	client := builder.client
+
+	gasLimit, err := call(ctx, client, "eth_estimateGas", func(ctx context.Context) (uint64, error) {
+		return client.Delegate.EstimateGas(ctx, ethereum.CallMsg{
+			From: relayed.relayer,
+			To:   &relayed.token,
+			Data: data,
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate gas of relayed transfer by (%s): %w",
+			relayed.relayer.Hex(), ClassifyError(err))
+	}
+
+	return gasLimit, nil
+}
+
+// relayedPayload builds the offset-th transaction of the relayer for the transfer.
+func (builder *TransactionBuilder) relayedPayload(
+	relayed *relayedTransfer,
+	offset uint64,
+	data []byte,
+	gasLimit uint64,
+) (*transaction.TransferPayload, error) {
+	raw, err := Marshal(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   relayed.chainID,
+		Nonce:     relayed.nonce + offset,
+		To:        &relayed.token,
+		Value:     big.NewInt(0),
+		Gas:       gasLimit,
+		GasTipCap: relayed.tipCap,
+		GasFeeCap: relayed.feeCap,
+		Data:      data,
+	}))
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &transaction.TransferPayload{
+		Req:    relayed.param,
+		Sender: relayed.relayer.Hex(),
+		Raw:    raw,
+	}, nil
+}
+
+func (builder *TransactionBuilder) callContract(
+	ctx context.Context,
+	contractABI *abi.ABI,
+	contract common.Address,
+	method string,
+	args ...interface{},
+) ([]byte, error) {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", method, err)
+	}
+
+	client := builder.client
+
+	result, err := call(ctx, client, "eth_call", func(ctx context.Context) ([]byte, error) {
+		return client.Delegate.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s of contract %s: %w", method, contract.Hex(), ClassifyError(err))
+	}
+
+	return result, nil
+}
+
+func (builder *TransactionBuilder) callString(
+	ctx context.Context,
+	token common.Address,
+	method string,
+) (string, error) {
+	result, err := builder.callContract(ctx, &relayABI, token, method)
+	if err != nil {
+
+		return "", err
+	}
+
+	var value string
+	if err := relayABI.UnpackIntoInterface(&value, method, result); err != nil {
+		return "", fmt.Errorf("failed to decode %s of token %s: %w", method, token.Hex(), err)
+	}
+
+	return value, nil
+}
+
+func (builder *TransactionBuilder) callBigInt(
+	ctx context.Context,
+	token common.Address,
+	method string,
+	args ...interface{},
+) (*big.Int, error) {
+	result, err := builder.callContract(ctx, &relayABI, token, method, args...)
+	if err != nil {
+
+		return nil, err
+	}
+
+	value := new(big.Int)
+	if err := relayABI.UnpackIntoInterface(&value, method, result); err != nil {
+		return nil, fmt.Errorf("failed to decode %s of token %s: %w", method, token.Hex(), err)
+	}
+
+	return value, nil
+}
+
+// splitSignature returns the v, r and s of a 65 byte signature with v 27 or 28.
+func splitSignature(signature []byte) (uint8, [32]byte, [32]byte) {
+	var r, s [32]byte
+
+	copy(r[:], signature[:32])
+	copy(s[:], signature[32:64])
+
+	return signature[64], r, s
+}
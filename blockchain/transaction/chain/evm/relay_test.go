@@ -0,0 +1,147 @@
+package evm_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+// newRelayTest deploys a PermitToken held by the owner, registers it as a
+// currency and returns a builder whose relayer is the second account.
+func newRelayTest(
+	t *testing.T,
+	currencyID string,
+) (*testChain, *contract, common.Address, *evm.TransactionBuilder, []common.Address) {
+	chain, accounts := newTestChain(t, 3)
+	owner, relayer := accounts[0], accounts[1]
+
+	token := loadContract(t, "PermitToken")
+	supply := evm.ToBaseUnits(decimal.NewFromInt(1000), 18)
+	address := chain.deploy(t, owner, token, "Permit Token", supply)
+
+	domain.RegisterToken(&domain.NetworkCurrency{
+		ID:       currencyID,
+		Scale:    18,
+		Address:  address.Hex(),
+		Network:  domain.Network{Code: domain.TestEth, NativeToken: domain.TestETH, Family: domain.FamilyEvm},
+		Currency: domain.Currency{Code: currencyID},
+	})
+
+	builder := evm.NewTransactionBuilder(chain.client)
+	builder.Relayer = relayer.Hex()
+	builder.Authorizer = evm.NewKeyStoreTransactionSigner(chain.keyStore)
+
+	return chain, token, address, builder, accounts
+}
+
+func relayedRequest(
+	accounts []common.Address,
+	currencyID string,
+	mode transaction.TransferMode,
+) *transaction.TransferRequest {
+	return &transaction.TransferRequest{
+		SourceAddress:      accounts[0].Hex(),
+		DestinationAddress: accounts[2].Hex(),
+		Amount:             decimal.NewFromInt(25),
+		NetworkCurrencyID:  currencyID,
+		Mode:               mode,
+	}
+}
+
+func TestRelayedPermitTransfer(t *testing.T) {
+	chain, token, address, builder, accounts := newRelayTest(t, "PERMIT_TOKEN")
+	owner, relayer, destination := accounts[0], accounts[1], accounts[2]
+	amount := evm.ToBaseUnits(decimal.NewFromInt(25), 18)
+
+	payload, err := chain.transferor(builder).Transfer(context.Background(),
+		relayedRequest(accounts, "PERMIT_TOKEN", transaction.TransferModePermit))
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	if len(payload.Preceding) != 1 {
+		t.Fatalf("Transfer returned %d preceding transactions, want the permit", len(payload.Preceding))
+	}
+
+	for _, txPayload := range payload.Transactions() {
+		if txPayload.Sender != relayer.Hex() {
+			t.Fatalf("transaction sent by %s, want the relayer %s", txPayload.Sender, relayer.Hex())
+		}
+	}
+
+	chain.mine(t, payload)
+
+	if balance := chain.call(t, token, address, "balanceOf", destination).(*big.Int); balance.Cmp(amount) != 0 {
+		t.Fatalf("destination holds %s, want %s", balance, amount)
+	}
+
+	if nonce := chain.call(t, token, address, "nonces", owner).(*big.Int); nonce.Int64() != 1 {
+		t.Fatalf("permit nonce of the owner is %s, want 1", nonce)
+	}
+
+	// the permit was used up by transferFrom, so the next transfer needs another
+	payload, err = chain.transferor(builder).Transfer(context.Background(),
+		relayedRequest(accounts, "PERMIT_TOKEN", transaction.TransferModePermit))
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	chain.mine(t, payload)
+
+	if nonce := chain.call(t, token, address, "nonces", owner).(*big.Int); nonce.Int64() != 2 {
+		t.Fatalf("permit nonce of the owner is %s, want 2", nonce)
+	}
+}
+
+func TestRelayedPermitTransferSkipsPermitWithAllowance(t *testing.T) {
+	chain, token, address, builder, accounts := newRelayTest(t, "ALLOWANCE_TOKEN")
+	owner, relayer := accounts[0], accounts[1]
+
+	chain.transact(t, owner, token, address, "approve", relayer, evm.ToBaseUnits(decimal.NewFromInt(100), 18))
+
+	payload, err := chain.transferor(builder).Transfer(context.Background(),
+		relayedRequest(accounts, "ALLOWANCE_TOKEN", transaction.TransferModePermit))
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	if len(payload.Preceding) != 0 {
+		t.Fatalf("Transfer returned %d preceding transactions, want none", len(payload.Preceding))
+	}
+
+	chain.mine(t, payload)
+
+	if nonce := chain.call(t, token, address, "nonces", owner).(*big.Int); nonce.Sign() != 0 {
+		t.Fatalf("permit nonce of the owner is %s, want 0", nonce)
+	}
+}
+
+func TestRelayedAuthorizationTransfer(t *testing.T) {
+	chain, token, address, builder, accounts := newRelayTest(t, "AUTHORIZATION_TOKEN")
+	owner, destination := accounts[0], accounts[2]
+	amount := evm.ToBaseUnits(decimal.NewFromInt(25), 18)
+
+	payload, err := chain.transferor(builder).Transfer(context.Background(),
+		relayedRequest(accounts, "AUTHORIZATION_TOKEN", transaction.TransferModeAuthorization))
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	chain.mine(t, payload)
+
+	if balance := chain.call(t, token, address, "balanceOf", destination).(*big.Int); balance.Cmp(amount) != 0 {
+		t.Fatalf("destination holds %s, want %s", balance, amount)
+	}
+
+	remaining := evm.ToBaseUnits(decimal.NewFromInt(975), 18)
+	if balance := chain.call(t, token, address, "balanceOf", owner).(*big.Int); balance.Cmp(remaining) != 0 {
+		t.Fatalf("owner holds %s, want %s", balance, remaining)
+	}
+}
@@ -0,0 +1,401 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/telemetry"
+)
+
+// SafeOperationCall is the operation of Safe transactions built here.
+// Delegate calls run foreign code in the context of the Safe and are not
+// built.
+const SafeOperationCall uint8 = 0
+
+// safeDefinition covers the Safe (v1.3.0 and later) methods used to build and
+// execute Safe transactions.
+const safeDefinition = `[
+	{"type":"function","name":"nonce","stateMutability":"view","inputs":[],"outputs":[{"type":"uint256"}]},
+	{"type":"function","name":"getThreshold","stateMutability":"view","inputs":[],"outputs":[{"type":"uint256"}]},
+	{"type":"function","name":"getOwners","stateMutability":"view","inputs":[],"outputs":[{"type":"address[]"}]},
+	{"type":"function","name":"getTransactionHash","stateMutability":"view",
+		"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},
+			{"name":"operation","type":"uint8"},{"name":"safeTxGas","type":"uint256"},
+			{"name":"baseGas","type":"uint256"},{"name":"gasPrice","type":"uint256"},
+			{"name":"gasToken","type":"address"},{"name":"refundReceiver","type":"address"},
+			{"name":"_nonce","type":"uint256"}],
+		"outputs":[{"type":"bytes32"}]},
+	{"type":"function","name":"execTransaction","stateMutability":"payable",
+		"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},
+			{"name":"operation","type":"uint8"},{"name":"safeTxGas","type":"uint256"},
+			{"name":"baseGas","type":"uint256"},{"name":"gasPrice","type":"uint256"},
+			{"name":"gasToken","type":"address"},{"name":"refundReceiver","type":"address"},
+			{"name":"signatures","type":"bytes"}],
+		"outputs":[{"type":"bool"}]}
+]`
+
+var safeABI = mustParseABI(safeDefinition)
+
+var safeTxTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"SafeTx": {
+		{Name: "to", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "data", Type: "bytes"},
+		{Name: "operation", Type: "uint8"},
+		{Name: "safeTxGas", Type: "uint256"},
+		{Name: "baseGas", Type: "uint256"},
+		{Name: "gasPrice", Type: "uint256"},
+		{Name: "gasToken", Type: "address"},
+		{Name: "refundReceiver", Type: "address"},
+		{Name: "nonce", Type: "uint256"},
+	},
+}
+
+type SafeThresholdNotMetError struct {
+	Safe       string
+	Threshold  uint64
+	Signatures int
+}
+
+func (e SafeThresholdNotMetError) Error() string {
+	return fmt.Sprintf("safe %s requires %d owner signatures, got %d", e.Safe, e.Threshold, e.Signatures)
+}
+
+// SafeTransaction is a transaction of a Safe, executed by the Safe once enough
+// owners signed its hash. Gas refunds are not used, so the executor pays the
+// gas of execTransaction like any transaction.
+type SafeTransaction struct {
+	Safe      common.Address
+	ChainID   *big.Int
+	To        common.Address
+	Value     *big.Int
+	Data      []byte
+	Operation uint8
+	Nonce     *big.Int
+}
+
+// TypedData returns the EIP-712 document that owners sign.
+func (safeTx *SafeTransaction) TypedData() apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       safeTxTypes,
+		PrimaryType: "SafeTx",
+		Domain: apitypes.TypedDataDomain{
+			ChainId:           (*math.HexOrDecimal256)(safeTx.ChainID),
+			VerifyingContract: safeTx.Safe.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"to":             safeTx.To.Hex(),
+			"value":          safeTx.Value.String(),
+			"data":           hexutil.Encode(safeTx.Data),
+			"operation":      fmt.Sprint(safeTx.Operation),
+			"safeTxGas":      "0",
+			"baseGas":        "0",
+			"gasPrice":       "0",
+			"gasToken":       common.Address{}.Hex(),
+			"refundReceiver": common.Address{}.Hex(),
+			"nonce":          safeTx.Nonce.String(),
+		},
+	}
+}
+
+// Hash returns the safeTxHash, the EIP-712 digest of the transaction.
+func (safeTx *SafeTransaction) Hash() (common.Hash, error) {
+	digest, _, err := apitypes.TypedDataAndHash(safeTx.TypedData())
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to hash safe transaction: %w", err)
+	}
+
+	return common.BytesToHash(digest), nil
+}
+
+// ExecData returns the execTransaction calldata with the signatures, which
+// must be sorted by owner address.
+func (safeTx *SafeTransaction) ExecData(signatures []byte) ([]byte, error) {
+	data, err := safeABI.Pack("execTransaction",
+		safeTx.To, safeTx.Value, safeTx.Data, safeTx.Operation, big.NewInt(0), big.NewInt(0), big.NewInt(0),
+		common.Address{}, common.Address{}, signatures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode execTransaction: %w", err)
+	}
+
+	return data, nil
+}
+
+// SafeTransactionBuilder builds transfers from Safe smart accounts: the
+// transfer becomes a Safe transaction, signed by owners until the threshold
+// of the Safe is met, which the executor submits with execTransaction. The
+// source address of the transfer is the Safe, the sender of the payload the
+// executor.
+type SafeTransactionBuilder struct {
+	builder *TransactionBuilder
+	// executor sends execTransaction and pays its gas. Any address can
+	// execute a Safe transaction with enough signatures.
+	executor string
+	// owners sign in order. Owners that the Safe does not list are skipped.
+	owners      []string
+	ownerSigner transaction.MessageSigner
+}
+
+var _ transaction.Builder = (*SafeTransactionBuilder)(nil)
+
+func NewSafeTransactionBuilder(
+	client *Client,
+	executor string,
+	owners []string,
+	ownerSigner transaction.MessageSigner,
+) *SafeTransactionBuilder {
+	return &SafeTransactionBuilder{
+		builder:     NewTransactionBuilder(client),
+		executor:    executor,
+		owners:      owners,
+		ownerSigner: ownerSigner,
+	}
+}
+
+func (builder *SafeTransactionBuilder) Build(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.TransferPayload, error) {
+	ctx, span := telemetry.Start(ctx, "evm.build_safe", transaction.TransferAttributes(param)...)
+	defer span.End()
+
+	payload, err := builder.build(ctx, param)
+	span.RecordError(err)
+
+	return payload, err
+}
+
+func (builder *SafeTransactionBuilder) build(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.TransferPayload, error) {
+	switch mode := param.TransferMode(); {
+	case mode != transaction.TransferModeDirect:
+		return nil, transaction.TransferModeNotSupportedError{Mode: mode, Reason: "safe transfers are executed directly"}
+	case param.Deploy != nil:
+		return nil, fmt.Errorf("safe %s cannot deploy contracts", param.SourceAddress)
+	}
+
+	networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	safeTx, err := builder.safeTransaction(ctx, param, networkCurrency)
+	if err != nil {
+
+		return nil, err
+	}
+
+	signatures, err := builder.collectSignatures(ctx, param, safeTx, networkCurrency.Network.Code)
+	if err != nil {
+
+		return nil, err
+	}
+
+	data, err := safeTx.ExecData(signatures)
+	if err != nil {
+
+		return nil, err
+	}
+
+	// the executor calls the Safe; the transfer itself happens inside
+	execution := *param
+	execution.SourceAddress = builder.executor
+	execution.DestinationAddress = safeTx.Safe.Hex()
+	execution.Amount = decimal.Zero
+	execution.NetworkCurrencyID = networkCurrency.Network.NativeToken
+	execution.Call = &transaction.ContractCall{ABI: []byte(safeDefinition), Data: data}
+	execution.NFT = nil
+
+	payload, err := builder.builder.Build(ctx, &execution)
+	if err != nil {
+
+		return nil, err
+	}
+
+	payload.Req = param
+	payload.Sender = builder.executor
+
+	return payload, nil
+}
+
+// safeTransaction returns the Safe transaction that makes the transfer, with
+// the current nonce of the Safe.
+func (builder *SafeTransactionBuilder) safeTransaction(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+	networkCurrency *domain.NetworkCurrency,
+) (*SafeTransaction, error) {
+	client := builder.builder.client
+	safe := common.HexToAddress(param.SourceAddress)
+
+	chainID, err := call(ctx, client, "eth_chainId", client.Delegate.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve chain ID: %w", ClassifyError(err))
+	}
+
+	nonce, err := callView[*big.Int](ctx, builder.builder, &safeABI, safe, "nonce")
+	if err != nil {
+
+		return nil, err
+	}
+
+	safeTx := &SafeTransaction{
+		Safe:      safe,
+		ChainID:   chainID,
+		Operation: SafeOperationCall,
+		Nonce:     nonce,
+	}
+
+	safeTx.To, safeTx.Value, safeTx.Data, err = builder.builder.accountCall(ctx, param, networkCurrency, safe)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return safeTx, nil
+}
+
+// collectSignatures has the configured owners sign the Safe transaction until
+// the threshold is met and returns the signatures sorted by owner, as
+// execTransaction expects.
+func (builder *SafeTransactionBuilder) collectSignatures(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+	safeTx *SafeTransaction,
+	networkCode string,
+) ([]byte, error) {
+	threshold, err := callView[*big.Int](ctx, builder.builder, &safeABI, safeTx.Safe, "getThreshold")
+	if err != nil {
+
+		return nil, err
+	}
+
+	safeOwners, err := callView[[]common.Address](ctx, builder.builder, &safeABI, safeTx.Safe, "getOwners")
+	if err != nil {
+
+		return nil, err
+	}
+
+	hash, err := builder.verifyHash(ctx, safeTx)
+	if err != nil {
+
+		return nil, err
+	}
+
+	document, err := json.Marshal(safeTx.TypedData())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal safe transaction: %w", err)
+	}
+
+	listed := make(map[common.Address]bool, len(safeOwners))
+	for _, owner := range safeOwners {
+		listed[owner] = true
+	}
+
+	signatures := make(map[common.Address][]byte)
+
+	for _, ownerAddress := range builder.owners {
+		owner := common.HexToAddress(ownerAddress)
+
+		if uint64(len(signatures)) >= threshold.Uint64() {
+			break
+		}
+
+		if !listed[owner] {
+			slog.Log(ctx, slog.LevelWarn, "skipping signer that is not an owner of the safe:",
+				"safe", safeTx.Safe.Hex(), "owner", owner.Hex())
+
+			continue
+		}
+
+		if signatures[owner] != nil {
+			continue
+		}
+
+		signature, err := builder.ownerSigner.SignTypedData(ctx, &transaction.MessageRequest{
+			ID:          param.ID,
+			Address:     owner.Hex(),
+			NetworkCode: networkCode,
+			TypedData:   document,
+			Principal:   param.Principal,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign safe transaction as owner %s: %w", owner.Hex(), err)
+		}
+
+		if !bytes.Equal(signature.Digest, hash.Bytes()) {
+			return nil, fmt.Errorf("owner %s signed %x instead of safe transaction %s", owner.Hex(), signature.Digest, hash)
+		}
+
+		signatures[owner] = signature.Signature
+	}
+
+	if uint64(len(signatures)) < threshold.Uint64() {
+		return nil, SafeThresholdNotMetError{
+			Safe:       safeTx.Safe.Hex(),
+			Threshold:  threshold.Uint64(),
+			Signatures: len(signatures),
+		}
+	}
+
+	owners := make([]common.Address, 0, len(signatures))
+	for owner := range signatures {
+		owners = append(owners, owner)
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		return bytes.Compare(owners[i].Bytes(), owners[j].Bytes()) < 0
+	})
+
+	var packed []byte
+	for _, owner := range owners {
+		packed = append(packed, signatures[owner]...)
+	}
+
+	return packed, nil
+}
+
+// verifyHash computes the safeTxHash and checks it against the Safe, as owner
+// signatures over any other hash would be rejected on chain.
+func (builder *SafeTransactionBuilder) verifyHash(ctx context.Context, safeTx *SafeTransaction) (common.Hash, error) {
+	hash, err := safeTx.Hash()
+	if err != nil {
+
+		return common.Hash{}, err
+	}
+
+	expected, err := callView[[32]byte](ctx, builder.builder, &safeABI, safeTx.Safe, "getTransactionHash",
+		safeTx.To, safeTx.Value, safeTx.Data, safeTx.Operation, big.NewInt(0), big.NewInt(0), big.NewInt(0),
+		common.Address{}, common.Address{}, safeTx.Nonce)
+	if err != nil {
+
+		return common.Hash{}, err
+	}
+
+	if hash != common.Hash(expected) {
+		return common.Hash{}, fmt.Errorf(
+			"safe transaction hash %s does not match %s of safe %s", hash, common.Hash(expected), safeTx.Safe.Hex())
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,105 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+// newSafeTest deploys a 2-of-3 Safe of the first three accounts holding 10
+// ETH. The fourth account executes, the fifth receives.
+func newSafeTest(t *testing.T) (*testChain, *contract, common.Address, []common.Address) {
+	chain, accounts := newTestChain(t, 5)
+
+	safe := loadContract(t, "TestSafe")
+	address := chain.deploy(t, accounts[3], safe, accounts[:3], big.NewInt(2))
+
+	// native transfers are built with 21000 gas, too little for receive()
+	opts := chain.transactOpts(t, accounts[3])
+	opts.Value = evm.ToBaseUnits(decimal.NewFromInt(10), 18)
+
+	if _, err := bind.NewBoundContract(address, safe.ABI, nil, chain.backend.Client(), nil).Transfer(opts); err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	chain.backend.Commit()
+
+	return chain, safe, address, accounts
+}
+
+func TestSafeTransfer(t *testing.T) {
+	chain, safe, address, accounts := newSafeTest(t)
+	executor, recipient := accounts[3], accounts[4]
+
+	// the recipient is listed but not an owner, so it is skipped
+	owners := []string{recipient.Hex(), accounts[2].Hex(), accounts[0].Hex(), accounts[1].Hex()}
+	builder := evm.NewSafeTransactionBuilder(chain.client, executor.Hex(), owners,
+		evm.NewKeyStoreTransactionSigner(chain.keyStore))
+
+	before, err := chain.backend.Client().BalanceAt(context.Background(), recipient, nil)
+	if err != nil {
+		t.Fatalf("BalanceAt: %v", err)
+	}
+
+	payload, err := chain.transferor(builder).Transfer(context.Background(), &transaction.TransferRequest{
+		SourceAddress:      address.Hex(),
+		DestinationAddress: recipient.Hex(),
+		Amount:             decimal.NewFromInt(1),
+		NetworkCurrencyID:  domain.TestETH,
+	})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	if payload.Sender != executor.Hex() {
+		t.Fatalf("transaction sent by %s, want the executor %s", payload.Sender, executor.Hex())
+	}
+
+	chain.mine(t, payload)
+
+	after, err := chain.backend.Client().BalanceAt(context.Background(), recipient, nil)
+	if err != nil {
+		t.Fatalf("BalanceAt: %v", err)
+	}
+
+	received := new(big.Int).Sub(after, before)
+	if want := evm.ToBaseUnits(decimal.NewFromInt(1), 18); received.Cmp(want) != 0 {
+		t.Fatalf("recipient received %s, want %s", received, want)
+	}
+
+	if nonce := chain.call(t, safe, address, "nonce").(*big.Int); nonce.Int64() != 1 {
+		t.Fatalf("nonce of the safe is %s, want 1", nonce)
+	}
+}
+
+func TestSafeTransferBelowThreshold(t *testing.T) {
+	chain, _, address, accounts := newSafeTest(t)
+
+	builder := evm.NewSafeTransactionBuilder(chain.client, accounts[3].Hex(), []string{accounts[0].Hex()},
+		evm.NewKeyStoreTransactionSigner(chain.keyStore))
+
+	_, err := chain.transferor(builder).Transfer(context.Background(), &transaction.TransferRequest{
+		SourceAddress:      address.Hex(),
+		DestinationAddress: accounts[4].Hex(),
+		Amount:             decimal.NewFromInt(1),
+		NetworkCurrencyID:  domain.TestETH,
+	})
+
+	var thresholdErr evm.SafeThresholdNotMetError
+	if !errors.As(err, &thresholdErr) {
+		t.Fatalf("Transfer returned %v, want SafeThresholdNotMetError", err)
+	}
+
+	if thresholdErr.Threshold != 2 || thresholdErr.Signatures != 1 {
+		t.Fatalf("Transfer returned %+v, want 1 of 2 signatures", thresholdErr)
+	}
+}
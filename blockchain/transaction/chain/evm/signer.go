@@ -2,13 +2,14 @@ package evm
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"fmt"
-	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 
 	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/telemetry"
 )
 
 type PrivKeyTransactionSigner struct {
@@ -24,22 +25,67 @@ func NewPrvKeyTransactionSigner(privateKey string) *PrivKeyTransactionSigner {
 }
 
 func (signer *PrivKeyTransactionSigner) Sign(
-	_ context.Context,
+	ctx context.Context,
 	payload *transaction.TransferPayload,
 ) error {
-	txn, err := Unmarshal(payload.Raw)
+	_, span := startSignSpan(ctx, payload)
+	defer span.End()
+
+	ecdsaPrivateKey, err := parsePrivateKey(signer.privateKey)
 	if err != nil {
+		span.RecordError(err)
+
 		return err
 	}
 
-	privateKey := signer.privateKey
-	if strings.HasPrefix(privateKey, "0x") || strings.HasPrefix(privateKey, "0X") {
-		privateKey = privateKey[2:]
+	err = signWithKey(payload, ecdsaPrivateKey)
+	span.RecordError(err)
+
+	return err
+}
+
+// KeyStoreTransactionSigner signs with the key of the transfer's source
+// address, so one signer serves every wallet of a provider.
+type KeyStoreTransactionSigner struct {
+	keyStore *KeyStore
+}
+
+var _ transaction.Signer = (*KeyStoreTransactionSigner)(nil)
+
+func NewKeyStoreTransactionSigner(keyStore *KeyStore) *KeyStoreTransactionSigner {
+	return &KeyStoreTransactionSigner{
+		keyStore: keyStore,
 	}
+}
+
+func (signer *KeyStoreTransactionSigner) Sign(
+	ctx context.Context,
+	payload *transaction.TransferPayload,
+) error {
+	_, span := startSignSpan(ctx, payload)
+	defer span.End()
 
-	ecdsaPrivateKey, err := crypto.HexToECDSA(privateKey)
+	ecdsaPrivateKey, err := signer.keyStore.Key(common.HexToAddress(payload.SenderAddress()))
 	if err != nil {
-		return fmt.Errorf("failed to convert key: %w", err)
+		span.RecordError(err)
+
+		return err
+	}
+
+	err = signWithKey(payload, ecdsaPrivateKey)
+	span.RecordError(err)
+
+	return err
+}
+
+func startSignSpan(ctx context.Context, payload *transaction.TransferPayload) (context.Context, *telemetry.Span) {
+	return telemetry.Start(ctx, "evm.sign", transaction.TransferAttributes(payload.Req)...)
+}
+
+func signWithKey(payload *transaction.TransferPayload, ecdsaPrivateKey *ecdsa.PrivateKey) error {
+	txn, err := Unmarshal(payload.Raw)
+	if err != nil {
+		return err
 	}
 
 	signedTx, err := types.SignTx(txn, types.NewLondonSigner(txn.ChainId()), ecdsaPrivateKey)
@@ -0,0 +1,217 @@
+package evm_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+)
+
+// testChain is a simulated chain whose accounts are funded with 100 ETH and
+// held by keyStore.
+type testChain struct {
+	backend  *simulated.Backend
+	client   *evm.Client
+	keyStore *evm.KeyStore
+	keys     map[common.Address]*ecdsa.PrivateKey
+}
+
+func newTestChain(t *testing.T, accounts int) (*testChain, []common.Address) {
+	t.Helper()
+
+	chain := &testChain{
+		keyStore: evm.NewKeyStore(),
+		keys:     make(map[common.Address]*ecdsa.PrivateKey),
+	}
+
+	alloc := types.GenesisAlloc{}
+	addresses := make([]common.Address, accounts)
+
+	for index := range addresses {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+
+		address, err := chain.keyStore.Import(hexutil.Encode(crypto.FromECDSA(key)))
+		if err != nil {
+			t.Fatalf("Import: %v", err)
+		}
+
+		alloc[address] = types.Account{Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))}
+		chain.keys[address] = key
+		addresses[index] = address
+	}
+
+	chain.backend = simulated.NewBackend(alloc)
+	chain.client = evm.NewBackendClient(chain.backend.Client(), "simulated")
+
+	t.Cleanup(func() {
+		chain.backend.Close()
+	})
+
+	return chain, addresses
+}
+
+// transferor sends transfers built by builder with the keys of the chain.
+func (chain *testChain) transferor(builder transaction.Builder) *transaction.GenericTranferor {
+	return transaction.NewGenericTransferor(
+		builder,
+		evm.NewKeyStoreTransactionSigner(chain.keyStore),
+		evm.NewTransactionBroadcaster(chain.client),
+	)
+}
+
+// mine commits the pending transactions and fails unless every given
+// transaction succeeded.
+func (chain *testChain) mine(t *testing.T, payloads ...*transaction.TransferPayload) {
+	t.Helper()
+
+	chain.backend.Commit()
+
+	for _, payload := range payloads {
+		for _, txPayload := range payload.Transactions() {
+			receipt, err := chain.backend.Client().TransactionReceipt(context.Background(), common.HexToHash(txPayload.ID))
+			if err != nil {
+				t.Fatalf("TransactionReceipt(%s): %v", txPayload.ID, err)
+			}
+
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				t.Fatalf("transaction %s reverted", txPayload.ID)
+			}
+		}
+	}
+}
+
+// contract is a compiled contract of testdata, see the .sol file of the same
+// name for its source. The JSON holds the abi and bin of solc 0.8.30 with the
+// optimizer at 200 runs for the paris EVM, which the simulated chain runs.
+type contract struct {
+	ABI abi.ABI
+	// JSON is the ABI as compiled, the format taken by transfer requests.
+	JSON     json.RawMessage
+	Bytecode []byte
+}
+
+func loadContract(t *testing.T, name string) *contract {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	var artifact struct {
+		ABI json.RawMessage `json:"abi"`
+		Bin string          `json:"bin"`
+	}
+
+	if err := json.Unmarshal(content, &artifact); err != nil {
+		t.Fatalf("Unmarshal %s: %v", name, err)
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(string(artifact.ABI)))
+	if err != nil {
+		t.Fatalf("abi.JSON %s: %v", name, err)
+	}
+
+	return &contract{ABI: parsed, JSON: artifact.ABI, Bytecode: common.FromHex(artifact.Bin)}
+}
+
+// deploy creates the contract from deployer and mines it.
+func (chain *testChain) deploy(
+	t *testing.T,
+	deployer common.Address,
+	compiled *contract,
+	args ...interface{},
+) common.Address {
+	t.Helper()
+
+	address, _, _, err := bind.DeployContract(chain.transactOpts(t, deployer), compiled.ABI, compiled.Bytecode,
+		chain.backend.Client(), args...)
+	if err != nil {
+		t.Fatalf("DeployContract: %v", err)
+	}
+
+	chain.backend.Commit()
+
+	return address
+}
+
+// transact sends a contract call from sender and mines it.
+func (chain *testChain) transact(
+	t *testing.T,
+	sender common.Address,
+	compiled *contract,
+	address common.Address,
+	method string,
+	args ...interface{},
+) {
+	t.Helper()
+
+	bound := bind.NewBoundContract(address, compiled.ABI, nil, chain.backend.Client(), nil)
+
+	txn, err := bound.Transact(chain.transactOpts(t, sender), method, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+
+	chain.backend.Commit()
+
+	receipt, err := chain.backend.Client().TransactionReceipt(context.Background(), txn.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("%s did not succeed: %v", method, err)
+	}
+}
+
+// call returns the first result of a view function.
+func (chain *testChain) call(
+	t *testing.T,
+	compiled *contract,
+	address common.Address,
+	method string,
+	args ...interface{},
+) interface{} {
+	t.Helper()
+
+	bound := bind.NewBoundContract(address, compiled.ABI, chain.backend.Client(), nil, nil)
+
+	var results []interface{}
+	if err := bound.Call(&bind.CallOpts{}, &results, method, args...); err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+
+	return results[0]
+}
+
+func (chain *testChain) transactOpts(t *testing.T, sender common.Address) *bind.TransactOpts {
+	t.Helper()
+
+	chainID, err := chain.backend.Client().ChainID(context.Background())
+	if err != nil {
+		t.Fatalf("ChainID: %v", err)
+	}
+
+	opts, err := bind.NewKeyedTransactorWithChainID(chain.keys[sender], chainID)
+	if err != nil {
+		t.Fatalf("NewKeyedTransactorWithChainID: %v", err)
+	}
+
+	return opts
+}
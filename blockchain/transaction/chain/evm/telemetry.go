@@ -0,0 +1,83 @@
+package evm
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/telemetry"
+)
+
+var (
+	rpcDuration = telemetry.DefaultRegistry.NewHistogram(
+		"evm_rpc_duration_seconds",
+		"Latency of node RPC calls by method, endpoint and outcome.",
+		telemetry.DefaultDurationBuckets,
+		"method", "endpoint", "outcome",
+	)
+	feePaid = telemetry.DefaultRegistry.NewHistogram(
+		"evm_transaction_fee_paid",
+		"Fee paid by mined transactions, in the native token of the network.",
+		[]float64{0.000001, 0.00001, 0.0001, 0.001, 0.01, 0.1, 1},
+		"network",
+	)
+)
+
+// call runs one RPC call of the client in its own span and records its latency.
+func call[T any](
+	ctx context.Context,
+	client *Client,
+	method string,
+	fn func(ctx context.Context) (T, error),
+) (T, error) {
+	return callEndpoint(ctx, client.Endpoint, method, fn)
+}
+
+// callEndpoint is call for RPC endpoints other than nodes, e.g. bundlers.
+func callEndpoint[T any](
+	ctx context.Context,
+	endpoint string,
+	method string,
+	fn func(ctx context.Context) (T, error),
+) (T, error) {
+	ctx, span := telemetry.Start(ctx, "rpc "+method,
+		telemetry.String("rpc.system", "jsonrpc"),
+		telemetry.String("rpc.method", method),
+		telemetry.String("rpc.endpoint", endpoint),
+	)
+	defer span.End()
+
+	start := time.Now()
+
+	result, err := fn(ctx)
+
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+
+	span.RecordError(err)
+	rpcDuration.Observe(time.Since(start).Seconds(), method, endpoint, outcome)
+
+	return result, err
+}
+
+// callNoResult is call for RPC methods that only return an error.
+func callNoResult(ctx context.Context, client *Client, method string, fn func(ctx context.Context) error) error {
+	_, err := call(ctx, client, method, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+
+	return err
+}
+
+// endpointLabel reduces a node URL to its scheme and host, since paths and
+// query strings of hosted nodes often carry API keys.
+func endpointLabel(nodeURL string) string {
+	parsed, err := url.Parse(nodeURL)
+	if err != nil || parsed.Host == "" {
+		return "unknown"
+	}
+
+	return parsed.Scheme + "://" + parsed.Host
+}
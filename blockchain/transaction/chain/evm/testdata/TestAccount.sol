@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+struct UserOperation {
+    address sender;
+    uint256 nonce;
+    bytes initCode;
+    bytes callData;
+    uint256 callGasLimit;
+    uint256 verificationGasLimit;
+    uint256 preVerificationGas;
+    uint256 maxFeePerGas;
+    uint256 maxPriorityFeePerGas;
+    bytes paymasterAndData;
+    bytes signature;
+}
+
+// TestAccount is the subset of SimpleAccount used to test user operations:
+// the owner signs the userOpHash as an EIP-191 message, and the EntryPoint
+// or the owner calls execute. It pays no prefund, as TestEntryPoint charges
+// none.
+contract TestAccount {
+    address public immutable owner;
+    address public immutable entryPoint;
+
+    constructor(address owner_, address entryPoint_) {
+        owner = owner_;
+        entryPoint = entryPoint_;
+    }
+
+    receive() external payable {}
+
+    function validateUserOp(UserOperation calldata userOp, bytes32 userOpHash, uint256)
+        external
+        view
+        returns (uint256)
+    {
+        require(msg.sender == entryPoint, "account: not from EntryPoint");
+        bytes calldata signature = userOp.signature;
+        if (signature.length != 65) {
+            return 1;
+        }
+        bytes32 digest = keccak256(abi.encodePacked("\x19Ethereum Signed Message:\n32", userOpHash));
+        address signer = ecrecover(digest, uint8(signature[64]), bytes32(signature[:32]), bytes32(signature[32:64]));
+        return signer == owner ? 0 : 1;
+    }
+
+    function execute(address dest, uint256 value, bytes calldata func) external {
+        require(msg.sender == entryPoint || msg.sender == owner, "account: not Owner or EntryPoint");
+        (bool success, bytes memory result) = dest.call{value: value}(func);
+        if (!success) {
+            assembly {
+                revert(add(result, 32), mload(result))
+            }
+        }
+    }
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+struct UserOperation {
+    address sender;
+    uint256 nonce;
+    bytes initCode;
+    bytes callData;
+    uint256 callGasLimit;
+    uint256 verificationGasLimit;
+    uint256 preVerificationGas;
+    uint256 maxFeePerGas;
+    uint256 maxPriorityFeePerGas;
+    bytes paymasterAndData;
+    bytes signature;
+}
+
+interface IAccount {
+    function validateUserOp(UserOperation calldata userOp, bytes32 userOpHash, uint256 missingAccountFunds)
+        external
+        returns (uint256 validationData);
+}
+
+// TestEntryPoint is the subset of the v0.6 EntryPoint used to test user
+// operations: the same userOpHash and sequential nonces of key 0, validation
+// by the account and its call. Deposits, gas payment, paymasters, aggregators
+// and account creation are left out.
+contract TestEntryPoint {
+    mapping(address => uint256) private nonces;
+
+    event UserOperationEvent(
+        bytes32 indexed userOpHash,
+        address indexed sender,
+        address indexed paymaster,
+        uint256 nonce,
+        bool success,
+        uint256 actualGasCost,
+        uint256 actualGasUsed
+    );
+
+    function getNonce(address sender, uint192 key) external view returns (uint256) {
+        require(key == 0, "only key 0 is supported");
+        return nonces[sender];
+    }
+
+    function getUserOpHash(UserOperation calldata userOp) public view returns (bytes32) {
+        bytes32 packed = keccak256(abi.encode(
+            userOp.sender, userOp.nonce, keccak256(userOp.initCode), keccak256(userOp.callData),
+            userOp.callGasLimit, userOp.verificationGasLimit, userOp.preVerificationGas, userOp.maxFeePerGas,
+            userOp.maxPriorityFeePerGas, keccak256(userOp.paymasterAndData)));
+        return keccak256(abi.encode(packed, address(this), block.chainid));
+    }
+
+    function handleOps(UserOperation[] calldata ops, address payable beneficiary) external {
+        require(beneficiary != address(0), "AA90 invalid beneficiary");
+        for (uint256 i = 0; i < ops.length; i++) {
+            uint256 gasStart = gasleft();
+            UserOperation calldata userOp = ops[i];
+            require(userOp.initCode.length == 0, "AA10 account creation is not supported");
+            require(userOp.paymasterAndData.length == 0, "AA30 paymasters are not supported");
+            require(userOp.nonce == nonces[userOp.sender]++, "AA25 invalid account nonce");
+
+            bytes32 userOpHash = getUserOpHash(userOp);
+            uint256 validationData = IAccount(userOp.sender).validateUserOp{gas: userOp.verificationGasLimit}(
+                userOp, userOpHash, 0);
+            require(validationData == 0, "AA24 signature error");
+
+            (bool success,) = userOp.sender.call{gas: userOp.callGasLimit}(userOp.callData);
+
+            uint256 gasUsed = gasStart - gasleft() + userOp.preVerificationGas;
+            emit UserOperationEvent(
+                userOpHash, userOp.sender, address(0), userOp.nonce, success, gasUsed * tx.gasprice, gasUsed);
+        }
+    }
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+// TestSafe is the subset of a Safe v1.3.0 used to test Safe transfers: the
+// same safeTxHash, and execTransaction with ECDSA owner signatures sorted by
+// owner. Delegate calls, gas refunds, eth_sign, contract and approved hash
+// signatures and owner management are left out.
+contract TestSafe {
+    bytes32 private constant DOMAIN_SEPARATOR_TYPEHASH =
+        keccak256("EIP712Domain(uint256 chainId,address verifyingContract)");
+    bytes32 private constant SAFE_TX_TYPEHASH = keccak256(
+        "SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)");
+
+    address[] private owners;
+    mapping(address => bool) public isOwner;
+    uint256 private threshold;
+    uint256 public nonce;
+
+    event ExecutionSuccess(bytes32 txHash, uint256 payment);
+
+    constructor(address[] memory owners_, uint256 threshold_) {
+        require(threshold_ > 0 && threshold_ <= owners_.length, "invalid threshold");
+        for (uint256 i = 0; i < owners_.length; i++) {
+            require(owners_[i] != address(0) && !isOwner[owners_[i]], "invalid owner");
+            isOwner[owners_[i]] = true;
+        }
+        owners = owners_;
+        threshold = threshold_;
+    }
+
+    receive() external payable {}
+
+    function getOwners() external view returns (address[] memory) {
+        return owners;
+    }
+
+    function getThreshold() external view returns (uint256) {
+        return threshold;
+    }
+
+    function domainSeparator() public view returns (bytes32) {
+        return keccak256(abi.encode(DOMAIN_SEPARATOR_TYPEHASH, block.chainid, address(this)));
+    }
+
+    function getTransactionHash(
+        address to,
+        uint256 value,
+        bytes calldata data,
+        uint8 operation,
+        uint256 safeTxGas,
+        uint256 baseGas,
+        uint256 gasPrice,
+        address gasToken,
+        address refundReceiver,
+        uint256 _nonce
+    ) public view returns (bytes32) {
+        bytes32 safeTxHash = keccak256(abi.encode(
+            SAFE_TX_TYPEHASH, to, value, keccak256(data), operation, safeTxGas, baseGas, gasPrice, gasToken,
+            refundReceiver, _nonce));
+        return keccak256(abi.encodePacked(bytes1(0x19), bytes1(0x01), domainSeparator(), safeTxHash));
+    }
+
+    function execTransaction(
+        address to,
+        uint256 value,
+        bytes calldata data,
+        uint8 operation,
+        uint256 safeTxGas,
+        uint256 baseGas,
+        uint256 gasPrice,
+        address gasToken,
+        address payable refundReceiver,
+        bytes calldata signatures
+    ) external payable returns (bool) {
+        require(operation == 0, "only calls are supported");
+        require(gasPrice == 0, "gas refunds are not supported");
+        bytes32 txHash = getTransactionHash(
+            to, value, data, operation, safeTxGas, baseGas, gasPrice, gasToken, refundReceiver, nonce);
+        nonce++;
+        checkSignatures(txHash, signatures);
+        (bool success,) = to.call{value: value}(data);
+        require(success, "GS013");
+        emit ExecutionSuccess(txHash, 0);
+        return true;
+    }
+
+    function checkSignatures(bytes32 txHash, bytes calldata signatures) private view {
+        require(signatures.length >= threshold * 65, "GS020");
+        address last = address(0);
+        for (uint256 i = 0; i < threshold; i++) {
+            bytes32 r = bytes32(signatures[i * 65:i * 65 + 32]);
+            bytes32 s = bytes32(signatures[i * 65 + 32:i * 65 + 64]);
+            uint8 v = uint8(signatures[i * 65 + 64]);
+            require(v == 27 || v == 28, "unsupported signature type");
+            address owner = ecrecover(txHash, v, r, s);
+            require(owner > last && isOwner[owner], "GS026");
+            last = owner;
+        }
+    }
+}
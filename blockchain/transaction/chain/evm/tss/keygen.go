@@ -0,0 +1,280 @@
+package tss
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// KeyShare is what one party keeps of a generated key. Secret and Paillier
+// must stay with the party; the rest is known to every party.
+type KeyShare struct {
+	Party     int `json:"party"`
+	Parties   int `json:"parties"`
+	Threshold int `json:"threshold"`
+	// Secret is the share of the private key, a point of a polynomial of
+	// degree Threshold-1 whose value at 0 is the private key.
+	Secret    *big.Int `json:"secret"`
+	PublicKey *Point   `json:"public_key"`
+	// PublicShares are the secrets of the parties times the generator.
+	PublicShares map[int]*Point             `json:"public_shares"`
+	Paillier     *PaillierPrivateKey        `json:"paillier"`
+	PaillierKeys map[int]*PaillierPublicKey `json:"paillier_keys"`
+}
+
+// Address returns the address of the shared key.
+func (share *KeyShare) Address() common.Address {
+	return crypto.PubkeyToAddress(ecdsa.PublicKey{Curve: curve, X: share.PublicKey.X, Y: share.PublicKey.Y})
+}
+
+// KeygenCommitment is broadcast in the first round of key generation. It
+// commits to the Feldman commitments of the party's polynomial.
+type KeygenCommitment struct {
+	From       int                `json:"from"`
+	Commitment []byte             `json:"commitment"`
+	Paillier   *PaillierPublicKey `json:"paillier"`
+}
+
+// KeygenDecommitment is broadcast in the second round. It opens the
+// commitment and proves knowledge of the party's secret, the value of its
+// polynomial at 0.
+type KeygenDecommitment struct {
+	From         int           `json:"from"`
+	Blind        []byte        `json:"blind"`
+	Coefficients []*Point      `json:"coefficients"`
+	Proof        *SchnorrProof `json:"proof"`
+}
+
+// KeygenShare is sent to one party in the second round: the value of the
+// sender's polynomial at the index of the receiver.
+type KeygenShare struct {
+	From  int      `json:"from"`
+	To    int      `json:"to"`
+	Share *big.Int `json:"share"`
+}
+
+// KeygenParty runs the distributed key generation of one party: each party
+// deals a secret with Feldman verifiable secret sharing, the private key being
+// the sum of the secrets and the share of a party the sum of what it is dealt.
+type KeygenParty struct {
+	party     int
+	parties   int
+	threshold int
+
+	coefficients []*big.Int
+	commitments  []*Point
+	blind        []byte
+	paillier     *PaillierPrivateKey
+	received     map[int]*KeygenCommitment
+}
+
+// NewKeygenParty returns party, indexed from 1, of parties any threshold of
+// which sign together.
+func NewKeygenParty(party int, parties int, threshold int) (*KeygenParty, error) {
+	if threshold < 1 || threshold > parties {
+		return nil, fmt.Errorf("threshold %d is not between 1 and the %d parties", threshold, parties)
+	}
+
+	if party < 1 || party > parties {
+		return nil, fmt.Errorf("party %d is not between 1 and %d", party, parties)
+	}
+
+	return &KeygenParty{
+		party:     party,
+		parties:   parties,
+		threshold: threshold,
+	}, nil
+}
+
+// Round1 picks the party's polynomial and Paillier key.
+func (party *KeygenParty) Round1() (*KeygenCommitment, error) {
+	paillier, err := generatePaillierKey()
+	if err != nil {
+
+		return nil, err
+	}
+
+	party.paillier = paillier
+	party.coefficients = make([]*big.Int, party.threshold)
+	party.commitments = make([]*Point, party.threshold)
+
+	for index := range party.coefficients {
+		coefficient, err := randomScalar()
+		if err != nil {
+
+			return nil, err
+		}
+
+		party.coefficients[index] = coefficient
+		party.commitments[index] = basePoint(coefficient)
+	}
+
+	commitmentValue, blind, err := commit(party.commitments...)
+	if err != nil {
+
+		return nil, err
+	}
+
+	party.blind = blind
+
+	return &KeygenCommitment{
+		From:       party.party,
+		Commitment: commitmentValue,
+		Paillier:   paillier.PublicKey,
+	}, nil
+}
+
+// Round2 takes the commitments of every party, its own included, and deals
+// the shares of the party's secret.
+func (party *KeygenParty) Round2(commitments []*KeygenCommitment) (*KeygenDecommitment, []*KeygenShare, error) {
+	received, err := byParty(commitments, func(message *KeygenCommitment) int { return message.From },
+		partyRange(party.parties))
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	moduli := make(map[string]int, len(received))
+
+	for from, message := range received {
+		if !message.Paillier.valid() {
+			return nil, nil, AbortError{Party: from, Reason: "invalid Paillier key"}
+		}
+
+		if other, ok := moduli[message.Paillier.N.String()]; ok {
+			return nil, nil, AbortError{Party: from, Reason: fmt.Sprintf("Paillier key of party %d", other)}
+		}
+
+		moduli[message.Paillier.N.String()] = from
+	}
+
+	party.received = received
+
+	proof, err := proveKnowledge(party.party, party.coefficients[0], party.commitments[0])
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	shares := make([]*KeygenShare, 0, party.parties-1)
+
+	for _, to := range partyRange(party.parties) {
+		if to == party.party {
+			continue
+		}
+
+		shares = append(shares, &KeygenShare{
+			From:  party.party,
+			To:    to,
+			Share: evaluate(party.coefficients, to),
+		})
+	}
+
+	return &KeygenDecommitment{
+		From:         party.party,
+		Blind:        party.blind,
+		Coefficients: party.commitments,
+		Proof:        proof,
+	}, shares, nil
+}
+
+// Finish takes the decommitments of every party, its own included, and the
+// shares dealt to the party, verifies them and returns the party's share of
+// the key.
+func (party *KeygenParty) Finish(decommitments []*KeygenDecommitment, shares []*KeygenShare) (*KeyShare, error) {
+	everyone := partyRange(party.parties)
+
+	opened, err := byParty(decommitments, func(message *KeygenDecommitment) int { return message.From }, everyone)
+	if err != nil {
+
+		return nil, err
+	}
+
+	dealt, err := byParty(shares, func(message *KeygenShare) int { return message.From }, others(everyone, party.party))
+	if err != nil {
+
+		return nil, err
+	}
+
+	secret := evaluate(party.coefficients, party.party)
+	publicKey := basePoint(new(big.Int))
+
+	for from, message := range opened {
+		if len(message.Coefficients) != party.threshold ||
+			!opens(party.received[from].Commitment, message.Blind, message.Coefficients) {
+			return nil, AbortError{Party: from, Reason: "commitments do not open"}
+		}
+
+		if !message.Proof.verify(from, message.Coefficients[0]) {
+			return nil, AbortError{Party: from, Reason: "invalid proof of knowledge of its secret"}
+		}
+
+		publicKey = publicKey.add(message.Coefficients[0])
+
+		if from == party.party {
+			continue
+		}
+
+		share := dealt[from]
+		if share.To != party.party || share.Share == nil || share.Share.Sign() < 0 || share.Share.Cmp(order) >= 0 ||
+			!basePoint(share.Share).equal(evaluateCommitments(message.Coefficients, party.party)) {
+			return nil, AbortError{Party: from, Reason: "share does not match its commitments"}
+		}
+
+		secret.Add(secret, share.Share)
+	}
+
+	if publicKey.isInfinity() {
+		return nil, AbortError{Reason: "public key is the point at infinity"}
+	}
+
+	keyShare := &KeyShare{
+		Party:        party.party,
+		Parties:      party.parties,
+		Threshold:    party.threshold,
+		Secret:       secret.Mod(secret, order),
+		PublicKey:    publicKey,
+		PublicShares: make(map[int]*Point, party.parties),
+		Paillier:     party.paillier,
+		PaillierKeys: make(map[int]*PaillierPublicKey, party.parties),
+	}
+
+	for _, index := range everyone {
+		publicShare := basePoint(new(big.Int))
+		for _, message := range opened {
+			publicShare = publicShare.add(evaluateCommitments(message.Coefficients, index))
+		}
+
+		keyShare.PublicShares[index] = publicShare
+		keyShare.PaillierKeys[index] = party.received[index].Paillier
+	}
+
+	party.coefficients = nil
+
+	return keyShare, nil
+}
+
+// partyRange returns the indexes of parties, 1 to parties.
+func partyRange(parties int) []int {
+	indexes := make([]int, parties)
+	for index := range indexes {
+		indexes[index] = index + 1
+	}
+
+	return indexes
+}
+
+func others(parties []int, party int) []int {
+	result := make([]int, 0, len(parties))
+
+	for _, other := range parties {
+		if other != party {
+			result = append(result, other)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,207 @@
+package tss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// GenerateKey runs the key generation of parties, any threshold of which sign
+// together, each party in its own goroutine, and returns the shares of the
+// parties in order of their indexes.
+func GenerateKey(ctx context.Context, parties int, threshold int) ([]*KeyShare, error) {
+	keygenParties := make([]*KeygenParty, parties)
+
+	for index := range keygenParties {
+		keygenParty, err := NewKeygenParty(index+1, parties, threshold)
+		if err != nil {
+
+			return nil, err
+		}
+
+		keygenParties[index] = keygenParty
+	}
+
+	commitments := make([]*KeygenCommitment, parties)
+
+	err := inParallel(ctx, parties, func(index int) (err error) {
+		commitments[index], err = keygenParties[index].Round1()
+
+		return err
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	decommitments := make([]*KeygenDecommitment, parties)
+	dealt := make([][]*KeygenShare, parties)
+
+	err = inParallel(ctx, parties, func(index int) (err error) {
+		decommitments[index], dealt[index], err = keygenParties[index].Round2(commitments)
+
+		return err
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	shares := make([]*KeyShare, parties)
+
+	err = inParallel(ctx, parties, func(index int) (err error) {
+		shares[index], err = keygenParties[index].Finish(decommitments,
+			deliver(dealt, index+1, func(share *KeygenShare) int { return share.To }))
+
+		return err
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+// Sign runs the signing of the 32 byte digest by the parties of shares, at
+// least a threshold of the shares of one key, each party in its own
+// goroutine, and returns r and s of the signature.
+func Sign(ctx context.Context, shares []*KeyShare, digest []byte) (*big.Int, *big.Int, error) {
+	if len(shares) == 0 {
+		return nil, nil, errors.New("no shares to sign with")
+	}
+
+	signers := make([]int, len(shares))
+	for index, share := range shares {
+		if !share.PublicKey.equal(shares[0].PublicKey) {
+			return nil, nil, fmt.Errorf("share of party %d is of another key", share.Party)
+		}
+
+		signers[index] = share.Party
+	}
+
+	signingParties := make([]*SigningParty, len(shares))
+
+	for index, share := range shares {
+		signingParty, err := NewSigningParty(share, signers, digest)
+		if err != nil {
+
+			return nil, nil, err
+		}
+
+		signingParties[index] = signingParty
+	}
+
+	commitments := make([]*SigningCommitment, len(shares))
+
+	err := inParallel(ctx, len(shares), func(index int) (err error) {
+		commitments[index], err = signingParties[index].Round1()
+
+		return err
+	})
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	conversions := make([][]*SigningConversion, len(shares))
+
+	err = inParallel(ctx, len(shares), func(index int) (err error) {
+		conversions[index], err = signingParties[index].Round2(commitments)
+
+		return err
+	})
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	deltas := make([]*SigningDelta, len(shares))
+
+	err = inParallel(ctx, len(shares), func(index int) (err error) {
+		deltas[index], err = signingParties[index].Round3(
+			deliver(conversions, signers[index], func(conversion *SigningConversion) int { return conversion.To }))
+
+		return err
+	})
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	decommitments := make([]*SigningDecommitment, len(shares))
+
+	err = inParallel(ctx, len(shares), func(index int) (err error) {
+		decommitments[index], err = signingParties[index].Round4(deltas)
+
+		return err
+	})
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	sShares := make([]*SigningShare, len(shares))
+
+	err = inParallel(ctx, len(shares), func(index int) (err error) {
+		sShares[index], err = signingParties[index].Round5(decommitments)
+
+		return err
+	})
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	// every party arrives at the same signature; the first one returns it
+	return signingParties[0].Finish(sShares)
+}
+
+// inParallel runs a round of count parties, one goroutine each, and returns
+// the error of the first party that failed.
+func inParallel(ctx context.Context, count int, round func(index int) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errs := make([]error, count)
+
+	var wg sync.WaitGroup
+
+	for index := range errs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[index] = round(index)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deliver returns the messages sent by any party to the party to.
+func deliver[M any](sent [][]M, to int, recipient func(M) int) []M {
+	var delivered []M
+
+	for _, messages := range sent {
+		for _, message := range messages {
+			if recipient(message) == to {
+				delivered = append(delivered, message)
+			}
+		}
+	}
+
+	return delivered
+}
@@ -0,0 +1,129 @@
+package tss
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// paillierPrimeBits is the size of the primes of a Paillier modulus. A 2048
+// bit modulus leaves room for the products of the MtA conversions, below
+// order^2, masked by values below order^5.
+const paillierPrimeBits = 1024
+
+var one = big.NewInt(1)
+
+// PaillierPublicKey encrypts with the generator N+1. The encryption is
+// additively homomorphic, which the MtA conversions of signing rely on.
+type PaillierPublicKey struct {
+	N *big.Int `json:"n"`
+}
+
+type PaillierPrivateKey struct {
+	PublicKey *PaillierPublicKey `json:"public_key"`
+	// Lambda is the totient of N, Mu its inverse modulo N.
+	Lambda *big.Int `json:"lambda"`
+	Mu     *big.Int `json:"mu"`
+}
+
+func generatePaillierKey() (*PaillierPrivateKey, error) {
+	for {
+		p, err := rand.Prime(rand.Reader, paillierPrimeBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Paillier key: %w", err)
+		}
+
+		q, err := rand.Prime(rand.Reader, paillierPrimeBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Paillier key: %w", err)
+		}
+
+		n := new(big.Int).Mul(p, q)
+		if p.Cmp(q) == 0 || n.BitLen() != 2*paillierPrimeBits {
+			continue
+		}
+
+		lambda := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+
+		mu := new(big.Int).ModInverse(lambda, n)
+		if mu == nil {
+			continue
+		}
+
+		return &PaillierPrivateKey{
+			PublicKey: &PaillierPublicKey{N: n},
+			Lambda:    lambda,
+			Mu:        mu,
+		}, nil
+	}
+}
+
+func (key *PaillierPublicKey) square() *big.Int {
+	return new(big.Int).Mul(key.N, key.N)
+}
+
+// valid reports whether the key can hold the values of the MtA conversions.
+func (key *PaillierPublicKey) valid() bool {
+	return key != nil && key.N != nil && key.N.BitLen() >= 2*paillierPrimeBits && key.N.Bit(0) == 1
+}
+
+// encrypt returns (1 + m*N) * r^N mod N^2 for a random r coprime to N.
+func (key *PaillierPublicKey) encrypt(message *big.Int) (*big.Int, error) {
+	if message.Sign() < 0 || message.Cmp(key.N) >= 0 {
+		return nil, errors.New("plaintext out of the range of the Paillier key")
+	}
+
+	var blind *big.Int
+
+	for {
+		var err error
+
+		blind, err = rand.Int(rand.Reader, key.N)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt: %w", err)
+		}
+
+		if blind.Sign() != 0 && new(big.Int).GCD(nil, nil, blind, key.N).Cmp(one) == 0 {
+			break
+		}
+	}
+
+	square := key.square()
+
+	ciphertext := new(big.Int).Mul(message, key.N)
+	ciphertext.Add(ciphertext, one)
+	ciphertext.Mul(ciphertext, new(big.Int).Exp(blind, key.N, square))
+
+	return ciphertext.Mod(ciphertext, square), nil
+}
+
+// add returns the encryption of the sum of the plaintexts.
+func (key *PaillierPublicKey) add(a *big.Int, b *big.Int) *big.Int {
+	sum := new(big.Int).Mul(a, b)
+
+	return sum.Mod(sum, key.square())
+}
+
+// multiply returns the encryption of the plaintext times k.
+func (key *PaillierPublicKey) multiply(ciphertext *big.Int, k *big.Int) *big.Int {
+	return new(big.Int).Exp(ciphertext, k, key.square())
+}
+
+// validCiphertext reports whether ciphertext is an element of Z*_{N^2}.
+func (key *PaillierPublicKey) validCiphertext(ciphertext *big.Int) bool {
+	return ciphertext != nil && ciphertext.Sign() > 0 && ciphertext.Cmp(key.square()) < 0 &&
+		new(big.Int).GCD(nil, nil, ciphertext, key.N).Cmp(one) == 0
+}
+
+// decrypt returns L(c^lambda mod N^2) * mu mod N, where L(u) = (u-1)/N.
+func (key *PaillierPrivateKey) decrypt(ciphertext *big.Int) *big.Int {
+	n := key.PublicKey.N
+
+	message := new(big.Int).Exp(ciphertext, key.Lambda, key.PublicKey.square())
+	message.Sub(message, one)
+	message.Div(message, n)
+	message.Mul(message, key.Mu)
+
+	return message.Mod(message, n)
+}
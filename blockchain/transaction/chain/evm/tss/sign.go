@@ -0,0 +1,379 @@
+package tss
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maskBound bounds the masks of the MtA conversions, order^5: they hide
+// products below order^2 and stay far below the Paillier modulus.
+var maskBound = new(big.Int).Exp(order, big.NewInt(5), nil)
+
+// SigningCommitment is broadcast in the first round of signing. It commits to
+// the party's gamma times the generator and carries its nonce share k,
+// encrypted with its Paillier key.
+type SigningCommitment struct {
+	From       int      `json:"from"`
+	Commitment []byte   `json:"commitment"`
+	EncryptedK *big.Int `json:"encrypted_k"`
+}
+
+// SigningConversion is sent to one party in the second round: the sender's
+// replies to the receiver's encrypted k in the multiplicative-to-additive
+// conversions of k times the sender's gamma and of k times its key share.
+type SigningConversion struct {
+	From  int      `json:"from"`
+	To    int      `json:"to"`
+	Gamma *big.Int `json:"gamma"`
+	Key   *big.Int `json:"key"`
+	// KeyMask is the mask of the key conversion times the generator, which
+	// checks the conversion against the sender's public share.
+	KeyMask *Point `json:"key_mask"`
+}
+
+// SigningDelta is broadcast in the third round: the party's additive share
+// of k times gamma.
+type SigningDelta struct {
+	From  int      `json:"from"`
+	Delta *big.Int `json:"delta"`
+}
+
+// SigningDecommitment is broadcast in the fourth round. It opens the
+// commitment of the first round and proves knowledge of gamma.
+type SigningDecommitment struct {
+	From  int           `json:"from"`
+	Blind []byte        `json:"blind"`
+	Gamma *Point        `json:"gamma"`
+	Proof *SchnorrProof `json:"proof"`
+}
+
+// SigningShare is broadcast in the fifth round: the party's share of s.
+type SigningShare struct {
+	From int      `json:"from"`
+	S    *big.Int `json:"s"`
+}
+
+// SigningParty runs the signing protocol of GG18 for one party of a quorum:
+// the nonce k and a mask gamma are sums of the parties' shares, k*gamma and
+// k*key are shared additively through MtA conversions, R is the sum of the
+// gammas times the generator, divided by k*gamma, and the shares of s add up
+// to k*(digest + r*key).
+type SigningParty struct {
+	share   *KeyShare
+	signers []int
+	digest  *big.Int
+
+	k          *big.Int
+	gamma      *big.Int
+	key        *big.Int
+	gammaPoint *Point
+	blind      []byte
+
+	commitments map[int]*SigningCommitment
+	// betas and nus are the party's shares of the conversions of its gamma
+	// and key share with the k of the other parties.
+	betas *big.Int
+	nus   *big.Int
+	delta *big.Int
+	sigma *big.Int
+	r     *big.Int
+}
+
+// NewSigningParty returns the party of share in the signing of the 32 byte
+// digest by signers, the indexes of at least a threshold of parties of the key.
+func NewSigningParty(share *KeyShare, signers []int, digest []byte) (*SigningParty, error) {
+	if len(digest) != common.HashLength {
+		return nil, fmt.Errorf("digest of %d bytes, want %d", len(digest), common.HashLength)
+	}
+
+	signers = slices.Clone(signers)
+	slices.Sort(signers)
+	signers = slices.Compact(signers)
+
+	if len(signers) < share.Threshold {
+		return nil, fmt.Errorf("%d signers do not meet the threshold of %d", len(signers), share.Threshold)
+	}
+
+	for _, signer := range signers {
+		if share.PublicShares[signer] == nil || share.PaillierKeys[signer] == nil {
+			return nil, fmt.Errorf("signer %d is not a party of the key", signer)
+		}
+	}
+
+	if !slices.Contains(signers, share.Party) {
+		return nil, fmt.Errorf("party %d is not one of the signers", share.Party)
+	}
+
+	return &SigningParty{
+		share:   share,
+		signers: signers,
+		digest:  new(big.Int).SetBytes(digest),
+		key:     new(big.Int).Mod(new(big.Int).Mul(lagrange(share.Party, signers), share.Secret), order),
+		betas:   new(big.Int),
+		nus:     new(big.Int),
+	}, nil
+}
+
+// Round1 picks the party's shares of k and gamma.
+func (party *SigningParty) Round1() (*SigningCommitment, error) {
+	var err error
+
+	if party.k, err = randomScalar(); err != nil {
+
+		return nil, err
+	}
+
+	if party.gamma, err = randomScalar(); err != nil {
+
+		return nil, err
+	}
+
+	party.gammaPoint = basePoint(party.gamma)
+
+	commitmentValue, blind, err := commit(party.gammaPoint)
+	if err != nil {
+
+		return nil, err
+	}
+
+	party.blind = blind
+
+	encryptedK, err := party.share.Paillier.PublicKey.encrypt(party.k)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &SigningCommitment{
+		From:       party.share.Party,
+		Commitment: commitmentValue,
+		EncryptedK: encryptedK,
+	}, nil
+}
+
+// Round2 takes the commitments of every signer, its own included, and
+// replies to the encrypted k of the other signers.
+func (party *SigningParty) Round2(commitments []*SigningCommitment) ([]*SigningConversion, error) {
+	received, err := byParty(commitments, func(message *SigningCommitment) int { return message.From }, party.signers)
+	if err != nil {
+
+		return nil, err
+	}
+
+	party.commitments = received
+	conversions := make([]*SigningConversion, 0, len(party.signers)-1)
+
+	for _, to := range others(party.signers, party.share.Party) {
+		paillier := party.share.PaillierKeys[to]
+		if !paillier.validCiphertext(received[to].EncryptedK) {
+			return nil, AbortError{Party: to, Reason: "invalid encryption of k"}
+		}
+
+		gamma, gammaMask, err := convert(paillier, received[to].EncryptedK, party.gamma)
+		if err != nil {
+
+			return nil, err
+		}
+
+		key, keyMask, err := convert(paillier, received[to].EncryptedK, party.key)
+		if err != nil {
+
+			return nil, err
+		}
+
+		party.betas.Sub(party.betas, gammaMask)
+		party.nus.Sub(party.nus, keyMask)
+
+		conversions = append(conversions, &SigningConversion{
+			From:    party.share.Party,
+			To:      to,
+			Gamma:   gamma,
+			Key:     key,
+			KeyMask: basePoint(keyMask),
+		})
+	}
+
+	return conversions, nil
+}
+
+// convert is Bob's side of an MtA conversion: given the encryption of a, it
+// returns the encryption of a*b plus a random mask. a*b is the sum of the
+// mask, Bob's share negated, and the decryption, Alice's share.
+func convert(paillier *PaillierPublicKey, encrypted *big.Int, b *big.Int) (*big.Int, *big.Int, error) {
+	mask, err := rand.Int(rand.Reader, maskBound)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate mask: %w", err)
+	}
+
+	encryptedMask, err := paillier.encrypt(mask)
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	return paillier.add(paillier.multiply(encrypted, b), encryptedMask), mask, nil
+}
+
+// Round3 takes the conversions addressed to the party and returns its share
+// of k times gamma.
+func (party *SigningParty) Round3(conversions []*SigningConversion) (*SigningDelta, error) {
+	received, err := byParty(conversions, func(message *SigningConversion) int { return message.From },
+		others(party.signers, party.share.Party))
+	if err != nil {
+
+		return nil, err
+	}
+
+	paillier := party.share.Paillier
+
+	delta := new(big.Int).Mul(party.k, party.gamma)
+	delta.Add(delta, party.betas)
+
+	sigma := new(big.Int).Mul(party.k, party.key)
+	sigma.Add(sigma, party.nus)
+
+	for from, message := range received {
+		if message.To != party.share.Party || !paillier.PublicKey.validCiphertext(message.Gamma) ||
+			!paillier.PublicKey.validCiphertext(message.Key) || !message.KeyMask.valid() {
+			return nil, AbortError{Party: from, Reason: "invalid conversion"}
+		}
+
+		alpha := new(big.Int).Mod(paillier.decrypt(message.Gamma), order)
+		mu := new(big.Int).Mod(paillier.decrypt(message.Key), order)
+
+		// mu = k * key share of the sender + its mask
+		publicKey := party.share.PublicShares[from].mul(lagrange(from, party.signers))
+		if !basePoint(mu).equal(publicKey.mul(party.k).add(message.KeyMask)) {
+			return nil, AbortError{Party: from, Reason: "conversion does not match its public share"}
+		}
+
+		delta.Add(delta, alpha)
+		sigma.Add(sigma, mu)
+	}
+
+	party.delta = delta.Mod(delta, order)
+	party.sigma = sigma.Mod(sigma, order)
+
+	return &SigningDelta{From: party.share.Party, Delta: party.delta}, nil
+}
+
+// Round4 takes the deltas of every signer, its own included, and opens the
+// commitment to the party's gamma.
+func (party *SigningParty) Round4(deltas []*SigningDelta) (*SigningDecommitment, error) {
+	received, err := byParty(deltas, func(message *SigningDelta) int { return message.From }, party.signers)
+	if err != nil {
+
+		return nil, err
+	}
+
+	delta := new(big.Int)
+
+	for from, message := range received {
+		if message.Delta == nil || message.Delta.Sign() < 0 || message.Delta.Cmp(order) >= 0 {
+			return nil, AbortError{Party: from, Reason: "invalid delta"}
+		}
+
+		delta.Add(delta, message.Delta)
+	}
+
+	if delta.Mod(delta, order).Sign() == 0 {
+		return nil, AbortError{Reason: "k times gamma is zero"}
+	}
+
+	party.delta = delta
+
+	proof, err := proveKnowledge(party.share.Party, party.gamma, party.gammaPoint)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &SigningDecommitment{
+		From:  party.share.Party,
+		Blind: party.blind,
+		Gamma: party.gammaPoint,
+		Proof: proof,
+	}, nil
+}
+
+// Round5 takes the decommitments of every signer, its own included, derives
+// r and returns the party's share of s.
+func (party *SigningParty) Round5(decommitments []*SigningDecommitment) (*SigningShare, error) {
+	received, err := byParty(decommitments, func(message *SigningDecommitment) int { return message.From },
+		party.signers)
+	if err != nil {
+
+		return nil, err
+	}
+
+	gamma := basePoint(new(big.Int))
+
+	for from, message := range received {
+		if !opens(party.commitments[from].Commitment, message.Blind, []*Point{message.Gamma}) {
+			return nil, AbortError{Party: from, Reason: "commitment to gamma does not open"}
+		}
+
+		if !message.Proof.verify(from, message.Gamma) {
+			return nil, AbortError{Party: from, Reason: "invalid proof of knowledge of gamma"}
+		}
+
+		gamma = gamma.add(message.Gamma)
+	}
+
+	// R = gamma * (k * gamma)^-1 = k^-1 times the generator
+	point := gamma.mul(new(big.Int).ModInverse(party.delta, order))
+
+	party.r = new(big.Int).Mod(point.X, order)
+	if party.r.Sign() == 0 {
+		return nil, AbortError{Reason: "r is zero"}
+	}
+
+	s := new(big.Int).Mul(party.digest, party.k)
+	s.Add(s, new(big.Int).Mul(party.r, party.sigma))
+
+	return &SigningShare{From: party.share.Party, S: s.Mod(s, order)}, nil
+}
+
+// Finish takes the shares of s of every signer, its own included, and
+// returns r and s of the signature once it verifies against the public key.
+func (party *SigningParty) Finish(shares []*SigningShare) (*big.Int, *big.Int, error) {
+	received, err := byParty(shares, func(message *SigningShare) int { return message.From }, party.signers)
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	s := new(big.Int)
+
+	for from, message := range received {
+		if message.S == nil || message.S.Sign() < 0 || message.S.Cmp(order) >= 0 {
+			return nil, nil, AbortError{Party: from, Reason: "invalid share of s"}
+		}
+
+		s.Add(s, message.S)
+	}
+
+	s.Mod(s, order)
+
+	if !verify(party.share.PublicKey, party.digest, party.r, s) {
+		return nil, nil, AbortError{Reason: "signature does not verify"}
+	}
+
+	return party.r, s, nil
+}
+
+// verify checks the ECDSA signature r, s of digest against publicKey.
+func verify(publicKey *Point, digest *big.Int, r *big.Int, s *big.Int) bool {
+	if s.Sign() == 0 {
+		return false
+	}
+
+	inverse := new(big.Int).ModInverse(s, order)
+	point := basePoint(new(big.Int).Mul(digest, inverse)).add(publicKey.mul(new(big.Int).Mul(r, inverse)))
+
+	return !point.isInfinity() && new(big.Int).Mod(point.X, order).Cmp(r) == 0
+}
@@ -0,0 +1,320 @@
+package tss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/telemetry"
+)
+
+// QuorumNotMetError reports a key with fewer shares at hand than its
+// threshold.
+type QuorumNotMetError struct {
+	Address   string
+	Threshold int
+	Shares    int
+}
+
+func (e QuorumNotMetError) Error() string {
+	return fmt.Sprintf("%d shares of key %s do not meet its threshold of %d", e.Shares, e.Address, e.Threshold)
+}
+
+// Signer holds the shares of the parties of its keys and signs by running the
+// parties of the first threshold shares of a key in this process. A signer
+// opened on a directory also generates keys, and keeps the share of each
+// party in the party's own subdirectory, encrypted.
+type Signer struct {
+	parties   int
+	threshold int
+
+	mutex sync.RWMutex
+	keys  map[common.Address][]*KeyShare
+
+	dir      string
+	password string
+}
+
+var (
+	_ evm.DigestSigner        = (*Signer)(nil)
+	_ domain.AddressGenerator = (*Signer)(nil)
+)
+
+// NewSigner returns a signer whose keys are shared among parties, any
+// threshold of which sign together.
+func NewSigner(parties int, threshold int) (*Signer, error) {
+	if threshold < 1 || threshold > parties {
+		return nil, fmt.Errorf("threshold %d is not between 1 and the %d parties", threshold, parties)
+	}
+
+	return &Signer{
+		parties:   parties,
+		threshold: threshold,
+		keys:      make(map[common.Address][]*KeyShare),
+	}, nil
+}
+
+// OpenSigner returns a signer that writes the shares of the keys it generates
+// to dir, each party's to the subdirectory party-<index>, as Web3 Secret
+// Storage ciphertexts encrypted with password, and loads the shares already
+// in dir.
+func OpenSigner(dir string, password string, parties int, threshold int) (*Signer, error) {
+	signer, err := NewSigner(parties, threshold)
+	if err != nil {
+
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create share store (%s): %w", dir, err)
+	}
+
+	signer.dir = dir
+	signer.password = password
+
+	partyDirs, err := filepath.Glob(filepath.Join(dir, "party-*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read share store (%s): %w", dir, err)
+	}
+
+	for _, partyDir := range partyDirs {
+		if err := signer.load(partyDir); err != nil {
+
+			return nil, err
+		}
+	}
+
+	return signer, nil
+}
+
+// shareFile is a share as stored: the share's JSON, encrypted.
+type shareFile struct {
+	Address common.Address      `json:"address"`
+	Party   int                 `json:"party"`
+	Crypto  keystore.CryptoJSON `json:"crypto"`
+}
+
+func (signer *Signer) load(partyDir string) error {
+	entries, err := os.ReadDir(partyDir)
+	if err != nil {
+		return fmt.Errorf("failed to read share store (%s): %w", partyDir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(partyDir, entry.Name())
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read share file (%s): %w", path, err)
+		}
+
+		var file shareFile
+		if err := json.Unmarshal(content, &file); err != nil {
+			return fmt.Errorf("failed to parse share file (%s): %w", path, err)
+		}
+
+		plaintext, err := keystore.DecryptDataV3(file.Crypto, signer.password)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt share file (%s): %w", path, err)
+		}
+
+		var share KeyShare
+		if err := json.Unmarshal(plaintext, &share); err != nil {
+			return fmt.Errorf("failed to parse share file (%s): %w", path, err)
+		}
+
+		if !share.PublicKey.valid() || share.Address() != file.Address || share.Party != file.Party {
+			return fmt.Errorf("share file (%s) does not hold the share of party %d of %s", path, file.Party,
+				file.Address.Hex())
+		}
+
+		signer.add(&share)
+	}
+
+	return nil
+}
+
+// AddShares makes a key available for signing with the given shares of its
+// parties and returns its address.
+func (signer *Signer) AddShares(shares []*KeyShare) (common.Address, error) {
+	if len(shares) == 0 {
+		return common.Address{}, errors.New("no shares to add")
+	}
+
+	address := shares[0].Address()
+
+	for _, share := range shares {
+		if share.Address() != address {
+			return common.Address{}, fmt.Errorf("share of party %d is of another key", share.Party)
+		}
+
+		signer.add(share)
+	}
+
+	return address, nil
+}
+
+func (signer *Signer) add(share *KeyShare) {
+	address := share.Address()
+
+	signer.mutex.Lock()
+	defer signer.mutex.Unlock()
+
+	// signatures in progress keep the shares they started with
+	shares := slices.Clone(signer.keys[address])
+	for index, existing := range shares {
+		if existing.Party == share.Party {
+			shares[index] = share
+			signer.keys[address] = shares
+
+			return
+		}
+	}
+
+	shares = append(shares, share)
+	slices.SortFunc(shares, func(a, b *KeyShare) int { return a.Party - b.Party })
+
+	signer.keys[address] = shares
+}
+
+// GenerateAddress runs the key generation of the parties, writes each share
+// to the directory of its party and returns the checksummed address of the
+// key. The same key is valid on every EVM network. A signer without a
+// directory generates nothing, as the shares would be lost with the process
+// while funds may already be sent to the key.
+func (signer *Signer) GenerateAddress(ctx context.Context, _ string) (string, error) {
+	if signer.dir == "" {
+		return "", evm.KeyStoreNotPersistentError{}
+	}
+
+	ctx, span := telemetry.Start(ctx, "tss.keygen")
+	defer span.End()
+
+	shares, err := GenerateKey(ctx, signer.parties, signer.threshold)
+	if err != nil {
+		span.RecordError(err)
+
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	for _, share := range shares {
+		if err := signer.store(share); err != nil {
+			span.RecordError(err)
+
+			return "", err
+		}
+	}
+
+	address, err := signer.AddShares(shares)
+	if err != nil {
+
+		return "", err
+	}
+
+	return address.Hex(), nil
+}
+
+// store writes the share to a new file in the directory of its party before
+// the key is handed out.
+func (signer *Signer) store(share *KeyShare) error {
+	address := share.Address()
+
+	plaintext, err := json.Marshal(share)
+	if err != nil {
+		return fmt.Errorf("failed to encode share (%s): %w", address.Hex(), err)
+	}
+
+	cryptoJSON, err := keystore.EncryptDataV3(plaintext, []byte(signer.password), keystore.StandardScryptN,
+		keystore.StandardScryptP)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt share (%s): %w", address.Hex(), err)
+	}
+
+	content, err := json.Marshal(shareFile{Address: address, Party: share.Party, Crypto: cryptoJSON})
+	if err != nil {
+		return fmt.Errorf("failed to encode share (%s): %w", address.Hex(), err)
+	}
+
+	partyDir := filepath.Join(signer.dir, fmt.Sprintf("party-%d", share.Party))
+	if err := os.MkdirAll(partyDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create share store (%s): %w", partyDir, err)
+	}
+
+	name := fmt.Sprintf("%x.json", address)
+
+	// the file only appears under its name once it is complete
+	tmp, err := os.CreateTemp(partyDir, "."+name+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to store share (%s): %w", address.Hex(), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to store share (%s): %w", address.Hex(), err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to store share (%s): %w", address.Hex(), err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to store share (%s): %w", address.Hex(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(partyDir, name)); err != nil {
+		return fmt.Errorf("failed to store share (%s): %w", address.Hex(), err)
+	}
+
+	return nil
+}
+
+func (signer *Signer) SignDigest(
+	ctx context.Context,
+	address common.Address,
+	digest []byte,
+) (*big.Int, *big.Int, error) {
+	ctx, span := telemetry.Start(ctx, "tss.sign", telemetry.String("address", address.Hex()))
+	defer span.End()
+
+	r, s, err := signer.signDigest(ctx, address, digest)
+	span.RecordError(err)
+
+	return r, s, err
+}
+
+func (signer *Signer) signDigest(ctx context.Context, address common.Address, digest []byte) (*big.Int, *big.Int, error) {
+	signer.mutex.RLock()
+	shares := signer.keys[address]
+	signer.mutex.RUnlock()
+
+	if len(shares) == 0 {
+		return nil, nil, evm.KeyNotFoundError{Address: address.Hex()}
+	}
+
+	threshold := shares[0].Threshold
+	if len(shares) < threshold {
+		return nil, nil, QuorumNotMetError{Address: address.Hex(), Threshold: threshold, Shares: len(shares)}
+	}
+
+	return Sign(ctx, shares[:threshold], digest)
+}
@@ -0,0 +1,269 @@
+// Package tss signs EVM transactions with secp256k1 keys that no single party
+// holds. A key is generated by n parties in a distributed key generation, each
+// keeping a share, and any threshold t of them sign together following GG18
+// (Gennaro and Goldfeder, "Fast Multiparty Threshold ECDSA with Fast Trustless
+// Setup"). Fewer than t parties learn nothing of the key. The signature is an
+// ordinary ECDSA signature of the shared key, which
+// evm.DigestTransactionSigner turns into a signed transaction.
+//
+// KeygenParty and SigningParty run the protocols of one party as rounds of
+// messages, which a coordinator delivers: broadcast messages to every party,
+// shares and MtA messages only to the party they are addressed to, over
+// private channels. GenerateKey and Sign run all parties in this process.
+//
+// Shares are verified against Feldman commitments, parties prove knowledge of
+// their secrets, the MtA conversions with key shares are checked against the
+// public shares and every signature is verified before it is returned. The
+// range proofs of the MtA conversions, the proofs that Paillier moduli are
+// well formed and the checks of phase 5 of GG18 are left out: the protocol
+// is secure against parties that follow it only, and a single party that
+// deviates can recover the whole key. Until they are added, the package must
+// not be used with parties that are not trusted to follow the protocol, and
+// the demo does not offer it as a provider.
+package tss
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	curve = crypto.S256()
+	order = curve.Params().N
+)
+
+// AbortError reports a protocol run that stopped because a party sent
+// messages that do not verify. Party is 0 when the culprit is unknown.
+type AbortError struct {
+	Party  int
+	Reason string
+}
+
+func (e AbortError) Error() string {
+	if e.Party == 0 {
+		return "threshold protocol aborted: " + e.Reason
+	}
+
+	return fmt.Sprintf("threshold protocol aborted by party %d: %s", e.Party, e.Reason)
+}
+
+// Point is a point of secp256k1 in affine coordinates, (0, 0) being the point
+// at infinity.
+type Point struct {
+	X *big.Int `json:"x"`
+	Y *big.Int `json:"y"`
+}
+
+func basePoint(k *big.Int) *Point {
+	return (&Point{X: curve.Params().Gx, Y: curve.Params().Gy}).mul(k)
+}
+
+func (point *Point) isInfinity() bool {
+	return point.X.Sign() == 0 && point.Y.Sign() == 0
+}
+
+func (point *Point) valid() bool {
+	return point != nil && point.X != nil && point.Y != nil &&
+		(point.isInfinity() || curve.IsOnCurve(point.X, point.Y))
+}
+
+func (point *Point) mul(k *big.Int) *Point {
+	k = new(big.Int).Mod(k, order)
+	if k.Sign() == 0 || point.isInfinity() {
+		return &Point{X: new(big.Int), Y: new(big.Int)}
+	}
+
+	x, y := curve.ScalarMult(point.X, point.Y, k.FillBytes(make([]byte, 32)))
+
+	return &Point{X: x, Y: y}
+}
+
+func (point *Point) add(other *Point) *Point {
+	x, y := curve.Add(point.X, point.Y, other.X, other.Y)
+
+	return &Point{X: x, Y: y}
+}
+
+func (point *Point) equal(other *Point) bool {
+	return point.X.Cmp(other.X) == 0 && point.Y.Cmp(other.Y) == 0
+}
+
+func (point *Point) bytes() []byte {
+	return curve.Marshal(point.X, point.Y)
+}
+
+// randomScalar returns a uniform nonzero scalar modulo the curve order.
+func randomScalar() (*big.Int, error) {
+	for {
+		k, err := rand.Int(rand.Reader, order)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate scalar: %w", err)
+		}
+
+		if k.Sign() != 0 {
+			return k, nil
+		}
+	}
+}
+
+// hash binds the values, each prefixed with its length, into one digest.
+func hash(values ...[]byte) []byte {
+	hasher := sha256.New()
+
+	for _, value := range values {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(value)))
+
+		hasher.Write(length[:])
+		hasher.Write(value)
+	}
+
+	return hasher.Sum(nil)
+}
+
+// commit returns a hash commitment to the points and the blinding factor that
+// opens it.
+func commit(points ...*Point) ([]byte, []byte, error) {
+	blind := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, blind); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate commitment: %w", err)
+	}
+
+	return commitment(blind, points), blind, nil
+}
+
+func commitment(blind []byte, points []*Point) []byte {
+	values := [][]byte{blind}
+	for _, point := range points {
+		values = append(values, point.bytes())
+	}
+
+	return hash(values...)
+}
+
+func opens(commitmentValue []byte, blind []byte, points []*Point) bool {
+	for _, point := range points {
+		if !point.valid() {
+			return false
+		}
+	}
+
+	return bytes.Equal(commitmentValue, commitment(blind, points))
+}
+
+// SchnorrProof proves knowledge of the discrete logarithm of a point without
+// revealing it, bound to the party that proves.
+type SchnorrProof struct {
+	Commitment *Point   `json:"commitment"`
+	Response   *big.Int `json:"response"`
+}
+
+func proveKnowledge(party int, secret *big.Int, public *Point) (*SchnorrProof, error) {
+	nonce, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+
+	nonceCommitment := basePoint(nonce)
+	challenge := proofChallenge(party, public, nonceCommitment)
+
+	response := new(big.Int).Mul(challenge, secret)
+	response.Add(response, nonce)
+	response.Mod(response, order)
+
+	return &SchnorrProof{Commitment: nonceCommitment, Response: response}, nil
+}
+
+func (proof *SchnorrProof) verify(party int, public *Point) bool {
+	if proof == nil || !proof.Commitment.valid() || proof.Response == nil || !public.valid() {
+		return false
+	}
+
+	challenge := proofChallenge(party, public, proof.Commitment)
+
+	return basePoint(proof.Response).equal(proof.Commitment.add(public.mul(challenge)))
+}
+
+func proofChallenge(party int, public *Point, nonceCommitment *Point) *big.Int {
+	challenge := hash(big.NewInt(int64(party)).Bytes(), public.bytes(), nonceCommitment.bytes())
+
+	return new(big.Int).Mod(new(big.Int).SetBytes(challenge), order)
+}
+
+// evaluate returns the polynomial of the coefficients at x.
+func evaluate(coefficients []*big.Int, x int) *big.Int {
+	result := new(big.Int)
+
+	for index := len(coefficients) - 1; index >= 0; index-- {
+		result.Mul(result, big.NewInt(int64(x)))
+		result.Add(result, coefficients[index])
+		result.Mod(result, order)
+	}
+
+	return result
+}
+
+// evaluateCommitments returns the commitment to the polynomial at x, given
+// the commitments to its coefficients.
+func evaluateCommitments(commitments []*Point, x int) *Point {
+	result := commitments[len(commitments)-1]
+
+	for index := len(commitments) - 2; index >= 0; index-- {
+		result = result.mul(big.NewInt(int64(x))).add(commitments[index])
+	}
+
+	return result
+}
+
+// lagrange returns the coefficient of the share of party in the interpolation
+// at 0 over the shares of parties.
+func lagrange(party int, parties []int) *big.Int {
+	numerator := big.NewInt(1)
+	denominator := big.NewInt(1)
+
+	for _, other := range parties {
+		if other == party {
+			continue
+		}
+
+		numerator.Mul(numerator, big.NewInt(int64(other)))
+		denominator.Mul(denominator, big.NewInt(int64(other-party)))
+	}
+
+	inverse := new(big.Int).ModInverse(denominator.Mod(denominator, order), order)
+
+	return numerator.Mul(numerator, inverse).Mod(numerator, order)
+}
+
+// byParty indexes one message of each of the expected parties.
+func byParty[M any](messages []M, from func(M) int, parties []int) (map[int]M, error) {
+	indexed := make(map[int]M, len(parties))
+
+	for _, message := range messages {
+		party := from(message)
+		if _, ok := indexed[party]; ok {
+			return nil, AbortError{Party: party, Reason: "sent more than one message in a round"}
+		}
+
+		indexed[party] = message
+	}
+
+	for _, party := range parties {
+		if _, ok := indexed[party]; !ok {
+			return nil, AbortError{Party: party, Reason: "sent no message in a round"}
+		}
+	}
+
+	if len(indexed) != len(parties) {
+		return nil, AbortError{Reason: "messages from parties outside the protocol"}
+	}
+
+	return indexed, nil
+}
@@ -0,0 +1,240 @@
+package tss_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm/tss"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+func generateKey(t *testing.T, parties int, threshold int) []*tss.KeyShare {
+	t.Helper()
+
+	shares, err := tss.GenerateKey(context.Background(), parties, threshold)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	return shares
+}
+
+func TestSignWithEveryQuorum(t *testing.T) {
+	shares := generateKey(t, 3, 2)
+	address := shares[0].Address()
+
+	for _, share := range shares {
+		if share.Address() != address {
+			t.Fatalf("party %d holds a share of %s, want %s", share.Party, share.Address().Hex(), address.Hex())
+		}
+	}
+
+	digest := crypto.Keccak256([]byte("threshold"))
+
+	for _, quorum := range [][]*tss.KeyShare{
+		{shares[0], shares[1]},
+		{shares[0], shares[2]},
+		{shares[1], shares[2]},
+		shares,
+	} {
+		r, s, err := tss.Sign(context.Background(), quorum, digest)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+
+		// recovers the address of the shared key, or fails
+		if _, err := evm.RecoverableSignature(digest, r, s, address); err != nil {
+			t.Fatalf("RecoverableSignature: %v", err)
+		}
+	}
+}
+
+func TestSignBelowThreshold(t *testing.T) {
+	shares := generateKey(t, 3, 2)
+
+	if _, _, err := tss.Sign(context.Background(), shares[:1], crypto.Keccak256(nil)); err == nil {
+		t.Fatal("Sign succeeded with one share of a 2-of-3 key")
+	}
+}
+
+func TestSignAbortsOnWrongShare(t *testing.T) {
+	shares := generateKey(t, 3, 2)
+
+	wrong := *shares[1]
+	wrong.Secret = new(big.Int).Add(wrong.Secret, big.NewInt(1))
+
+	_, _, err := tss.Sign(context.Background(), []*tss.KeyShare{shares[0], &wrong}, crypto.Keccak256(nil))
+
+	var abortErr tss.AbortError
+	if !errors.As(err, &abortErr) || abortErr.Party != 2 {
+		t.Fatalf("Sign returned %v, want an abort by party 2", err)
+	}
+}
+
+func TestKeygenAbortsOnWrongShare(t *testing.T) {
+	parties := make([]*tss.KeygenParty, 3)
+	commitments := make([]*tss.KeygenCommitment, 3)
+
+	for index := range parties {
+		party, err := tss.NewKeygenParty(index+1, 3, 2)
+		if err != nil {
+			t.Fatalf("NewKeygenParty: %v", err)
+		}
+
+		parties[index] = party
+
+		if commitments[index], err = party.Round1(); err != nil {
+			t.Fatalf("Round1: %v", err)
+		}
+	}
+
+	decommitments := make([]*tss.KeygenDecommitment, 3)
+
+	var dealt []*tss.KeygenShare
+
+	for index, party := range parties {
+		decommitment, shares, err := party.Round2(commitments)
+		if err != nil {
+			t.Fatalf("Round2: %v", err)
+		}
+
+		decommitments[index] = decommitment
+		dealt = append(dealt, shares...)
+	}
+
+	var toFirst []*tss.KeygenShare
+
+	for _, share := range dealt {
+		if share.To == 1 {
+			if share.From == 3 {
+				share.Share = new(big.Int).Add(share.Share, big.NewInt(1))
+			}
+
+			toFirst = append(toFirst, share)
+		}
+	}
+
+	_, err := parties[0].Finish(decommitments, toFirst)
+
+	var abortErr tss.AbortError
+	if !errors.As(err, &abortErr) || abortErr.Party != 3 {
+		t.Fatalf("Finish returned %v, want an abort by party 3", err)
+	}
+}
+
+func TestSignerTransfer(t *testing.T) {
+	signer, err := tss.NewSigner(3, 2)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+
+	source, err := signer.AddShares(generateKey(t, 3, 2))
+	if err != nil {
+		t.Fatalf("AddShares: %v", err)
+	}
+
+	destination := common.HexToAddress("0x00000000000000000000000000000000000000d5")
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		source: {Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))},
+	})
+	t.Cleanup(func() {
+		backend.Close()
+	})
+
+	client := evm.NewBackendClient(backend.Client(), "simulated")
+	transferor := transaction.NewGenericTransferor(
+		evm.NewTransactionBuilder(client),
+		evm.NewDigestTransactionSigner(signer),
+		evm.NewTransactionBroadcaster(client),
+	)
+
+	payload, err := transferor.Transfer(context.Background(), &transaction.TransferRequest{
+		SourceAddress:      source.Hex(),
+		DestinationAddress: destination.Hex(),
+		Amount:             decimal.NewFromInt(1),
+		NetworkCurrencyID:  domain.TestETH,
+	})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	backend.Commit()
+
+	receipt, err := backend.Client().TransactionReceipt(context.Background(), common.HexToHash(payload.ID))
+	if err != nil {
+		t.Fatalf("TransactionReceipt: %v", err)
+	}
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction %s reverted", payload.ID)
+	}
+
+	balance, err := backend.Client().BalanceAt(context.Background(), destination, nil)
+	if err != nil {
+		t.Fatalf("BalanceAt: %v", err)
+	}
+
+	if want := evm.ToBaseUnits(decimal.NewFromInt(1), 18); balance.Cmp(want) != 0 {
+		t.Fatalf("destination holds %s, want %s", balance, want)
+	}
+}
+
+func TestSignerKeepsGeneratedShares(t *testing.T) {
+	dir := t.TempDir()
+
+	signer, err := tss.OpenSigner(dir, "password", 3, 2)
+	if err != nil {
+		t.Fatalf("OpenSigner: %v", err)
+	}
+
+	address, err := signer.GenerateAddress(context.Background(), domain.TestEth)
+	if err != nil {
+		t.Fatalf("GenerateAddress: %v", err)
+	}
+
+	reopened, err := tss.OpenSigner(dir, "password", 3, 2)
+	if err != nil {
+		t.Fatalf("OpenSigner: %v", err)
+	}
+
+	digest := crypto.Keccak256([]byte("kept"))
+
+	r, s, err := reopened.SignDigest(context.Background(), common.HexToAddress(address), digest)
+	if err != nil {
+		t.Fatalf("SignDigest: %v", err)
+	}
+
+	if _, err := evm.RecoverableSignature(digest, r, s, common.HexToAddress(address)); err != nil {
+		t.Fatalf("RecoverableSignature: %v", err)
+	}
+
+	if _, err := tss.OpenSigner(dir, "wrong", 3, 2); err == nil {
+		t.Fatal("OpenSigner succeeded with the wrong password")
+	}
+}
+
+func TestSignerWithoutDirGeneratesNothing(t *testing.T) {
+	signer, err := tss.NewSigner(3, 2)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+
+	_, err = signer.GenerateAddress(context.Background(), domain.TestEth)
+
+	var notPersistentErr evm.KeyStoreNotPersistentError
+	if !errors.As(err, &notPersistentErr) {
+		t.Fatalf("GenerateAddress returned %v, want KeyStoreNotPersistentError", err)
+	}
+}
@@ -0,0 +1,585 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/telemetry"
+)
+
+// EntryPointV06 is the address of the v0.6 EntryPoint, the same on every
+// network.
+const EntryPointV06 = "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"
+
+// userOperationKey is the nonce key of the operations built here, which are
+// therefore executed in order like transactions.
+const userOperationKey = 0
+
+// entryPointDefinition covers the EntryPoint methods used to build user
+// operations.
+const entryPointDefinition = `[
+	{"type":"function","name":"getNonce","stateMutability":"view",
+		"inputs":[{"name":"sender","type":"address"},{"name":"key","type":"uint192"}],
+		"outputs":[{"type":"uint256"}]}
+]`
+
+// smartAccountDefinition covers the methods of SimpleAccount, the reference
+// account of ERC-4337, that operations call. Accounts derived from it, e.g.
+// LightAccount, have the same methods.
+const smartAccountDefinition = `[
+	{"type":"function","name":"owner","stateMutability":"view","inputs":[],"outputs":[{"type":"address"}]},
+	{"type":"function","name":"execute","stateMutability":"nonpayable",
+		"inputs":[{"name":"dest","type":"address"},{"name":"value","type":"uint256"},{"name":"func","type":"bytes"}],
+		"outputs":[]}
+]`
+
+var (
+	entryPointABI   = mustParseABI(entryPointDefinition)
+	smartAccountABI = mustParseABI(smartAccountDefinition)
+)
+
+// dummySignature has the length and shape of an owner signature, so that
+// bundlers can simulate the validation of an operation before it is signed.
+var dummySignature = hexutil.MustDecode("0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007" +
+	"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
+
+var userOperationArgs = abi.Arguments{
+	{Type: mustNewType("address")},
+	{Type: mustNewType("uint256")},
+	{Type: mustNewType("bytes32")},
+	{Type: mustNewType("bytes32")},
+	{Type: mustNewType("uint256")},
+	{Type: mustNewType("uint256")},
+	{Type: mustNewType("uint256")},
+	{Type: mustNewType("uint256")},
+	{Type: mustNewType("uint256")},
+	{Type: mustNewType("bytes32")},
+}
+
+var userOperationHashArgs = abi.Arguments{
+	{Type: mustNewType("bytes32")},
+	{Type: mustNewType("address")},
+	{Type: mustNewType("uint256")},
+}
+
+func mustNewType(typ string) abi.Type {
+	parsed, err := abi.NewType(typ, "", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
+
+// UserOperation is an ERC-4337 (v0.6) user operation, in the JSON format of
+// bundlers.
+type UserOperation struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// Hash returns the userOpHash, which commits to every field but the signature,
+// the EntryPoint and the chain.
+func (op *UserOperation) Hash(entryPoint common.Address, chainID *big.Int) (common.Hash, error) {
+	packed, err := userOperationArgs.Pack(
+		op.Sender,
+		op.Nonce.ToInt(),
+		crypto.Keccak256Hash(op.InitCode),
+		crypto.Keccak256Hash(op.CallData),
+		op.CallGasLimit.ToInt(),
+		op.VerificationGasLimit.ToInt(),
+		op.PreVerificationGas.ToInt(),
+		op.MaxFeePerGas.ToInt(),
+		op.MaxPriorityFeePerGas.ToInt(),
+		crypto.Keccak256Hash(op.PaymasterAndData),
+	)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to pack user operation: %w", err)
+	}
+
+	encoded, err := userOperationHashArgs.Pack(crypto.Keccak256Hash(packed), entryPoint, chainID)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to pack user operation hash: %w", err)
+	}
+
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// userOperationEnvelope is the raw and signed form of a user operation in a
+// payload, with the EntryPoint and chain that its hash commits to.
+type userOperationEnvelope struct {
+	EntryPoint common.Address `json:"entryPoint"`
+	ChainID    *hexutil.Big   `json:"chainId"`
+	Operation  *UserOperation `json:"userOperation"`
+}
+
+func MarshalUserOperation(op *UserOperation, entryPoint common.Address, chainID *big.Int) ([]byte, error) {
+	bytes, err := json.Marshal(&userOperationEnvelope{
+		EntryPoint: entryPoint,
+		ChainID:    (*hexutil.Big)(chainID),
+		Operation:  op,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal user operation: %w", err)
+	}
+
+	return bytes, nil
+}
+
+func UnmarshalUserOperation(bytes []byte) (*UserOperation, common.Address, *big.Int, error) {
+	var envelope userOperationEnvelope
+
+	if err := json.Unmarshal(bytes, &envelope); err != nil || envelope.Operation == nil || envelope.ChainID == nil {
+		return nil, common.Address{}, nil, fmt.Errorf("failed to unmarshal user operation: %v", err)
+	}
+
+	return envelope.Operation, envelope.EntryPoint, envelope.ChainID.ToInt(), nil
+}
+
+// Paymaster sponsors the gas of user operations, following the stub and final
+// data steps of ERC-7677.
+type Paymaster interface {
+	// StubData returns paymasterAndData to estimate the gas of an operation.
+	StubData(ctx context.Context, op *UserOperation, entryPoint common.Address, chainID *big.Int) ([]byte, error)
+	// Data returns the paymasterAndData of an operation whose other fields,
+	// but the signature, are final.
+	Data(ctx context.Context, op *UserOperation, entryPoint common.Address, chainID *big.Int) ([]byte, error)
+}
+
+// StaticPaymaster sponsors operations with fixed paymasterAndData, for
+// paymasters that do not sign what they sponsor, e.g. ones that allow a list
+// of accounts.
+type StaticPaymaster struct {
+	Address common.Address
+	// Params follow the address in paymasterAndData.
+	Params []byte
+}
+
+var _ Paymaster = (*StaticPaymaster)(nil)
+
+func (paymaster *StaticPaymaster) StubData(
+	_ context.Context,
+	_ *UserOperation,
+	_ common.Address,
+	_ *big.Int,
+) ([]byte, error) {
+	return append(paymaster.Address.Bytes(), paymaster.Params...), nil
+}
+
+func (paymaster *StaticPaymaster) Data(
+	ctx context.Context,
+	op *UserOperation,
+	entryPoint common.Address,
+	chainID *big.Int,
+) ([]byte, error) {
+	return paymaster.StubData(ctx, op, entryPoint, chainID)
+}
+
+// UserOperationBuilder builds transfers from ERC-4337 smart accounts: the
+// transfer becomes a call of execute on the account, wrapped in a user
+// operation that a bundler submits to the EntryPoint. The source address of
+// the transfer is the account, which must be deployed, and the sender of the
+// payload its owner, whose key signs the operation. Without Paymaster the
+// account pays for its gas.
+type UserOperationBuilder struct {
+	builder    *TransactionBuilder
+	bundler    *Bundler
+	entryPoint common.Address
+	// Paymaster sponsors the gas of operations.
+	Paymaster Paymaster
+}
+
+var _ transaction.Builder = (*UserOperationBuilder)(nil)
+
+func NewUserOperationBuilder(client *Client, bundler *Bundler, entryPoint string) *UserOperationBuilder {
+	return &UserOperationBuilder{
+		builder:    NewTransactionBuilder(client),
+		bundler:    bundler,
+		entryPoint: common.HexToAddress(entryPoint),
+	}
+}
+
+func (builder *UserOperationBuilder) Build(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.TransferPayload, error) {
+	ctx, span := telemetry.Start(ctx, "evm.build_user_operation", transaction.TransferAttributes(param)...)
+	defer span.End()
+
+	payload, err := builder.build(ctx, param)
+	span.RecordError(err)
+
+	return payload, err
+}
+
+func (builder *UserOperationBuilder) build(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.TransferPayload, error) {
+	switch mode := param.TransferMode(); {
+	case mode != transaction.TransferModeDirect:
+		return nil, transaction.TransferModeNotSupportedError{Mode: mode, Reason: "user operations are sent directly"}
+	case param.Deploy != nil:
+		return nil, fmt.Errorf("smart account %s cannot deploy contracts", param.SourceAddress)
+	}
+
+	networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	client := builder.builder.client
+	account := common.HexToAddress(param.SourceAddress)
+
+	chainID, err := call(ctx, client, "eth_chainId", client.Delegate.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve chain ID: %w", ClassifyError(err))
+	}
+
+	owner, err := callView[common.Address](ctx, builder.builder, &smartAccountABI, account, "owner")
+	if err != nil {
+
+		return nil, err
+	}
+
+	nonce, err := callView[*big.Int](ctx, builder.builder, &entryPointABI, builder.entryPoint, "getNonce",
+		account, big.NewInt(userOperationKey))
+	if err != nil {
+
+		return nil, err
+	}
+
+	toAddr, value, data, err := builder.builder.accountCall(ctx, param, networkCurrency, account)
+	if err != nil {
+
+		return nil, err
+	}
+
+	callData, err := smartAccountABI.Pack("execute", toAddr, value, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode execute: %w", err)
+	}
+
+	gasTipCap, gasFeeCap, err := builder.builder.fees(ctx, param)
+	if err != nil {
+
+		return nil, err
+	}
+
+	op := &UserOperation{
+		Sender:               account,
+		Nonce:                (*hexutil.Big)(nonce),
+		InitCode:             []byte{},
+		CallData:             callData,
+		MaxFeePerGas:         (*hexutil.Big)(gasFeeCap),
+		MaxPriorityFeePerGas: (*hexutil.Big)(gasTipCap),
+		PaymasterAndData:     []byte{},
+		Signature:            dummySignature,
+	}
+
+	if err := builder.estimate(ctx, op, chainID); err != nil {
+		return nil, err
+	}
+
+	op.Signature = []byte{}
+
+	bytes, err := MarshalUserOperation(op, builder.entryPoint, chainID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	payload := &transaction.TransferPayload{
+		Req:    param,
+		Sender: owner.Hex(),
+		Raw:    bytes,
+	}
+
+	switch {
+	case param.Call != nil:
+		payload.Call, err = decodeCall(param.Call, data)
+	case param.NFT != nil:
+		var definition string
+
+		definition, _, err = nftDefinition(param.NFT.Asset.Standard)
+		if err == nil {
+			payload.Call, err = decodeCall(&transaction.ContractCall{ABI: []byte(definition)}, data)
+		}
+	}
+
+	if err != nil {
+
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// estimate has the bundler estimate the gas of the operation, sponsored by the
+// paymaster if any, and sets the gas fields and the final paymaster data.
+func (builder *UserOperationBuilder) estimate(ctx context.Context, op *UserOperation, chainID *big.Int) error {
+	var err error
+
+	op.CallGasLimit = new(hexutil.Big)
+	op.VerificationGasLimit = new(hexutil.Big)
+	op.PreVerificationGas = new(hexutil.Big)
+
+	if builder.Paymaster != nil {
+		op.PaymasterAndData, err = builder.Paymaster.StubData(ctx, op, builder.entryPoint, chainID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve paymaster stub data: %w", err)
+		}
+	}
+
+	gas, err := builder.bundler.EstimateUserOperationGas(ctx, op, builder.entryPoint)
+	if err != nil {
+
+		return err
+	}
+
+	op.CallGasLimit = gas.CallGasLimit
+	op.VerificationGasLimit = gas.VerificationGasLimit
+	op.PreVerificationGas = gas.PreVerificationGas
+
+	if builder.Paymaster != nil {
+		op.PaymasterAndData, err = builder.Paymaster.Data(ctx, op, builder.entryPoint, chainID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve paymaster data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UserOperationSigner signs user operations with the key of the owner of their
+// account: the owner signs the userOpHash as an EIP-191 message, as
+// SimpleAccount expects.
+type UserOperationSigner struct {
+	ownerSigner transaction.MessageSigner
+}
+
+var _ transaction.Signer = (*UserOperationSigner)(nil)
+
+func NewUserOperationSigner(ownerSigner transaction.MessageSigner) *UserOperationSigner {
+	return &UserOperationSigner{
+		ownerSigner: ownerSigner,
+	}
+}
+
+func (signer *UserOperationSigner) Sign(ctx context.Context, payload *transaction.TransferPayload) error {
+	ctx, span := telemetry.Start(ctx, "evm.sign_user_operation", transaction.TransferAttributes(payload.Req)...)
+	defer span.End()
+
+	err := signer.sign(ctx, payload)
+	span.RecordError(err)
+
+	return err
+}
+
+func (signer *UserOperationSigner) sign(ctx context.Context, payload *transaction.TransferPayload) error {
+	op, entryPoint, chainID, err := UnmarshalUserOperation(payload.Raw)
+	if err != nil {
+
+		return err
+	}
+
+	hash, err := op.Hash(entryPoint, chainID)
+	if err != nil {
+
+		return err
+	}
+
+	networkCurrency, err := domain.NewNetworkCurrency(payload.Req.NetworkCurrencyID)
+	if err != nil {
+
+		return err
+	}
+
+	signature, err := signer.ownerSigner.SignPersonal(ctx, &transaction.MessageRequest{
+		ID:          payload.Req.ID,
+		Address:     payload.SenderAddress(),
+		NetworkCode: networkCurrency.Network.Code,
+		Message:     hash.Bytes(),
+		Principal:   payload.Req.Principal,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sign user operation as owner %s: %w", payload.SenderAddress(), err)
+	}
+
+	if !bytes.Equal(signature.Digest, PersonalHash(hash.Bytes())) {
+		return fmt.Errorf("owner %s signed %x instead of user operation %s", payload.SenderAddress(), signature.Digest, hash)
+	}
+
+	op.Signature = signature.Signature
+
+	signed, err := MarshalUserOperation(op, entryPoint, chainID)
+	if err != nil {
+
+		return err
+	}
+
+	if payload.ID == "" {
+		payload.ID = hash.Hex()
+	}
+
+	payload.Signed = signed
+
+	return nil
+}
+
+// UserOperationBroadcaster submits signed user operations to a bundler and
+// follows them until they are included.
+type UserOperationBroadcaster struct {
+	builder *TransactionBuilder
+	bundler *Bundler
+}
+
+var (
+	_ transaction.Broadcaster   = (*UserOperationBroadcaster)(nil)
+	_ transaction.OutboxChecker = (*UserOperationBroadcaster)(nil)
+)
+
+func NewUserOperationBroadcaster(client *Client, bundler *Bundler) *UserOperationBroadcaster {
+	return &UserOperationBroadcaster{
+		builder: NewTransactionBuilder(client),
+		bundler: bundler,
+	}
+}
+
+func (broadcaster *UserOperationBroadcaster) Broadcast(
+	ctx context.Context,
+	payload *transaction.TransferPayload,
+) error {
+	ctx, span := telemetry.Start(ctx, "evm.broadcast_user_operation", transaction.TransferAttributes(payload.Req)...)
+	defer span.End()
+
+	span.SetAttributes(telemetry.String("user_operation.hash", payload.ID))
+
+	op, entryPoint, _, err := UnmarshalUserOperation(payload.Signed)
+	if err != nil {
+		span.RecordError(err)
+
+		return err
+	}
+
+	hash, err := broadcaster.bundler.SendUserOperation(ctx, op, entryPoint)
+	if err != nil {
+		span.RecordError(err)
+
+		return fmt.Errorf("failed to send user operation (%s): %w", payload.ID, err)
+	}
+
+	if hash.Hex() != payload.ID {
+		err = fmt.Errorf("bundler accepted user operation %s as %s", payload.ID, hash.Hex())
+		span.RecordError(err)
+
+		return err
+	}
+
+	return nil
+}
+
+// IsKnown reports whether the bundler has the user operation of the payload,
+// either pending or included.
+func (broadcaster *UserOperationBroadcaster) IsKnown(
+	ctx context.Context,
+	payload *transaction.TransferPayload,
+) (bool, error) {
+	return broadcaster.bundler.HasUserOperation(ctx, common.HexToHash(payload.ID))
+}
+
+// Status polls the receipt of the user operation, which is reverted when the
+// call of the account failed. An operation that neither
+// the bundler nor the EntryPoint knows is superseded once the nonce of its
+// account moved past it.
+func (broadcaster *UserOperationBroadcaster) Status(
+	ctx context.Context,
+	payload *transaction.TransferPayload,
+) (transaction.OutboxStatus, error) {
+	hash := common.HexToHash(payload.ID)
+
+	receipt, err := broadcaster.bundler.UserOperationReceipt(ctx, hash)
+	if err != nil {
+
+		return "", err
+	}
+
+	if receipt != nil {
+		broadcaster.recordReceipt(payload, receipt)
+
+		if !receipt.Success {
+			return transaction.OutboxStatusReverted, nil
+		}
+
+		return transaction.OutboxStatusMined, nil
+	}
+
+	known, err := broadcaster.bundler.HasUserOperation(ctx, hash)
+	if err != nil {
+
+		return "", err
+	}
+
+	if known {
+		return transaction.OutboxStatusPending, nil
+	}
+
+	op, entryPoint, _, err := UnmarshalUserOperation(payload.Signed)
+	if err != nil {
+
+		return "", err
+	}
+
+	nonce, err := callView[*big.Int](ctx, broadcaster.builder, &entryPointABI, entryPoint, "getNonce",
+		op.Sender, big.NewInt(userOperationKey))
+	if err != nil {
+
+		return "", err
+	}
+
+	if nonce.Cmp(op.Nonce.ToInt()) > 0 {
+		return transaction.OutboxStatusSuperseded, nil
+	}
+
+	return transaction.OutboxStatusUnseen, nil
+}
+
+// recordReceipt observes the gas cost of an included operation, paid by the
+// account or its paymaster.
+func (broadcaster *UserOperationBroadcaster) recordReceipt(
+	payload *transaction.TransferPayload,
+	receipt *UserOperationReceipt,
+) {
+	networkCurrency, err := domain.NewNetworkCurrency(payload.Req.NetworkCurrencyID)
+	if err != nil || receipt.ActualGasCost == nil {
+		return
+	}
+
+	nativeCurrency, err := nativeNetworkCurrency(networkCurrency.Network.Code)
+	if err != nil {
+		return
+	}
+
+	feePaid.Observe(ToDecimal(receipt.ActualGasCost.ToInt(), nativeCurrency.Scale).InexactFloat64(),
+		networkCurrency.Network.Code)
+}
@@ -0,0 +1,56 @@
+package transaction
+
+import (
+	"context"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventBuilt     EventType = "built"
+	EventBroadcast EventType = "broadcast"
+	EventConfirmed EventType = "confirmed"
+	EventFailed    EventType = "failed"
+	EventReplaced  EventType = "replaced"
+)
+
+var EventTypes = []EventType{
+	EventBuilt,
+	EventBroadcast,
+	EventConfirmed,
+	EventFailed,
+	EventReplaced,
+}
+
+func ParseEventType(value string) (EventType, bool) {
+	for _, eventType := range EventTypes {
+		if string(eventType) == value {
+			return eventType, true
+		}
+	}
+
+	return "", false
+}
+
+type Event struct {
+	Type       EventType
+	Payload    *TransferPayload
+	Err        error
+	OccurredAt time.Time
+}
+
+func NewEvent(eventType EventType, payload *TransferPayload, err error) *Event {
+	return &Event{
+		Type:       eventType,
+		Payload:    payload,
+		Err:        err,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// Notifier receives transfer lifecycle events. Implementations must not block
+// the caller, as events are emitted inline with the transfer.
+type Notifier interface {
+	Notify(ctx context.Context, event *Event)
+}
@@ -0,0 +1,50 @@
+package transaction
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+type MessageSigningNotSupportedError struct {
+	ProviderID string
+}
+
+func (e MessageSigningNotSupportedError) Error() string {
+	return "message signing not supported for provider " + e.ProviderID
+}
+
+// MessageRequest asks the key of Address on the network to sign a message
+// rather than a transaction, e.g. a login challenge or an off-chain order.
+type MessageRequest struct {
+	ID          string
+	Address     string
+	NetworkCode string
+	// Message is signed by SignPersonal.
+	Message []byte
+	// TypedData is the typed data document signed by SignTypedData, in the
+	// JSON format of the chain, e.g. EIP-712 for EVM chains.
+	TypedData json.RawMessage
+	// Principal is the caller that requested the signature, nil for internal
+	// callers.
+	Principal *domain.Principal
+}
+
+type MessageSignature struct {
+	Req     *MessageRequest
+	Address string
+	// Digest is the hash that was signed.
+	Digest    []byte
+	Signature []byte
+}
+
+// MessageSigner signs messages with the keys that sign transactions.
+// Signers, and the transferors routing to them, implement it optionally.
+type MessageSigner interface {
+	// SignPersonal signs a plain message, prefixed so that it cannot be a
+	// valid transaction (EIP-191 on EVM chains).
+	SignPersonal(ctx context.Context, req *MessageRequest) (*MessageSignature, error)
+	// SignTypedData signs a structured message (EIP-712 on EVM chains).
+	SignTypedData(ctx context.Context, req *MessageRequest) (*MessageSignature, error)
+}
@@ -0,0 +1,110 @@
+package transaction
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+type NFTNotOwnedError struct {
+	Asset   *domain.NFT
+	Owner   string
+	Balance *big.Int
+}
+
+func (e NFTNotOwnedError) Error() string {
+	if e.Asset.Standard == domain.NFTStandardERC1155 {
+		return fmt.Sprintf("%s holds %s of %s", e.Owner, e.Balance, e.Asset)
+	}
+
+	return fmt.Sprintf("%s does not own %s", e.Owner, e.Asset)
+}
+
+type NFTNotApprovedError struct {
+	Asset    *domain.NFT
+	Owner    string
+	Operator string
+}
+
+func (e NFTNotApprovedError) Error() string {
+	return fmt.Sprintf("%s is not approved by %s to transfer %s", e.Operator, e.Owner, e.Asset)
+}
+
+// NFTTransferRequest transfers an NFT from a managed address.
+type NFTTransferRequest struct {
+	ID                 string
+	SourceAddress      string
+	DestinationAddress string
+	Asset              *domain.NFT
+	// Amount is the number of ERC-1155 tokens to transfer. It must be nil or
+	// one for ERC-721 tokens.
+	Amount *big.Int
+	// Owner holds the token if it is not the source address, which then
+	// transfers it as an operator approved by Owner.
+	Owner   string
+	QuoteID string
+	FeeTier FeeTier
+	// Principal is the caller that requested the transfer, nil for internal
+	// callers.
+	Principal *domain.Principal
+}
+
+// NFTTransfer is the NFT a transfer moves to its destination address instead
+// of an amount of its currency.
+type NFTTransfer struct {
+	Asset  domain.NFT
+	Amount *big.Int
+	Owner  string
+}
+
+// Quantity returns the number of tokens transferred, which defaults to one.
+func (transfer *NFTTransfer) Quantity() *big.Int {
+	if transfer.Amount == nil {
+		return big.NewInt(1)
+	}
+
+	return transfer.Amount
+}
+
+// OwnerAddress returns the holder of the token, the source address unless
+// Owner is set.
+func (transfer *NFTTransfer) OwnerAddress(param *TransferRequest) string {
+	if transfer.Owner != "" {
+		return transfer.Owner
+	}
+
+	return param.SourceAddress
+}
+
+// TransferRequest returns the transfer of the NFT. It is paid for in the
+// native currency of the network and moves no amount of it.
+func (req *NFTTransferRequest) TransferRequest() (*TransferRequest, error) {
+	if req.Asset == nil {
+		return nil, fmt.Errorf("NFT transfer %s has no asset", req.ID)
+	}
+
+	network, err := domain.GetNetwork(req.Asset.NetworkCode)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &TransferRequest{
+		ID:                 req.ID,
+		SourceAddress:      req.SourceAddress,
+		DestinationAddress: req.DestinationAddress,
+		Amount:             decimal.Zero,
+		NetworkCurrencyID:  network.NativeToken,
+		QuoteID:            req.QuoteID,
+		FeeTier:            req.FeeTier,
+		NFT: &NFTTransfer{
+			Asset:  *req.Asset,
+			Amount: req.Amount,
+			Owner:  req.Owner,
+		},
+		Principal: req.Principal,
+	}, nil
+}
@@ -0,0 +1,234 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	BundleVersion = 1
+
+	// bundleTextPrefix marks the text form of a bundle, which only uses
+	// characters that survive QR codes and copy and paste.
+	bundleTextPrefix = "bsb1:"
+)
+
+type OfflineNotSupportedError struct {
+	ProviderID string
+}
+
+func (e OfflineNotSupportedError) Error() string {
+	return "offline signing not supported by provider " + e.ProviderID
+}
+
+type BundleNotFoundError struct {
+	TransferID string
+}
+
+func (e BundleNotFoundError) Error() string {
+	return "no exported bundle for transfer " + e.TransferID
+}
+
+type BundleMismatchError struct {
+	TransferID string
+	Reason     string
+}
+
+func (e BundleMismatchError) Error() string {
+	return fmt.Sprintf("bundle of transfer %s does not match its export: %s", e.TransferID, e.Reason)
+}
+
+// TransactionDescription is the chain specific part of a bundle.
+type TransactionDescription struct {
+	Chain   string
+	ChainID string
+	// Lines describe the transaction for the person approving its signature.
+	Lines []string
+}
+
+// Describer decodes a built transaction for offline review. Builders that
+// support offline signing implement it.
+type Describer interface {
+	Describe(payload *TransferPayload) (*TransactionDescription, error)
+}
+
+// SignatureVerifier checks that payload.Signed is payload.Raw signed by the
+// source address of the transfer, and returns the transaction hash.
+type SignatureVerifier interface {
+	VerifySigned(payload *TransferPayload) (string, error)
+}
+
+// OfflineTransferor splits a transfer into building, done by Prepare, and
+// broadcasting a transaction that was signed elsewhere, done by Submit.
+type OfflineTransferor interface {
+	Prepare(ctx context.Context, param *TransferRequest) (*Bundle, error)
+	Submit(ctx context.Context, payload *TransferPayload) (*TransferPayload, error)
+}
+
+// Bundle carries an unsigned transaction to an offline signer and the signed
+// transaction back. Checksum covers everything but the signature fields, so a
+// signer can detect a bundle that was changed in transit; it does not
+// authenticate the bundle, which is why Manager also compares a returned
+// bundle with its export.
+type Bundle struct {
+	Version            int      `json:"version"`
+	TransferID         string   `json:"transfer_id"`
+	ProviderID         string   `json:"provider_id"`
+	Chain              string   `json:"chain"`
+	ChainID            string   `json:"chain_id"`
+	NetworkCurrencyID  string   `json:"network_currency_id"`
+	SourceAddress      string   `json:"source_address"`
+	DestinationAddress string   `json:"destination_address"`
+	Amount             string   `json:"amount"`
+	Summary            []string `json:"summary"`
+	Raw                []byte   `json:"raw"`
+	Checksum           string   `json:"checksum"`
+	Signed             []byte   `json:"signed,omitempty"`
+	TransactionHash    string   `json:"transaction_hash,omitempty"`
+}
+
+func NewBundle(param *TransferRequest, payload *TransferPayload, description *TransactionDescription) *Bundle {
+	return &Bundle{
+		Version:            BundleVersion,
+		TransferID:         param.ID,
+		Chain:              description.Chain,
+		ChainID:            description.ChainID,
+		NetworkCurrencyID:  param.NetworkCurrencyID,
+		SourceAddress:      param.SourceAddress,
+		DestinationAddress: param.DestinationAddress,
+		Amount:             param.Amount.String(),
+		Summary:            description.Lines,
+		Raw:                payload.Raw,
+	}
+}
+
+func (bundle *Bundle) ComputeChecksum() (string, error) {
+	unsigned := *bundle
+	unsigned.Checksum = ""
+	unsigned.Signed = nil
+	unsigned.TransactionHash = ""
+
+	content, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshall bundle: %w", err)
+	}
+
+	digest := sha256.Sum256(content)
+
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// Seal sets the checksum of a bundle that is ready for export.
+func (bundle *Bundle) Seal() error {
+	checksum, err := bundle.ComputeChecksum()
+	if err != nil {
+		return err
+	}
+
+	bundle.Checksum = checksum
+
+	return nil
+}
+
+func (bundle *Bundle) VerifyChecksum() error {
+	if bundle.Version != BundleVersion {
+		return BundleMismatchError{
+			TransferID: bundle.TransferID,
+			Reason:     fmt.Sprintf("unsupported version %d", bundle.Version),
+		}
+	}
+
+	checksum, err := bundle.ComputeChecksum()
+	if err != nil {
+		return err
+	}
+
+	if checksum != bundle.Checksum {
+		return BundleMismatchError{TransferID: bundle.TransferID, Reason: "checksum does not match content"}
+	}
+
+	return nil
+}
+
+// Text encodes the bundle on a single line, e.g. for a QR code.
+func (bundle *Bundle) Text() (string, error) {
+	content, err := json.Marshal(bundle)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshall bundle: %w", err)
+	}
+
+	return bundleTextPrefix + base64.RawURLEncoding.EncodeToString(content), nil
+}
+
+// ParseBundle reads a bundle in either its JSON or its text form.
+func ParseBundle(content []byte) (*Bundle, error) {
+	content = bytes.TrimSpace(content)
+
+	if text, ok := strings.CutPrefix(string(content), bundleTextPrefix); ok {
+		decoded, err := base64.RawURLEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode bundle: %w", err)
+		}
+
+		content = decoded
+	}
+
+	bundle := &Bundle{}
+
+	if err := json.Unmarshal(content, bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+
+	return bundle, nil
+}
+
+// exportedBundle is a bundle waiting for its signature, with the request it
+// was built from.
+type exportedBundle struct {
+	bundle *Bundle
+	param  *TransferRequest
+}
+
+type bundleStore struct {
+	mu      sync.Mutex
+	bundles map[string]*exportedBundle
+}
+
+func newBundleStore() *bundleStore {
+	return &bundleStore{
+		bundles: make(map[string]*exportedBundle),
+	}
+}
+
+func (store *bundleStore) put(exported *exportedBundle) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.bundles[exported.bundle.TransferID] = exported
+}
+
+func (store *bundleStore) get(transferID string) (*exportedBundle, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	exported, ok := store.bundles[transferID]
+	if !ok {
+		return nil, BundleNotFoundError{TransferID: transferID}
+	}
+
+	return exported, nil
+}
+
+func (store *bundleStore) remove(transferID string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.bundles, transferID)
+}
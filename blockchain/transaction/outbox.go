@@ -0,0 +1,194 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+)
+
+const DefaultRebroadcastInterval = 15 * time.Second
+
+type OutboxStatus string
+
+const (
+	// OutboxStatusUnseen means the node knows nothing about the transaction and it should be sent again.
+	OutboxStatusUnseen OutboxStatus = "unseen"
+	// OutboxStatusPending means the transaction is waiting in the mempool.
+	OutboxStatusPending OutboxStatus = "pending"
+	// OutboxStatusMined means the transaction is included in a block.
+	OutboxStatusMined OutboxStatus = "mined"
+	// OutboxStatusReverted means the transaction is included in a block, but its execution failed.
+	OutboxStatusReverted OutboxStatus = "reverted"
+	// OutboxStatusSuperseded means another transaction consumed the nonce.
+	OutboxStatusSuperseded OutboxStatus = "superseded"
+)
+
+type KnownCheckNotSupportedError struct{}
+
+func (e KnownCheckNotSupportedError) Error() string {
+	return "broadcaster cannot tell whether the node has a transaction"
+}
+
+type OutboxEntry struct {
+	Payload   *TransferPayload `json:"payload"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// Outbox durably keeps signed payloads from before they are broadcast until
+// they are mined or superseded, so that a crash between signing and
+// broadcasting does not lose the signed transaction.
+type Outbox interface {
+	Put(ctx context.Context, entry *OutboxEntry) error
+	Remove(ctx context.Context, id string) error
+	List(ctx context.Context) ([]*OutboxEntry, error)
+}
+
+type OutboxChecker interface {
+	Status(ctx context.Context, payload *TransferPayload) (OutboxStatus, error)
+}
+
+// KnownChecker reports whether the node already has the signed transaction of
+// the payload, either pending or mined.
+type KnownChecker interface {
+	IsKnown(ctx context.Context, payload *TransferPayload) (bool, error)
+}
+
+// Rebroadcaster sends outbox entries that the node has not seen again and
+// retires the ones that are mined or superseded.
+type Rebroadcaster struct {
+	outbox      Outbox
+	broadcaster Broadcaster
+	checker     OutboxChecker
+	interval    time.Duration
+	// Auditor, if set, records the entries that are mined together with what
+	// the checker learned from their receipts, e.g. the address of a deployed
+	// contract, and the entries that reverted.
+	Auditor Auditor
+	// Notifier, if set, receives EventConfirmed for entries the checker finds
+	// mined, EventReplaced for entries whose nonce another transaction took
+	// and EventFailed for entries that reverted or that the node rejects for
+	// good.
+	Notifier Notifier
+}
+
+func NewRebroadcaster(
+	outbox Outbox,
+	broadcaster Broadcaster,
+	checker OutboxChecker,
+	interval time.Duration,
+) *Rebroadcaster {
+	return &Rebroadcaster{
+		outbox:      outbox,
+		broadcaster: broadcaster,
+		checker:     checker,
+		interval:    interval,
+	}
+}
+
+// Run sweeps the outbox immediately, to recover entries left over from a
+// previous run, and then on every interval until the context is done.
+func (rebroadcaster *Rebroadcaster) Run(ctx context.Context) {
+	ticker := time.NewTicker(rebroadcaster.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := rebroadcaster.Sweep(ctx); err != nil {
+			slog.Log(ctx, slog.LevelError, "failed to sweep outbox:", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rebroadcaster *Rebroadcaster) Sweep(ctx context.Context) error {
+	entries, err := rebroadcaster.outbox.List(ctx)
+	if err != nil {
+
+		return err
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := rebroadcaster.process(ctx, entry); err != nil {
+			slog.Log(ctx, slog.LevelWarn, "failed to process outbox entry:", "id", entry.Payload.ID, "err", err)
+		}
+	}
+
+	return nil
+}
+
+func (rebroadcaster *Rebroadcaster) process(ctx context.Context, entry *OutboxEntry) error {
+	status, err := rebroadcaster.checker.Status(ctx, entry.Payload)
+	if err != nil {
+
+		return err
+	}
+
+	switch status {
+	case OutboxStatusMined, OutboxStatusSuperseded:
+		if status == OutboxStatusMined {
+			auditOutcome(ctx, rebroadcaster.Auditor, AuditMined, entry.Payload.Req, entry.Payload, nil)
+			rebroadcaster.notify(ctx, EventConfirmed, entry.Payload, nil)
+		} else {
+			rebroadcaster.notify(ctx, EventReplaced, entry.Payload, nil)
+		}
+
+		slog.Log(ctx, slog.LevelInfo, "retiring outbox entry:", "id", entry.Payload.ID, "status", status)
+
+		return rebroadcaster.outbox.Remove(ctx, entry.Payload.ID)
+	case OutboxStatusReverted:
+		err := blockchain.RevertedError{ID: entry.Payload.ID}
+
+		auditOutcome(ctx, rebroadcaster.Auditor, AuditFailed, entry.Payload.Req, entry.Payload, err)
+		rebroadcaster.notify(ctx, EventFailed, entry.Payload, err)
+
+		slog.Log(ctx, slog.LevelWarn, "retiring reverted outbox entry:", "id", entry.Payload.ID)
+
+		return rebroadcaster.outbox.Remove(ctx, entry.Payload.ID)
+	case OutboxStatusPending:
+		return nil
+	case OutboxStatusUnseen:
+	}
+
+	slog.Log(ctx, slog.LevelInfo, "re-broadcasting outbox entry:", "id", entry.Payload.ID)
+
+	err = rebroadcaster.broadcaster.Broadcast(ctx, entry.Payload)
+
+	var alreadyKnownErr blockchain.AlreadyKnownError
+	if err == nil || errors.As(err, &alreadyKnownErr) {
+		return nil
+	}
+
+	if !blockchain.IsRetryable(err) {
+		// the node rejects the transaction for good, e.g. for insufficient funds
+		slog.Log(ctx, slog.LevelWarn, "dropping rejected outbox entry:", "id", entry.Payload.ID, "err", err)
+		rebroadcaster.notify(ctx, EventFailed, entry.Payload, err)
+
+		return rebroadcaster.outbox.Remove(ctx, entry.Payload.ID)
+	}
+
+	return err
+}
+
+func (rebroadcaster *Rebroadcaster) notify(
+	ctx context.Context,
+	eventType EventType,
+	payload *TransferPayload,
+	err error,
+) {
+	if rebroadcaster.Notifier == nil {
+		return
+	}
+
+	rebroadcaster.Notifier.Notify(ctx, NewEvent(eventType, payload, err))
+}
@@ -12,7 +12,12 @@ type TransactionTransferor struct {
 	delegates map[string]transaction.Transferor
 }
 
-var _ transaction.Transferor = (*TransactionTransferor)(nil)
+var (
+	_ transaction.Transferor        = (*TransactionTransferor)(nil)
+	_ transaction.Quoter            = (*TransactionTransferor)(nil)
+	_ transaction.OfflineTransferor = (*TransactionTransferor)(nil)
+	_ transaction.MessageSigner     = (*TransactionTransferor)(nil)
+)
 
 func NewTransactionTranferor(delegates map[string]transaction.Transferor) *TransactionTransferor {
 	return &TransactionTransferor{
@@ -43,3 +48,112 @@ func (ttf *TransactionTransferor) Transfer(
 
 	return payload, nil
 }
+
+func (ttf *TransactionTransferor) Quote(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.Quote, error) {
+	networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	ctr := ttf.delegates[networkCurrency.Network.Code]
+	if ctr == nil {
+		return nil, &blockchain.NetworkNotSupportedError{NetworkCode: networkCurrency.Network.Code}
+	}
+
+	quoter, ok := ctr.(transaction.Quoter)
+	if !ok {
+		return nil, transaction.QuoteNotSupportedError{}
+	}
+
+	return quoter.Quote(ctx, param)
+}
+
+func (ttf *TransactionTransferor) Prepare(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.Bundle, error) {
+	offline, err := ttf.offline(param)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return offline.Prepare(ctx, param)
+}
+
+func (ttf *TransactionTransferor) Submit(
+	ctx context.Context,
+	payload *transaction.TransferPayload,
+) (*transaction.TransferPayload, error) {
+	offline, err := ttf.offline(payload.Req)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return offline.Submit(ctx, payload)
+}
+
+func (ttf *TransactionTransferor) offline(param *transaction.TransferRequest) (transaction.OfflineTransferor, error) {
+	networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	ctr := ttf.delegates[networkCurrency.Network.Code]
+	if ctr == nil {
+		return nil, &blockchain.NetworkNotSupportedError{NetworkCode: networkCurrency.Network.Code}
+	}
+
+	offline, ok := ctr.(transaction.OfflineTransferor)
+	if !ok {
+		return nil, transaction.OfflineNotSupportedError{}
+	}
+
+	return offline, nil
+}
+
+func (ttf *TransactionTransferor) SignPersonal(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+) (*transaction.MessageSignature, error) {
+	signer, err := ttf.messageSigner(req)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return signer.SignPersonal(ctx, req)
+}
+
+func (ttf *TransactionTransferor) SignTypedData(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+) (*transaction.MessageSignature, error) {
+	signer, err := ttf.messageSigner(req)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return signer.SignTypedData(ctx, req)
+}
+
+func (ttf *TransactionTransferor) messageSigner(req *transaction.MessageRequest) (transaction.MessageSigner, error) {
+	ctr := ttf.delegates[req.NetworkCode]
+	if ctr == nil {
+		return nil, &blockchain.NetworkNotSupportedError{NetworkCode: req.NetworkCode}
+	}
+
+	signer, ok := ctr.(transaction.MessageSigner)
+	if !ok {
+		return nil, transaction.MessageSigningNotSupportedError{}
+	}
+
+	return signer, nil
+}
@@ -0,0 +1,160 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const DefaultQuoteTTL = 30 * time.Second
+
+type FeeTier string
+
+const (
+	FeeTierSlow     FeeTier = "slow"
+	FeeTierStandard FeeTier = "standard"
+	FeeTierFast     FeeTier = "fast"
+)
+
+type QuoteNotSupportedError struct {
+	ProviderID string
+}
+
+func (e QuoteNotSupportedError) Error() string {
+	return "quote not supported for provider " + e.ProviderID
+}
+
+type QuoteNotFoundError struct {
+	QuoteID string
+}
+
+func (e QuoteNotFoundError) Error() string {
+	return "quote not found " + e.QuoteID
+}
+
+type QuoteExpiredError struct {
+	QuoteID   string
+	ExpiresAt time.Time
+}
+
+func (e QuoteExpiredError) Error() string {
+	return fmt.Sprintf("quote %s expired at %s", e.QuoteID, e.ExpiresAt.Format(time.RFC3339))
+}
+
+type QuoteMismatchError struct {
+	QuoteID string
+	Field   string
+}
+
+func (e QuoteMismatchError) Error() string {
+	return fmt.Sprintf("transfer does not match quote %s: %s differs", e.QuoteID, e.Field)
+}
+
+// PinnedFee fixes the gas parameters of a transfer, so that it is built with
+// the fees the user accepted in a quote rather than freshly estimated ones.
+type PinnedFee struct {
+	GasLimit             uint64
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+type FeeOption struct {
+	Tier                 FeeTier         `json:"tier"`
+	MaxFeePerGas         *big.Int        `json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas *big.Int        `json:"max_priority_fee_per_gas"`
+	Fee                  decimal.Decimal `json:"fee"`
+	TotalCost            decimal.Decimal `json:"total_cost"`
+	BalanceAfter         decimal.Decimal `json:"balance_after"`
+}
+
+// Quote is an estimate of what a transfer costs. Fees, total cost and native
+// balances are denominated in FeeCurrencyID; for token transfers the token
+// balance is reported separately.
+type Quote struct {
+	ID                   string           `json:"id"`
+	Req                  *TransferRequest `json:"-"`
+	ProviderID           string           `json:"provider_id"`
+	GasLimit             uint64           `json:"gas_limit"`
+	FeeCurrencyID        string           `json:"fee_currency_id"`
+	Balance              decimal.Decimal  `json:"balance"`
+	FeeOptions           []*FeeOption     `json:"fee_options"`
+	CurrencyBalance      *decimal.Decimal `json:"currency_balance,omitempty"`
+	CurrencyBalanceAfter *decimal.Decimal `json:"currency_balance_after,omitempty"`
+	ExpiresAt            time.Time        `json:"expires_at"`
+}
+
+func (quote *Quote) FeeOption(tier FeeTier) (*FeeOption, bool) {
+	for _, option := range quote.FeeOptions {
+		if option.Tier == tier {
+			return option, true
+		}
+	}
+
+	return nil, false
+}
+
+type Quoter interface {
+	Quote(ctx context.Context, param *TransferRequest) (*Quote, error)
+}
+
+type quoteStore struct {
+	mutex  sync.Mutex
+	quotes map[string]*Quote
+}
+
+func newQuoteStore() *quoteStore {
+	return &quoteStore{
+		quotes: make(map[string]*Quote),
+	}
+}
+
+func (store *quoteStore) put(quote *Quote) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+
+	for id, stored := range store.quotes {
+		if now.After(stored.ExpiresAt) {
+			delete(store.quotes, id)
+		}
+	}
+
+	store.quotes[quote.ID] = quote
+}
+
+// get returns the quote without removing it.
+func (store *quoteStore) get(quoteID string) (*Quote, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	quote, ok := store.quotes[quoteID]
+	if !ok {
+		return nil, QuoteNotFoundError{QuoteID: quoteID}
+	}
+
+	if time.Now().After(quote.ExpiresAt) {
+		return nil, QuoteExpiredError{QuoteID: quoteID, ExpiresAt: quote.ExpiresAt}
+	}
+
+	return quote, nil
+}
+
+// take removes the quote, so that a quote pins at most one transfer. It fails
+// if another transfer took the quote first.
+func (store *quoteStore) take(quoteID string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.quotes[quoteID]; !ok {
+		return QuoteNotFoundError{QuoteID: quoteID}
+	}
+
+	delete(store.quotes, quoteID)
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package retry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 30 * time.Second
+)
+
+type CircuitOpenError struct {
+	Name string
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %s is open", e.Name)
+}
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// Breaker is a circuit breaker shared by the decorators of one provider. It
+// opens after FailureThreshold consecutive calls that failed transiently,
+// rejects calls for Cooldown, then lets a single trial call through to decide
+// whether to close again.
+type Breaker struct {
+	name             string
+	failureThreshold int
+	cooldown         time.Duration
+	// now is replaced in tests.
+	now func() time.Time
+
+	mutex    sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func NewBreaker(name string, failureThreshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		now:              time.Now,
+	}
+}
+
+func (breaker *Breaker) Allow() error {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	switch breaker.state {
+	case stateOpen:
+		if breaker.now().Sub(breaker.openedAt) < breaker.cooldown {
+			return CircuitOpenError{Name: breaker.name}
+		}
+
+		breaker.state = stateHalfOpen
+
+		return nil
+	case stateHalfOpen:
+		// a trial call is already in flight
+		return CircuitOpenError{Name: breaker.name}
+	case stateClosed:
+	}
+
+	return nil
+}
+
+func (breaker *Breaker) Success() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.state = stateClosed
+	breaker.failures = 0
+}
+
+func (breaker *Breaker) Failure() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.failures++
+
+	if breaker.state == stateHalfOpen || breaker.failures >= breaker.failureThreshold {
+		breaker.state = stateOpen
+		breaker.openedAt = breaker.now()
+	}
+}
+
+// Release ends a call whose outcome says nothing about the node, e.g. one
+// rejected for insufficient funds. A trial call hands the trial over to the
+// next call; otherwise nothing changes.
+func (breaker *Breaker) Release() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if breaker.state == stateHalfOpen {
+		breaker.state = stateOpen
+	}
+}
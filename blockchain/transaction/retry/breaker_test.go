@@ -0,0 +1,179 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.now
+}
+
+func (clock *fakeClock) Advance(duration time.Duration) {
+	clock.now = clock.now.Add(duration)
+}
+
+func newTestBreaker(failureThreshold int, cooldown time.Duration) (*Breaker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)}
+
+	breaker := NewBreaker("test", failureThreshold, cooldown)
+	breaker.now = clock.Now
+
+	return breaker, clock
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	breaker, _ := newTestBreaker(3, time.Minute)
+
+	for range 2 {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+
+		breaker.Failure()
+	}
+
+	// a success resets the count of consecutive failures
+	breaker.Success()
+
+	for range 2 {
+		breaker.Failure()
+	}
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow returned %v below the threshold", err)
+	}
+
+	breaker.Failure()
+
+	var openErr CircuitOpenError
+	if err := breaker.Allow(); !errors.As(err, &openErr) || openErr.Name != "test" {
+		t.Fatalf("Allow returned %v, want CircuitOpenError", err)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	breaker, clock := newTestBreaker(1, time.Minute)
+
+	breaker.Failure()
+
+	clock.Advance(time.Minute - time.Nanosecond)
+
+	if err := breaker.Allow(); err == nil {
+		t.Fatal("Allow succeeded before the cooldown passed")
+	}
+
+	clock.Advance(time.Nanosecond)
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow returned %v for the trial call", err)
+	}
+
+	// only one trial call at a time
+	if err := breaker.Allow(); err == nil {
+		t.Fatal("Allow let a second call through while half-open")
+	}
+
+	// a failed trial opens the breaker for another cooldown
+	breaker.Failure()
+
+	if err := breaker.Allow(); err == nil {
+		t.Fatal("Allow succeeded after a failed trial call")
+	}
+
+	clock.Advance(time.Minute)
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow returned %v for the trial call", err)
+	}
+
+	breaker.Success()
+
+	for range 3 {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Allow returned %v after a successful trial call", err)
+		}
+	}
+}
+
+func TestBreakerReleaseHandsOverTrial(t *testing.T) {
+	breaker, clock := newTestBreaker(1, time.Minute)
+
+	breaker.Failure()
+	clock.Advance(time.Minute)
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow returned %v for the trial call", err)
+	}
+
+	// the trial call said nothing about the node, so the next call is the trial
+	breaker.Release()
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow returned %v after the trial was released", err)
+	}
+}
+
+func TestDoCountsOneOutcomePerCall(t *testing.T) {
+	ctx := context.Background()
+	breaker, _ := newTestBreaker(2, time.Minute)
+	policy := Policy{MaxAttempts: 3, InitialBackoff: time.Microsecond, MaxBackoff: time.Microsecond}
+
+	transientErr := blockchain.TransientNetworkError{Err: errors.New("connection refused")}
+
+	attempts := 0
+
+	err := Do(ctx, policy, breaker, func(context.Context) error {
+		attempts++
+
+		return transientErr
+	}, nil)
+	if !errors.Is(err, transientErr) || attempts != 3 {
+		t.Fatalf("Do returned %v after %d attempts, want the transient error after 3", err, attempts)
+	}
+
+	// three failed attempts are one failure, so the breaker is still closed
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow returned %v after one failed call", err)
+	}
+
+	// an error that is not transient is not retried and leaves the breaker as it was
+	fundsErr := blockchain.InsufficientFundsError{Err: errors.New("insufficient funds")}
+
+	attempts = 0
+
+	err = Do(ctx, policy, breaker, func(context.Context) error {
+		attempts++
+
+		return fundsErr
+	}, nil)
+	if !errors.Is(err, fundsErr) || attempts != 1 {
+		t.Fatalf("Do returned %v after %d attempts, want the error after 1", err, attempts)
+	}
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow returned %v after a call that failed for good", err)
+	}
+
+	_ = Do(ctx, policy, breaker, func(context.Context) error { return transientErr }, nil)
+
+	var openErr CircuitOpenError
+
+	err = Do(ctx, policy, breaker, func(context.Context) error {
+		t.Fatal("Do ran the operation while the breaker was open")
+
+		return nil
+	}, nil)
+	if !errors.As(err, &openErr) {
+		t.Fatalf("Do returned %v, want CircuitOpenError", err)
+	}
+}
@@ -0,0 +1,216 @@
+package retry
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+type Builder struct {
+	delegate transaction.Builder
+	policy   Policy
+	breaker  *Breaker
+}
+
+var _ transaction.Builder = (*Builder)(nil)
+
+func NewBuilder(delegate transaction.Builder, policy Policy, breaker *Breaker) *Builder {
+	return &Builder{
+		delegate: delegate,
+		policy:   policy,
+		breaker:  breaker,
+	}
+}
+
+func (builder *Builder) Build(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.TransferPayload, error) {
+	var payload *transaction.TransferPayload
+
+	err := Do(ctx, builder.policy, builder.breaker, func(ctx context.Context) error {
+		var err error
+		payload, err = builder.delegate.Build(ctx, param)
+
+		return err
+	}, nil)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// Quote keeps quotes available through the decorator when the delegate supports them.
+func (builder *Builder) Quote(
+	ctx context.Context,
+	param *transaction.TransferRequest,
+) (*transaction.Quote, error) {
+	quoter, ok := builder.delegate.(transaction.Quoter)
+	if !ok {
+		return nil, transaction.QuoteNotSupportedError{}
+	}
+
+	var quote *transaction.Quote
+
+	err := Do(ctx, builder.policy, builder.breaker, func(ctx context.Context) error {
+		var err error
+		quote, err = quoter.Quote(ctx, param)
+
+		return err
+	}, nil)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+// Describe keeps offline signing available through the decorator. Describing
+// a built transaction does not call the node, so it is not retried.
+func (builder *Builder) Describe(
+	payload *transaction.TransferPayload,
+) (*transaction.TransactionDescription, error) {
+	describer, ok := builder.delegate.(transaction.Describer)
+	if !ok {
+		return nil, transaction.OfflineNotSupportedError{}
+	}
+
+	return describer.Describe(payload)
+}
+
+func (builder *Builder) VerifySigned(payload *transaction.TransferPayload) (string, error) {
+	verifier, ok := builder.delegate.(transaction.SignatureVerifier)
+	if !ok {
+		return "", transaction.OfflineNotSupportedError{}
+	}
+
+	return verifier.VerifySigned(payload)
+}
+
+type Signer struct {
+	delegate transaction.Signer
+	policy   Policy
+	breaker  *Breaker
+}
+
+var _ transaction.Signer = (*Signer)(nil)
+
+func NewSigner(delegate transaction.Signer, policy Policy, breaker *Breaker) *Signer {
+	return &Signer{
+		delegate: delegate,
+		policy:   policy,
+		breaker:  breaker,
+	}
+}
+
+func (signer *Signer) Sign(ctx context.Context, payload *transaction.TransferPayload) error {
+	return Do(ctx, signer.policy, signer.breaker, func(ctx context.Context) error {
+		return signer.delegate.Sign(ctx, payload)
+	}, nil)
+}
+
+// SignPersonal keeps message signing available through the decorator when the
+// delegate supports it.
+func (signer *Signer) SignPersonal(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+) (*transaction.MessageSignature, error) {
+	messageSigner, ok := signer.delegate.(transaction.MessageSigner)
+	if !ok {
+		return nil, transaction.MessageSigningNotSupportedError{}
+	}
+
+	var signature *transaction.MessageSignature
+
+	err := Do(ctx, signer.policy, signer.breaker, func(ctx context.Context) error {
+		var err error
+		signature, err = messageSigner.SignPersonal(ctx, req)
+
+		return err
+	}, nil)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+func (signer *Signer) SignTypedData(
+	ctx context.Context,
+	req *transaction.MessageRequest,
+) (*transaction.MessageSignature, error) {
+	messageSigner, ok := signer.delegate.(transaction.MessageSigner)
+	if !ok {
+		return nil, transaction.MessageSigningNotSupportedError{}
+	}
+
+	var signature *transaction.MessageSignature
+
+	err := Do(ctx, signer.policy, signer.breaker, func(ctx context.Context) error {
+		var err error
+		signature, err = messageSigner.SignTypedData(ctx, req)
+
+		return err
+	}, nil)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+// Broadcaster retries sending a signed transaction. Before every retry it asks
+// the checker whether the node has already accepted the transaction, as a
+// timed out send may have reached the node.
+type Broadcaster struct {
+	delegate transaction.Broadcaster
+	checker  transaction.KnownChecker
+	policy   Policy
+	breaker  *Breaker
+}
+
+var (
+	_ transaction.Broadcaster  = (*Broadcaster)(nil)
+	_ transaction.KnownChecker = (*Broadcaster)(nil)
+)
+
+func NewBroadcaster(
+	delegate transaction.Broadcaster,
+	checker transaction.KnownChecker,
+	policy Policy,
+	breaker *Breaker,
+) *Broadcaster {
+	return &Broadcaster{
+		delegate: delegate,
+		checker:  checker,
+		policy:   policy,
+		breaker:  breaker,
+	}
+}
+
+func (broadcaster *Broadcaster) Broadcast(ctx context.Context, payload *transaction.TransferPayload) error {
+	return Do(ctx, broadcaster.policy, broadcaster.breaker, func(ctx context.Context) error {
+		return broadcaster.delegate.Broadcast(ctx, payload)
+	}, func(ctx context.Context) bool {
+		known, err := broadcaster.checker.IsKnown(ctx, payload)
+		if err != nil {
+			slog.Log(ctx, slog.LevelWarn, "failed to check transaction before retrying broadcast:",
+				"id", payload.ID, "err", err)
+
+			return false
+		}
+
+		return known
+	})
+}
+
+// IsKnown keeps the check available to the transferor through the decorator.
+func (broadcaster *Broadcaster) IsKnown(ctx context.Context, payload *transaction.TransferPayload) (bool, error) {
+	return broadcaster.checker.IsKnown(ctx, payload)
+}
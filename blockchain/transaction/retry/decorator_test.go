@@ -0,0 +1,91 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+// flakyBroadcaster fails its first sends with a transient error.
+type flakyBroadcaster struct {
+	failures int
+	sends    int
+}
+
+func (broadcaster *flakyBroadcaster) Broadcast(context.Context, *transaction.TransferPayload) error {
+	broadcaster.sends++
+
+	if broadcaster.sends <= broadcaster.failures {
+		return blockchain.TransientNetworkError{Err: errors.New("i/o timeout")}
+	}
+
+	return nil
+}
+
+type stubChecker struct {
+	known  bool
+	err    error
+	checks int
+}
+
+func (checker *stubChecker) IsKnown(context.Context, *transaction.TransferPayload) (bool, error) {
+	checker.checks++
+
+	return checker.known, checker.err
+}
+
+func TestBroadcasterSkipsKnownTransaction(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		checker   *stubChecker
+		wantSends int
+	}{
+		// the timed out send reached the node, so sending it again is pointless
+		{name: "Known", checker: &stubChecker{known: true}, wantSends: 1},
+		{name: "Unknown", checker: &stubChecker{}, wantSends: 2},
+		// the send is repeated when the check cannot tell, as a node that
+		// has the transaction answers with AlreadyKnownError
+		{name: "CheckFailed", checker: &stubChecker{err: errors.New("i/o timeout")}, wantSends: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			delegate := &flakyBroadcaster{failures: 1}
+			policy := Policy{MaxAttempts: 3, InitialBackoff: time.Microsecond, MaxBackoff: time.Microsecond}
+			breaker, _ := newTestBreaker(DefaultFailureThreshold, DefaultCooldown)
+
+			broadcaster := NewBroadcaster(delegate, tc.checker, policy, breaker)
+
+			err := broadcaster.Broadcast(context.Background(), &transaction.TransferPayload{ID: "0x01"})
+			if err != nil {
+				t.Fatalf("Broadcast: %v", err)
+			}
+
+			if delegate.sends != tc.wantSends || tc.checker.checks != 1 {
+				t.Fatalf("Broadcast sent %d times after %d checks, want %d after 1",
+					delegate.sends, tc.checker.checks, tc.wantSends)
+			}
+		})
+	}
+}
+
+func TestBroadcasterChecksBeforeEveryRetry(t *testing.T) {
+	delegate := &flakyBroadcaster{failures: 5}
+	checker := &stubChecker{}
+	policy := Policy{MaxAttempts: 3, InitialBackoff: time.Microsecond, MaxBackoff: time.Microsecond}
+
+	broadcaster := NewBroadcaster(delegate, checker, policy, nil)
+
+	err := broadcaster.Broadcast(context.Background(), &transaction.TransferPayload{ID: "0x01"})
+
+	var transientErr blockchain.TransientNetworkError
+	if !errors.As(err, &transientErr) {
+		t.Fatalf("Broadcast returned %v, want TransientNetworkError", err)
+	}
+
+	if delegate.sends != 3 || checker.checks != 2 {
+		t.Fatalf("Broadcast sent %d times after %d checks, want 3 after 2", delegate.sends, checker.checks)
+	}
+}
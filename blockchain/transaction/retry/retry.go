@@ -0,0 +1,140 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+)
+
+const (
+	DefaultMaxAttempts    = 4
+	DefaultInitialBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+)
+
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// ShouldRetry decides whether a failed attempt is repeated; IsTransient is used when nil.
+	ShouldRetry func(error) bool
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:    DefaultMaxAttempts,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+	}
+}
+
+// IsTransient reports whether err is a transient network error. Other
+// retryable errors, such as a nonce that is too low, need the transaction to
+// be rebuilt and are left to the caller of the decorated component.
+func IsTransient(err error) bool {
+	var transientErr blockchain.TransientNetworkError
+
+	return errors.As(err, &transientErr)
+}
+
+// Do runs operation until it succeeds, fails with an error the policy does not
+// retry, or runs out of attempts. It never sleeps past the context deadline.
+// beforeRetry, when not nil, runs before every retry and stops the loop with
+// its result if it reports done.
+//
+// The breaker sees one outcome per call rather than per attempt: a success, or
+// a failure once the retries of a transient error are exhausted. An error that
+// is not transient says nothing about the health of the node and leaves the
+// breaker as it was.
+func Do(
+	ctx context.Context,
+	policy Policy,
+	breaker *Breaker,
+	operation func(ctx context.Context) error,
+	beforeRetry func(ctx context.Context) (done bool),
+) error {
+	shouldRetry := policy.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = IsTransient
+	}
+
+	if breaker != nil {
+		if err := breaker.Allow(); err != nil {
+			return err
+		}
+	}
+
+	err := attempt(ctx, policy, shouldRetry, operation, beforeRetry)
+
+	if breaker != nil {
+		switch {
+		case err == nil:
+			breaker.Success()
+		case shouldRetry(err):
+			breaker.Failure()
+		default:
+			breaker.Release()
+		}
+	}
+
+	return err
+}
+
+func attempt(
+	ctx context.Context,
+	policy Policy,
+	shouldRetry func(error) bool,
+	operation func(ctx context.Context) error,
+	beforeRetry func(ctx context.Context) (done bool),
+) error {
+	for attempt := 1; ; attempt++ {
+		err := operation(ctx)
+
+		if err == nil || !shouldRetry(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		if !sleep(ctx, policy.backoff(attempt)) {
+			return err
+		}
+
+		if beforeRetry != nil && beforeRetry(ctx) {
+			return nil
+		}
+	}
+}
+
+// backoff returns a duration with full jitter up to the exponential backoff of the attempt.
+func (policy Policy) backoff(attempt int) time.Duration {
+	ceiling := policy.InitialBackoff << (attempt - 1)
+	if ceiling <= 0 || ceiling > policy.MaxBackoff {
+		ceiling = policy.MaxBackoff
+	}
+
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return rand.N(ceiling) + 1
+}
+
+// sleep waits for the duration and reports false if the context is done first
+// or its deadline would pass while waiting.
+func sleep(ctx context.Context, duration time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < duration {
+		return false
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,70 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+)
+
+func TestBackoffBounds(t *testing.T) {
+	policy := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for _, tc := range []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{attempt: 1, ceiling: 100 * time.Millisecond},
+		{attempt: 2, ceiling: 200 * time.Millisecond},
+		{attempt: 4, ceiling: 800 * time.Millisecond},
+		{attempt: 5, ceiling: time.Second},
+		// the shift overflows long before this
+		{attempt: 80, ceiling: time.Second},
+	} {
+		var longest time.Duration
+
+		for range 1000 {
+			backoff := policy.backoff(tc.attempt)
+			if backoff <= 0 || backoff > tc.ceiling {
+				t.Fatalf("backoff(%d) returned %s, want (0, %s]", tc.attempt, backoff, tc.ceiling)
+			}
+
+			longest = max(longest, backoff)
+		}
+
+		// full jitter spreads the backoffs over the whole range
+		if longest <= tc.ceiling/2 {
+			t.Fatalf("backoff(%d) never exceeded %s in 1000 draws up to %s", tc.attempt, longest, tc.ceiling)
+		}
+	}
+
+	if backoff := (Policy{}).backoff(1); backoff != 0 {
+		t.Fatalf("backoff without durations returned %s, want 0", backoff)
+	}
+}
+
+func TestDoStopsBeforeDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	policy := Policy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+	transientErr := blockchain.TransientNetworkError{Err: errors.New("connection refused")}
+
+	attempts := 0
+	start := time.Now()
+
+	err := Do(ctx, policy, nil, func(context.Context) error {
+		attempts++
+
+		return transientErr
+	}, nil)
+	if !errors.Is(err, transientErr) || attempts != 1 {
+		t.Fatalf("Do returned %v after %d attempts, want the transient error after 1", err, attempts)
+	}
+
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("Do waited %s for a backoff past the deadline", elapsed)
+	}
+}
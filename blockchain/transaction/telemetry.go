@@ -0,0 +1,47 @@
+package transaction
+
+import (
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/telemetry"
+)
+
+const (
+	outcomeBroadcast = "broadcast"
+	outcomeFailed    = "failed"
+)
+
+var (
+	transfersTotal = telemetry.DefaultRegistry.NewCounter(
+		"transfers_total",
+		"Transfers handled by transferors, by outcome.",
+		"chain", "network", "currency", "outcome",
+	)
+	transferDuration = telemetry.DefaultRegistry.NewHistogram(
+		"transfer_duration_seconds",
+		"Time from building to broadcasting a transfer, by outcome.",
+		telemetry.DefaultDurationBuckets,
+		"chain", "network", "currency", "outcome",
+	)
+)
+
+// TransferAttributes describes the chain, network and currency of a transfer
+// for spans. Unknown currencies yield only the currency attribute.
+func TransferAttributes(param *TransferRequest) []telemetry.Attribute {
+	chain, network := transferLabels(param)
+
+	return []telemetry.Attribute{
+		telemetry.String("chain", chain),
+		telemetry.String("network", network),
+		telemetry.String("currency", param.NetworkCurrencyID),
+		telemetry.String("transfer.id", param.ID),
+	}
+}
+
+func transferLabels(param *TransferRequest) (string, string) {
+	networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+	if err != nil {
+		return "", ""
+	}
+
+	return networkCurrency.Network.Family, networkCurrency.Network.Code
+}
@@ -2,15 +2,30 @@ package transaction
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+	"github.com/ivxivx/demo-blockchain/telemetry"
 )
 
 type GenericTranferor struct {
 	Builder     Builder
 	Signer      Signer
 	Broadcaster Broadcaster
+	Notifier    Notifier
+	Outbox      Outbox
+	Auditor     Auditor
 }
 
-var _ Transferor = (*GenericTranferor)(nil)
+var (
+	_ Transferor        = (*GenericTranferor)(nil)
+	_ Quoter            = (*GenericTranferor)(nil)
+	_ OfflineTransferor = (*GenericTranferor)(nil)
+	_ MessageSigner     = (*GenericTranferor)(nil)
+	_ KnownChecker      = (*GenericTranferor)(nil)
+)
 
 func NewGenericTransferor(
 	builder Builder,
@@ -27,20 +42,232 @@ func NewGenericTransferor(
 func (creator *GenericTranferor) Transfer(
 	ctx context.Context,
 	param *TransferRequest,
+) (*TransferPayload, error) {
+	ctx, span := telemetry.Start(ctx, "transfer", TransferAttributes(param)...)
+	defer span.End()
+
+	start := time.Now()
+
+	payload, err := creator.transfer(ctx, param)
+
+	outcome := outcomeBroadcast
+	if err != nil {
+		outcome = outcomeFailed
+	}
+
+	if payload != nil {
+		span.SetAttributes(telemetry.String("transaction.hash", payload.ID))
+	}
+
+	span.RecordError(err)
+
+	chain, network := transferLabels(param)
+	transfersTotal.Inc(chain, network, param.NetworkCurrencyID, outcome)
+	transferDuration.Observe(time.Since(start).Seconds(), chain, network, param.NetworkCurrencyID, outcome)
+
+	return payload, err
+}
+
+func (creator *GenericTranferor) transfer(
+	ctx context.Context,
+	param *TransferRequest,
 ) (*TransferPayload, error) {
 	payload, err := creator.Builder.Build(ctx, param)
 	if err != nil {
+		auditOutcome(ctx, creator.Auditor, AuditFailed, param, nil, err)
+		creator.notify(ctx, EventFailed, &TransferPayload{Req: param}, err)
+
+		return nil, err
+	}
+
+	auditOutcome(ctx, creator.Auditor, AuditBuilt, param, payload, nil)
+	creator.notify(ctx, EventBuilt, payload, nil)
+
+	// every transaction is signed before the first is broadcast, so that a
+	// failing signer cannot leave a permit on chain without its transfer
+	for _, txPayload := range payload.Transactions() {
+		err = creator.Signer.Sign(ctx, txPayload)
+		if err != nil {
+			auditOutcome(ctx, creator.Auditor, AuditFailed, param, txPayload, err)
+			creator.notify(ctx, EventFailed, txPayload, err)
+
+			return nil, err
+		}
+	}
+
+	for _, preceding := range payload.Preceding {
+		if _, err := creator.release(ctx, preceding); err != nil {
+			return nil, err
+		}
+	}
+
+	return creator.release(ctx, payload)
+}
+
+// release records a signed payload and broadcasts it.
+func (creator *GenericTranferor) release(
+	ctx context.Context,
+	payload *TransferPayload,
+) (*TransferPayload, error) {
+	param := payload.Req
+
+	// a signed transaction can move funds, so it must be on record before
+	// it can leave this process
+	if err := audit(ctx, creator.Auditor, AuditSigned, param, payload, nil); err != nil {
+		creator.notify(ctx, EventFailed, payload, err)
 
 		return nil, err
 	}
 
-	err = creator.Signer.Sign(ctx, payload)
+	if creator.Outbox != nil {
+		entry := &OutboxEntry{Payload: payload, CreatedAt: time.Now().UTC()}
+
+		if err := creator.Outbox.Put(ctx, entry); err != nil {
+			creator.notify(ctx, EventFailed, payload, err)
+
+			return nil, err
+		}
+	}
+
+	err := creator.Broadcaster.Broadcast(ctx, payload)
+
+	// the node already holds this exact signed transaction, e.g. from an
+	// earlier attempt, so the broadcast has succeeded
+	var alreadyKnownErr blockchain.AlreadyKnownError
+	if errors.As(err, &alreadyKnownErr) {
+		err = nil
+	}
+
+	if err != nil {
+		creator.discard(ctx, payload, err)
+		auditOutcome(ctx, creator.Auditor, AuditFailed, param, payload, err)
+		creator.notify(ctx, EventFailed, payload, err)
+
+		return payload, err
+	}
+
+	auditOutcome(ctx, creator.Auditor, AuditBroadcast, param, payload, nil)
+	creator.notify(ctx, EventBroadcast, payload, nil)
+
+	return payload, nil
+}
+
+// discard removes a payload from the outbox once the node has rejected it for
+// good. Payloads that failed for a retryable reason stay for the Rebroadcaster.
+func (creator *GenericTranferor) discard(ctx context.Context, payload *TransferPayload, err error) {
+	if creator.Outbox == nil || blockchain.IsRetryable(err) {
+		return
+	}
+
+	if errR := creator.Outbox.Remove(ctx, payload.ID); errR != nil {
+		slog.Log(ctx, slog.LevelError, "failed to remove payload from outbox:", "id", payload.ID, "err", errR)
+	}
+}
+
+func (creator *GenericTranferor) notify(
+	ctx context.Context,
+	eventType EventType,
+	payload *TransferPayload,
+	err error,
+) {
+	if creator.Notifier == nil {
+		return
+	}
+
+	creator.Notifier.Notify(ctx, NewEvent(eventType, payload, err))
+}
+
+// Prepare builds the transaction of a transfer for signing elsewhere.
+func (creator *GenericTranferor) Prepare(ctx context.Context, param *TransferRequest) (*Bundle, error) {
+	describer, ok := creator.Builder.(Describer)
+	if !ok {
+		return nil, OfflineNotSupportedError{}
+	}
+
+	// a bundle carries a single transaction signed by the source
+	if mode := param.TransferMode(); mode.Relayed() {
+		return nil, TransferModeNotSupportedError{Mode: mode, Reason: "relayed transfers cannot be signed offline"}
+	}
+
+	payload, err := creator.Builder.Build(ctx, param)
+	if err != nil {
+		auditOutcome(ctx, creator.Auditor, AuditFailed, param, nil, err)
+		creator.notify(ctx, EventFailed, &TransferPayload{Req: param}, err)
+
+		return nil, err
+	}
+
+	auditOutcome(ctx, creator.Auditor, AuditBuilt, param, payload, nil)
+	creator.notify(ctx, EventBuilt, payload, nil)
+
+	description, err := describer.Describe(payload)
 	if err != nil {
 
 		return nil, err
 	}
 
-	err = creator.Broadcaster.Broadcast(ctx, payload)
+	return NewBundle(param, payload, description), nil
+}
+
+// Submit broadcasts a payload that was built by Prepare and signed elsewhere.
+func (creator *GenericTranferor) Submit(ctx context.Context, payload *TransferPayload) (*TransferPayload, error) {
+	ctx, span := telemetry.Start(ctx, "transfer.submit", TransferAttributes(payload.Req)...)
+	defer span.End()
+
+	verifier, ok := creator.Builder.(SignatureVerifier)
+	if !ok {
+		return nil, OfflineNotSupportedError{}
+	}
+
+	hash, err := verifier.VerifySigned(payload)
+	if err != nil {
+		span.RecordError(err)
+
+		return nil, err
+	}
+
+	payload.ID = hash
+
+	payload, err = creator.release(ctx, payload)
+	span.RecordError(err)
 
 	return payload, err
 }
+
+func (creator *GenericTranferor) Quote(ctx context.Context, param *TransferRequest) (*Quote, error) {
+	quoter, ok := creator.Builder.(Quoter)
+	if !ok {
+		return nil, QuoteNotSupportedError{}
+	}
+
+	return quoter.Quote(ctx, param)
+}
+
+// IsKnown reports whether the node has the signed transaction of the payload,
+// when the broadcaster can tell.
+func (creator *GenericTranferor) IsKnown(ctx context.Context, payload *TransferPayload) (bool, error) {
+	checker, ok := creator.Broadcaster.(KnownChecker)
+	if !ok {
+		return false, KnownCheckNotSupportedError{}
+	}
+
+	return checker.IsKnown(ctx, payload)
+}
+
+func (creator *GenericTranferor) SignPersonal(ctx context.Context, req *MessageRequest) (*MessageSignature, error) {
+	signer, ok := creator.Signer.(MessageSigner)
+	if !ok {
+		return nil, MessageSigningNotSupportedError{}
+	}
+
+	return signer.SignPersonal(ctx, req)
+}
+
+func (creator *GenericTranferor) SignTypedData(ctx context.Context, req *MessageRequest) (*MessageSignature, error) {
+	signer, ok := creator.Signer.(MessageSigner)
+	if !ok {
+		return nil, MessageSigningNotSupportedError{}
+	}
+
+	return signer.SignTypedData(ctx, req)
+}
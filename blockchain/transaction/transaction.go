@@ -2,15 +2,74 @@ package transaction
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/domain"
 )
 
+// TransferMode selects who sends and pays for the transaction of a transfer.
+type TransferMode string
+
+const (
+	// TransferModeDirect sends the transfer from the source address.
+	TransferModeDirect TransferMode = "direct"
+	// TransferModePermit has a relayer move tokens with transferFrom after
+	// the source approved it with an EIP-2612 permit.
+	TransferModePermit TransferMode = "permit"
+	// TransferModeAuthorization has a relayer submit an EIP-3009
+	// transferWithAuthorization signed by the source.
+	TransferModeAuthorization TransferMode = "authorization"
+)
+
+// Relayed reports whether a relayer rather than the source sends the
+// transaction and pays its fees.
+func (mode TransferMode) Relayed() bool {
+	return mode == TransferModePermit || mode == TransferModeAuthorization
+}
+
+type TransferModeNotSupportedError struct {
+	Mode   TransferMode
+	Reason string
+}
+
+func (e TransferModeNotSupportedError) Error() string {
+	return fmt.Sprintf("transfer mode %s not supported: %s", e.Mode, e.Reason)
+}
+
 type TransferRequest struct {
+	ID                 string
 	SourceAddress      string
 	DestinationAddress string
 	Amount             decimal.Decimal
 	NetworkCurrencyID  string
+	QuoteID            string
+	FeeTier            FeeTier
+	Fee                *PinnedFee
+	// Mode defaults to TransferModeDirect.
+	Mode TransferMode
+	// Call, if set, is made on the destination address, which receives the
+	// amount in the native currency.
+	Call *ContractCall
+	// Deploy, if set, creates a contract instead of transferring to the
+	// destination address, which must be empty.
+	Deploy *ContractDeployment
+	// NFT, if set, is transferred to the destination address instead of
+	// Amount, which must be zero.
+	NFT *NFTTransfer
+	// Principal is the caller that requested the transfer, nil for internal
+	// callers. It restricts the usable wallets and networks and is recorded
+	// for auditing.
+	Principal *domain.Principal
+}
+
+func (param *TransferRequest) TransferMode() TransferMode {
+	if param.Mode == "" {
+		return TransferModeDirect
+	}
+
+	return param.Mode
 }
 
 type TransferPayload struct {
@@ -18,8 +77,33 @@ type TransferPayload struct {
 	SourceWalletID string
 	ProviderID     string
 	ID             string
-	Raw            []byte
-	Signed         []byte
+	// Sender signs the transaction and pays its fees. It is the source
+	// address of the request unless the transfer is relayed.
+	Sender string
+	Raw    []byte
+	Signed []byte
+	// Preceding transactions must be broadcast, in order, before this one,
+	// e.g. the permit of a relayed transfer.
+	Preceding []*TransferPayload
+	// Call is the decoded contract call of the transaction, if any.
+	Call *DecodedCall
+	// ContractAddress is the contract a deployment creates. It is predicted
+	// when the transaction is built and replaced by the address in the
+	// receipt once the transaction is mined.
+	ContractAddress string
+}
+
+func (payload *TransferPayload) SenderAddress() string {
+	if payload.Sender != "" {
+		return payload.Sender
+	}
+
+	return payload.Req.SourceAddress
+}
+
+// Transactions returns the preceding transactions followed by the payload.
+func (payload *TransferPayload) Transactions() []*TransferPayload {
+	return append(append([]*TransferPayload{}, payload.Preceding...), payload)
 }
 
 type Builder interface {
@@ -1,9 +1,17 @@
 package transaction
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
 	"github.com/ivxivx/demo-blockchain/domain"
 )
 
@@ -20,6 +28,13 @@ type Manager struct {
 	walletRepo  domain.WalletRepo
 
 	transferorMap map[string]Transferor
+
+	quotes   *quoteStore
+	QuoteTTL time.Duration
+
+	bundles *bundleStore
+
+	Auditor Auditor
 }
 
 func NewManager(
@@ -31,44 +46,451 @@ func NewManager(
 		addressRepo:   addressRepo,
 		walletRepo:    walletRepo,
 		transferorMap: transferorMap,
+		quotes:        newQuoteStore(),
+		bundles:       newBundleStore(),
+		QuoteTTL:      DefaultQuoteTTL,
 	}
 }
 
 func (txmgr *Manager) Transfer(ctx context.Context, param *TransferRequest) (*TransferPayload, error) {
-	networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+	wallet, transferor, err := txmgr.authorize(ctx, param)
 	if err != nil {
 
 		return nil, err
 	}
 
-	if param.SourceAddress == "" {
-		return nil, fmt.Errorf("source address is not provided")
+	payload, err := transferor.Transfer(ctx, param)
+	if err != nil && txmgr.shouldRebuild(ctx, transferor, param, payload, err) {
+		payload, err = transferor.Transfer(ctx, param)
 	}
 
-	address, err := txmgr.addressRepo.GetAddressByValue(ctx, param.SourceAddress, networkCurrency.Network.Code)
 	if err != nil {
 
 		return nil, err
 	}
 
-	wallet, err := txmgr.walletRepo.GetWallet(ctx, address.WalletID)
+	payload.ProviderID = wallet.ProviderID
+
+	return payload, nil
+}
+
+// Call makes a contract call from a managed address. It is a transfer of the
+// call's value to the contract and passes the same checks and audit steps.
+func (txmgr *Manager) Call(ctx context.Context, req *ContractCallRequest) (*TransferPayload, error) {
+	param, err := req.TransferRequest()
 	if err != nil {
 
 		return nil, err
 	}
 
-	transferor, ok := txmgr.transferorMap[wallet.ProviderID]
+	payload, err := txmgr.Transfer(ctx, param)
+	req.ID = param.ID
+
+	return payload, err
+}
+
+// Deploy creates a contract from a managed address. Like Call, it is a
+// transfer of the constructor's value and passes the same checks and audit
+// steps.
+func (txmgr *Manager) Deploy(ctx context.Context, req *ContractDeployRequest) (*TransferPayload, error) {
+	param, err := req.TransferRequest()
+	if err != nil {
+
+		return nil, err
+	}
+
+	payload, err := txmgr.Transfer(ctx, param)
+	req.ID = param.ID
+
+	return payload, err
+}
+
+// TransferNFT transfers an NFT from a managed address, with the same checks
+// and audit steps as a transfer.
+func (txmgr *Manager) TransferNFT(ctx context.Context, req *NFTTransferRequest) (*TransferPayload, error) {
+	param, err := req.TransferRequest()
+	if err != nil {
+
+		return nil, err
+	}
+
+	payload, err := txmgr.Transfer(ctx, param)
+	req.ID = param.ID
+
+	return payload, err
+}
+
+// ExportTransfer builds a transfer for signing on an offline machine. The
+// returned bundle is kept until ImportTransfer receives it back with a
+// signature.
+func (txmgr *Manager) ExportTransfer(ctx context.Context, param *TransferRequest) (*Bundle, error) {
+	wallet, transferor, err := txmgr.authorize(ctx, param)
+	if err != nil {
+
+		return nil, err
+	}
+
+	offline, ok := transferor.(OfflineTransferor)
 	if !ok {
-		return nil, TransferorNotFoundError{ProviderID: wallet.ProviderID}
+		return nil, OfflineNotSupportedError{ProviderID: wallet.ProviderID}
 	}
 
-	payload, err := transferor.Transfer(ctx, param)
+	bundle, err := offline.Prepare(ctx, param)
 	if err != nil {
 
 		return nil, err
 	}
 
-	payload.ProviderID = wallet.ProviderID
+	bundle.ProviderID = wallet.ProviderID
+
+	if err := bundle.Seal(); err != nil {
+		return nil, err
+	}
+
+	txmgr.bundles.put(&exportedBundle{bundle: bundle, param: param})
+
+	return bundle, nil
+}
+
+// ImportTransfer broadcasts the signed transaction of a bundle returned by
+// ExportTransfer. Everything but the signature must be unchanged since the
+// export, and the caller must be the principal that exported it.
+func (txmgr *Manager) ImportTransfer(
+	ctx context.Context,
+	bundle *Bundle,
+	principal *domain.Principal,
+) (*TransferPayload, error) {
+	if err := bundle.VerifyChecksum(); err != nil {
+		return nil, err
+	}
+
+	exported, err := txmgr.bundles.get(bundle.TransferID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	switch {
+	case exported.bundle.Checksum != bundle.Checksum:
+		return nil, BundleMismatchError{TransferID: bundle.TransferID, Reason: "content changed since export"}
+	case principalID(exported.param.Principal) != principalID(principal):
+		return nil, BundleMismatchError{TransferID: bundle.TransferID, Reason: "exported by another principal"}
+	case len(bundle.Signed) == 0:
+		return nil, BundleMismatchError{TransferID: bundle.TransferID, Reason: "bundle is not signed"}
+	}
+
+	transferor, ok := txmgr.transferorMap[exported.bundle.ProviderID]
+	if !ok {
+		return nil, TransferorNotFoundError{ProviderID: exported.bundle.ProviderID}
+	}
+
+	offline, ok := transferor.(OfflineTransferor)
+	if !ok {
+		return nil, OfflineNotSupportedError{ProviderID: exported.bundle.ProviderID}
+	}
+
+	payload, err := offline.Submit(ctx, &TransferPayload{
+		Req:        exported.param,
+		ProviderID: exported.bundle.ProviderID,
+		Raw:        exported.bundle.Raw,
+		Signed:     bundle.Signed,
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	if bundle.TransactionHash != "" && bundle.TransactionHash != payload.ID {
+		slog.Log(ctx, slog.LevelWarn, "bundle reports another transaction hash:",
+			"id", payload.ID, "reported", bundle.TransactionHash)
+	}
+
+	txmgr.bundles.remove(bundle.TransferID)
+
+	payload.ProviderID = exported.bundle.ProviderID
 
 	return payload, nil
 }
+
+// SignPersonal signs a plain message with the key of req.Address.
+func (txmgr *Manager) SignPersonal(ctx context.Context, req *MessageRequest) (*MessageSignature, error) {
+	signer, err := txmgr.messageSigner(ctx, req)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return signer.SignPersonal(ctx, req)
+}
+
+// SignTypedData signs a structured message with the key of req.Address.
+func (txmgr *Manager) SignTypedData(ctx context.Context, req *MessageRequest) (*MessageSignature, error) {
+	signer, err := txmgr.messageSigner(ctx, req)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return signer.SignTypedData(ctx, req)
+}
+
+func (txmgr *Manager) messageSigner(ctx context.Context, req *MessageRequest) (MessageSigner, error) {
+	if req.ID == "" {
+		req.ID = uuid.Must(uuid.NewV7()).String()
+	}
+
+	if req.Address == "" {
+		return nil, fmt.Errorf("address is not provided")
+	}
+
+	wallet, transferor, err := txmgr.resolveAddress(ctx, req.Address, req.NetworkCode, req.Principal)
+	if err != nil {
+
+		return nil, err
+	}
+
+	signer, ok := transferor.(MessageSigner)
+	if !ok {
+		return nil, MessageSigningNotSupportedError{ProviderID: wallet.ProviderID}
+	}
+
+	return signer, nil
+}
+
+// Quote estimates the cost of a transfer without signing or broadcasting it.
+// The returned quote ID can be set on TransferRequest.QuoteID within QuoteTTL
+// to build the transfer with the quoted fees of TransferRequest.FeeTier.
+func (txmgr *Manager) Quote(ctx context.Context, param *TransferRequest) (*Quote, error) {
+	wallet, transferor, err := txmgr.resolve(ctx, param)
+	if err != nil {
+
+		return nil, err
+	}
+
+	quoter, ok := transferor.(Quoter)
+	if !ok {
+		return nil, QuoteNotSupportedError{ProviderID: wallet.ProviderID}
+	}
+
+	quote, err := quoter.Quote(ctx, param)
+	if err != nil {
+
+		return nil, err
+	}
+
+	quote.ID = uuid.Must(uuid.NewV7()).String()
+	quote.Req = param
+	quote.ProviderID = wallet.ProviderID
+	quote.ExpiresAt = time.Now().UTC().Add(txmgr.QuoteTTL)
+
+	txmgr.quotes.put(quote)
+
+	return quote, nil
+}
+
+// authorize resolves the transferor of a request and records the decision.
+func (txmgr *Manager) authorize(ctx context.Context, param *TransferRequest) (*domain.Wallet, Transferor, error) {
+	if param.ID == "" {
+		param.ID = uuid.Must(uuid.NewV7()).String()
+	}
+
+	if err := audit(ctx, txmgr.Auditor, AuditRequested, param, nil, nil); err != nil {
+		return nil, nil, err
+	}
+
+	wallet, transferor, err := txmgr.resolve(ctx, param)
+	if err == nil && param.QuoteID != "" {
+		err = txmgr.pinFee(param)
+	}
+
+	if err != nil {
+		auditOutcome(ctx, txmgr.Auditor, AuditRejected, param, nil, err)
+
+		return nil, nil, err
+	}
+
+	if err := audit(ctx, txmgr.Auditor, AuditAuthorized, param, nil, nil); err != nil {
+		return nil, nil, err
+	}
+
+	return wallet, transferor, nil
+}
+
+func (txmgr *Manager) resolve(ctx context.Context, param *TransferRequest) (*domain.Wallet, Transferor, error) {
+	networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	if param.SourceAddress == "" {
+		return nil, nil, fmt.Errorf("source address is not provided")
+	}
+
+	return txmgr.resolveAddress(ctx, param.SourceAddress, networkCurrency.Network.Code, param.Principal)
+}
+
+// resolveAddress finds the transferor of the wallet holding an address.
+func (txmgr *Manager) resolveAddress(
+	ctx context.Context,
+	value string,
+	networkCode string,
+	principal *domain.Principal,
+) (*domain.Wallet, Transferor, error) {
+	address, err := txmgr.addressRepo.GetAddressByValue(ctx, value, networkCode)
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	wallet, err := txmgr.walletRepo.GetWallet(ctx, address.WalletID)
+	if err != nil {
+
+		return nil, nil, err
+	}
+
+	if principal != nil {
+		if !principal.AllowsWallet(wallet.ID) {
+			return nil, nil, domain.PermissionDeniedError{
+				KeyID:  principal.KeyID,
+				Reason: "use wallet " + wallet.ID.String(),
+			}
+		}
+
+		if !principal.AllowsNetwork(networkCode) {
+			return nil, nil, domain.PermissionDeniedError{
+				KeyID:  principal.KeyID,
+				Reason: "use network " + networkCode,
+			}
+		}
+	}
+
+	transferor, ok := txmgr.transferorMap[wallet.ProviderID]
+	if !ok {
+		return nil, nil, TransferorNotFoundError{ProviderID: wallet.ProviderID}
+	}
+
+	return wallet, transferor, nil
+}
+
+// pinFee fixes the fee of the transfer to the quoted one. The quote is only
+// consumed once the transfer matches it, so a mistaken request does not void
+// the quote.
+func (txmgr *Manager) pinFee(param *TransferRequest) error {
+	quote, err := txmgr.quotes.get(param.QuoteID)
+	if err != nil {
+
+		return err
+	}
+
+	switch {
+	case quote.Req.SourceAddress != param.SourceAddress:
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "source address"}
+	case quote.Req.DestinationAddress != param.DestinationAddress:
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "destination address"}
+	case !quote.Req.Amount.Equal(param.Amount):
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "amount"}
+	case quote.Req.NetworkCurrencyID != param.NetworkCurrencyID:
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "currency"}
+	case principalID(quote.Req.Principal) != principalID(param.Principal):
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "principal"}
+	case quote.Req.TransferMode() != param.TransferMode():
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "mode"}
+	case !sameJSON(quote.Req.Call, param.Call):
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "contract call"}
+	case !sameJSON(quote.Req.Deploy, param.Deploy):
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "contract deployment"}
+	case !sameJSON(quote.Req.NFT, param.NFT):
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "nft"}
+	}
+
+	tier := param.FeeTier
+	if tier == "" {
+		tier = FeeTierStandard
+	}
+
+	option, ok := quote.FeeOption(tier)
+	if !ok {
+		return QuoteMismatchError{QuoteID: param.QuoteID, Field: "fee tier"}
+	}
+
+	if err := txmgr.quotes.take(param.QuoteID); err != nil {
+		return err
+	}
+
+	param.Fee = &PinnedFee{
+		GasLimit:             quote.GasLimit,
+		MaxFeePerGas:         option.MaxFeePerGas,
+		MaxPriorityFeePerGas: option.MaxPriorityFeePerGas,
+	}
+
+	return nil
+}
+
+// sameJSON reports whether a and b encode alike, which compares the calls,
+// deployments and NFTs of requests down to their arguments. The gas limit of
+// a quote holds only for the transaction it was estimated for.
+func sameJSON(a any, b any) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// shouldRebuild reports whether the transfer was rejected for a reason that a
+// freshly built transaction can avoid without spending twice.
+//
+// A nonce that is too low is usually taken by a concurrent transfer of the
+// same source, but it is also what a node answers for a transaction it has
+// already mined, e.g. when an earlier send timed out after reaching it. The
+// transfer is only rebuilt once the node confirms it has no record of the
+// rejected transaction. A fee cap below the base fee keeps the transaction out
+// of the mempool, so building it again is safe.
+func (txmgr *Manager) shouldRebuild(
+	ctx context.Context,
+	transferor Transferor,
+	param *TransferRequest,
+	payload *TransferPayload,
+	err error,
+) bool {
+	var nonceTooLowErr blockchain.NonceTooLowError
+	if errors.As(err, &nonceTooLowErr) {
+		return isUnknown(ctx, transferor, payload)
+	}
+
+	var feeCapTooLowErr blockchain.FeeCapTooLowError
+	if errors.As(err, &feeCapTooLowErr) {
+		// pinned fees would be rebuilt unchanged
+		return param.Fee == nil
+	}
+
+	return false
+}
+
+// isUnknown reports whether the node confirms that it has no record of the
+// signed transaction of the payload. It is false whenever that cannot be told,
+// e.g. when a preceding transaction failed and no payload is returned.
+func isUnknown(ctx context.Context, transferor Transferor, payload *TransferPayload) bool {
+	checker, ok := transferor.(KnownChecker)
+	if !ok || payload == nil {
+		return false
+	}
+
+	known, err := checker.IsKnown(ctx, payload)
+	if err != nil {
+		slog.Log(ctx, slog.LevelWarn, "failed to check transaction before rebuilding transfer:",
+			"id", payload.ID, "err", err)
+
+		return false
+	}
+
+	return !known
+}
+
+func principalID(principal *domain.Principal) uuid.UUID {
+	if principal == nil {
+		return uuid.Nil
+	}
+
+	return principal.KeyID
+}
@@ -0,0 +1,84 @@
+// Command audit verifies and exports the audit log of the demo.
+//
+//	go run ./cmd/audit [-file auditlog/audit.jsonl | -sqlite demo.db] verify|export
+//
+// verify exits with status 1 when the hash chain is broken; export writes the
+// log to stdout as JSON Lines.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ivxivx/demo-blockchain/audit"
+	"github.com/ivxivx/demo-blockchain/repo"
+	"github.com/ivxivx/demo-blockchain/repo/sqlite"
+)
+
+func main() {
+	if err := run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	file := flags.String("file", "auditlog/audit.jsonl", "audit log file of the in-memory storage")
+	sqlitePath := flags.String("sqlite", "", "SQLite database holding the audit log")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: audit [-file path | -sqlite path] verify|export")
+	}
+
+	store, err := openStore(ctx, *file, *sqlitePath)
+	if err != nil {
+
+		return err
+	}
+
+	switch flags.Arg(0) {
+	case "verify":
+		report, err := audit.Verify(ctx, store)
+		if report != nil {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+
+			if errE := encoder.Encode(report); errE != nil {
+				return errE
+			}
+		}
+
+		return err
+	case "export":
+		return audit.Export(ctx, store, os.Stdout)
+	}
+
+	return fmt.Errorf("unknown command: %s", flags.Arg(0))
+}
+
+func openStore(ctx context.Context, file string, sqlitePath string) (audit.Store, error) {
+	if sqlitePath == "" {
+		if _, err := os.Stat(file); err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+
+		return repo.NewFileAuditStore(file)
+	}
+
+	db, err := sqlite.Open(ctx, sqlitePath)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return sqlite.NewAuditStore(db), nil
+}
@@ -0,0 +1,234 @@
+// Command sign signs a transfer bundle exported by the demo on a machine
+// without network access.
+//
+//	go run ./cmd/sign [-key-file key.hex] [-yes] [-text] [-o signed.json] bundle.json
+//
+// The key is read from -key-file or the SIGN_PRIVATE_KEY environment
+// variable. The transaction is decoded from the bundle and shown for
+// confirmation before it is signed; the signed bundle is written to -o or
+// stdout and can be submitted to POST /api/v1/transfers/offline/submit.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const keyEnv = "SIGN_PRIVATE_KEY"
+
+func main() {
+	if err := run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("sign", flag.ContinueOnError)
+	keyFile := flags.String("key-file", "", "file holding the hex encoded private key, defaults to $"+keyEnv)
+	yes := flags.Bool("yes", false, "sign without asking for confirmation")
+	text := flags.Bool("text", false, "write the signed bundle in its single line text form")
+	output := flags.String("o", "", "file to write the signed bundle to, defaults to stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: sign [-key-file path] [-yes] [-text] [-o path] bundle|-")
+	}
+
+	bundle, err := readBundle(flags.Arg(0))
+	if err != nil {
+
+		return err
+	}
+
+	payload, err := unsignedPayload(bundle)
+	if err != nil {
+
+		return err
+	}
+
+	description, err := evm.Describe(payload)
+	if err != nil {
+
+		return err
+	}
+
+	// what is signed is the transaction, so its decoded form must agree with
+	// the summary the server exported
+	if description.ChainID != bundle.ChainID || !slices.Equal(description.Lines, bundle.Summary) {
+		return transaction.BundleMismatchError{
+			TransferID: bundle.TransferID,
+			Reason:     "summary does not match the transaction",
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Transfer %s\n", bundle.TransferID)
+
+	for _, line := range description.Lines {
+		fmt.Fprintln(os.Stderr, "  "+line)
+	}
+
+	if !*yes {
+		if flags.Arg(0) == "-" {
+			return errors.New("the bundle was read from stdin, confirm with -yes")
+		}
+
+		if !confirm(os.Stdin) {
+			return errors.New("signing cancelled")
+		}
+	}
+
+	privateKey, err := readKey(*keyFile)
+	if err != nil {
+
+		return err
+	}
+
+	if err := evm.NewPrvKeyTransactionSigner(privateKey).Sign(ctx, payload); err != nil {
+		return err
+	}
+
+	hash, err := evm.VerifySigned(payload)
+	if err != nil {
+
+		return err
+	}
+
+	bundle.Signed = payload.Signed
+	bundle.TransactionHash = hash
+
+	return writeBundle(bundle, *output, *text)
+}
+
+func readBundle(path string) (*transaction.Bundle, error) {
+	var (
+		content []byte
+		err     error
+	)
+
+	if path == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	bundle, err := transaction.ParseBundle(content)
+	if err != nil {
+
+		return nil, err
+	}
+
+	if err := bundle.VerifyChecksum(); err != nil {
+		return nil, err
+	}
+
+	if bundle.Chain != domain.FamilyEvm {
+		return nil, fmt.Errorf("unsupported chain: %s", bundle.Chain)
+	}
+
+	return bundle, nil
+}
+
+func unsignedPayload(bundle *transaction.Bundle) (*transaction.TransferPayload, error) {
+	amount, err := decimal.NewFromString(bundle.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount: %w", err)
+	}
+
+	return &transaction.TransferPayload{
+		Req: &transaction.TransferRequest{
+			ID:                 bundle.TransferID,
+			SourceAddress:      bundle.SourceAddress,
+			DestinationAddress: bundle.DestinationAddress,
+			Amount:             amount,
+			NetworkCurrencyID:  bundle.NetworkCurrencyID,
+		},
+		ProviderID: bundle.ProviderID,
+		Raw:        bundle.Raw,
+	}, nil
+}
+
+func confirm(input io.Reader) bool {
+	fmt.Fprint(os.Stderr, "Sign this transaction? [y/N] ")
+
+	answer, _ := bufio.NewReader(input).ReadString('\n')
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+
+	return false
+}
+
+func readKey(keyFile string) (string, error) {
+	if keyFile == "" {
+		privateKey := os.Getenv(keyEnv)
+		if privateKey == "" {
+			return "", fmt.Errorf("no private key, set -key-file or $%s", keyEnv)
+		}
+
+		return privateKey, nil
+	}
+
+	content, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+func writeBundle(bundle *transaction.Bundle, output string, text bool) error {
+	var content []byte
+
+	if text {
+		encoded, err := bundle.Text()
+		if err != nil {
+
+			return err
+		}
+
+		content = []byte(encoded + "\n")
+	} else {
+		encoded, err := json.MarshalIndent(bundle, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshall bundle: %w", err)
+		}
+
+		content = append(encoded, '\n')
+	}
+
+	if output == "" {
+		_, err := os.Stdout.Write(content)
+
+		return err
+	}
+
+	if err := os.WriteFile(output, content, 0o600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/sse"
+)
+
+type CreateAPIKeyRequest struct {
+	Name         string         `json:"name"`
+	Scopes       []domain.Scope `json:"scopes"`
+	WalletIDs    []uuid.UUID    `json:"wallet_ids,omitempty"`
+	NetworkCodes []string       `json:"network_codes,omitempty"`
+}
+
+// APIKeyResponse carries the token only on creation and rotation; it cannot
+// be retrieved afterwards.
+type APIKeyResponse struct {
+	*domain.APIKey
+	Token string `json:"token,omitempty"`
+}
+
+// require protects a handler with the given scope, unless authentication is
+// disabled in the config.
+func (demoContext *DemoContext) require(scope domain.Scope, handler http.HandlerFunc) http.HandlerFunc {
+	if demoContext.authenticator == nil {
+		return handler
+	}
+
+	return demoContext.authenticator.Require(scope, handler)
+}
+
+// requireUnrestricted is require for routes whose data spans every wallet and
+// network, such as webhook subscriptions and the audit log, which keys
+// restricted to some of them may not use.
+func (demoContext *DemoContext) requireUnrestricted(scope domain.Scope, handler http.HandlerFunc) http.HandlerFunc {
+	return demoContext.require(scope, func(resp http.ResponseWriter, req *http.Request) {
+		principal := auth.PrincipalFromContext(req.Context())
+		if principal != nil && principal.Restricted() {
+			writeError(resp, req, http.StatusForbidden, domain.PermissionDeniedError{
+				KeyID:  principal.KeyID,
+				Reason: "use a route spanning every wallet and network",
+			}.Error())
+
+			return
+		}
+
+		handler(resp, req)
+	})
+}
+
+func (demoContext *DemoContext) requireStream(scope domain.Scope, handler http.HandlerFunc) http.HandlerFunc {
+	if demoContext.authenticator == nil {
+		return handler
+	}
+
+	return demoContext.authenticator.RequireStream(scope, handler)
+}
+
+func createAPIKey(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var body CreateAPIKeyRequest
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		var fieldErrs FieldErrors
+
+		if body.Name == "" {
+			fieldErrs = append(fieldErrs, FieldError{Field: "name", Message: "is required"})
+		}
+
+		if len(body.Scopes) == 0 {
+			fieldErrs = append(fieldErrs, FieldError{Field: "scopes", Message: "is required"})
+		}
+
+		for _, scope := range body.Scopes {
+			if _, err := domain.ParseScope(string(scope)); err != nil {
+				fieldErrs = append(fieldErrs, FieldError{Field: "scopes", Message: err.Error()})
+			}
+		}
+
+		for _, networkCode := range body.NetworkCodes {
+			if _, err := domain.GetNetwork(networkCode); err != nil {
+				fieldErrs = append(fieldErrs, FieldError{Field: "network_codes", Message: err.Error()})
+			}
+		}
+
+		if len(fieldErrs) > 0 {
+			writeJSON(resp, req, http.StatusUnprocessableEntity, ErrorResponse{
+				Error:  "validation failed",
+				Fields: fieldErrs,
+			})
+
+			return
+		}
+
+		requested := &domain.Principal{
+			Scopes:       body.Scopes,
+			WalletIDs:    body.WalletIDs,
+			NetworkCodes: body.NetworkCodes,
+		}
+		if err := checkCovers(req, requested, "create"); err != nil {
+			writeRepoError(resp, req, "failed to create api key", err)
+
+			return
+		}
+
+		key, token, err := demoContext.apiKeys.Create(req.Context(), &domain.CreateAPIKeyPayload{
+			Name:         body.Name,
+			Scopes:       body.Scopes,
+			WalletIDs:    body.WalletIDs,
+			NetworkCodes: body.NetworkCodes,
+		})
+		if err != nil {
+			writeRepoError(resp, req, "failed to create api key", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusCreated, APIKeyResponse{APIKey: key, Token: token})
+	}
+}
+
+func getAPIKeys(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		keys, err := demoContext.apiKeys.List(req.Context())
+		if err != nil {
+			writeRepoError(resp, req, "failed to retrieve api keys", err)
+
+			return
+		}
+
+		// a restricted admin sees the keys it could have minted
+		if principal := auth.PrincipalFromContext(req.Context()); principal != nil {
+			keys = slices.DeleteFunc(keys, func(key *domain.APIKey) bool {
+				return !principal.Covers(key.Principal())
+			})
+		}
+
+		if keys == nil {
+			keys = []*domain.APIKey{}
+		}
+
+		writeJSON(resp, req, http.StatusOK, keys)
+	}
+}
+
+func rotateAPIKey(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		keyID, err := uuid.Parse(req.PathValue("id"))
+		if err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid api key id")
+
+			return
+		}
+
+		if err := demoContext.checkManages(req, keyID, "rotate"); err != nil {
+			writeRepoError(resp, req, "failed to rotate api key", err)
+
+			return
+		}
+
+		key, token, err := demoContext.apiKeys.Rotate(req.Context(), keyID)
+		if err != nil {
+			writeRepoError(resp, req, "failed to rotate api key", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusOK, APIKeyResponse{APIKey: key, Token: token})
+	}
+}
+
+func revokeAPIKey(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		keyID, err := uuid.Parse(req.PathValue("id"))
+		if err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid api key id")
+
+			return
+		}
+
+		if err := demoContext.checkManages(req, keyID, "revoke"); err != nil {
+			writeRepoError(resp, req, "failed to revoke api key", err)
+
+			return
+		}
+
+		key, err := demoContext.apiKeys.Revoke(req.Context(), keyID)
+		if err != nil {
+			writeRepoError(resp, req, "failed to revoke api key", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusOK, APIKeyResponse{APIKey: key})
+	}
+}
+
+// checkManages rejects managing a key that grants more than the caller holds.
+func (demoContext *DemoContext) checkManages(req *http.Request, keyID uuid.UUID, action string) error {
+	if auth.PrincipalFromContext(req.Context()) == nil {
+		return nil
+	}
+
+	key, err := demoContext.apiKeys.Get(req.Context(), keyID)
+	if err != nil {
+
+		return err
+	}
+
+	return checkCovers(req, key.Principal(), action)
+}
+
+// checkCovers rejects handing out a key with more scopes, wallets or networks
+// than the caller's own, so that a restricted admin cannot escape its
+// restrictions by minting or rotating a broader key.
+func checkCovers(req *http.Request, key *domain.Principal, action string) error {
+	principal := auth.PrincipalFromContext(req.Context())
+	if principal == nil || principal.Covers(key) {
+		return nil
+	}
+
+	return domain.PermissionDeniedError{
+		KeyID:  principal.KeyID,
+		Reason: action + " a key beyond its own scopes, wallets or networks",
+	}
+}
+
+// allowsWallet reports whether the caller may see the wallet. Requests
+// without a principal come from a server with authentication disabled.
+func allowsWallet(req *http.Request, walletID uuid.UUID) bool {
+	principal := auth.PrincipalFromContext(req.Context())
+
+	return principal == nil || principal.AllowsWallet(walletID)
+}
+
+func allowsNetwork(req *http.Request, networkCode string) bool {
+	principal := auth.PrincipalFromContext(req.Context())
+
+	return principal == nil || principal.AllowsNetwork(networkCode)
+}
+
+// permitsEvents returns the check of the SSE events of the wallets and
+// networks the caller may see, or nil when it may see every event.
+func permitsEvents(req *http.Request) func(event *sse.Event) bool {
+	principal := auth.PrincipalFromContext(req.Context())
+	if principal == nil || !principal.Restricted() {
+		return nil
+	}
+
+	return func(event *sse.Event) bool {
+		walletID, err := uuid.Parse(event.WalletID)
+
+		return err == nil && principal.AllowsWallet(walletID) && principal.AllowsNetwork(event.NetworkCode)
+	}
+}
+
+// allowsTransaction reports whether the caller may see a transaction: one
+// sent from or to an address of a wallet it may use.
+func (demoContext *DemoContext) allowsTransaction(req *http.Request, details *evm.TransactionDetails) bool {
+	principal := auth.PrincipalFromContext(req.Context())
+	if principal == nil || len(principal.WalletIDs) == 0 {
+		return true
+	}
+
+	parties := []string{details.From, details.To}
+	if details.TokenTransfer != nil {
+		parties = append(parties, details.TokenTransfer.To)
+	}
+
+	if details.NFTTransfer != nil {
+		parties = append(parties, details.NFTTransfer.From, details.NFTTransfer.To)
+	}
+
+	for _, party := range parties {
+		if party == "" {
+			continue
+		}
+
+		address, err := demoContext.addressRepo.GetAddressByValue(req.Context(), party, details.NetworkCode)
+		if err == nil && principal.AllowsAddress(address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// visibleAddresses drops the addresses the caller may not use.
+func visibleAddresses(req *http.Request, addresses []*domain.Address) []*domain.Address {
+	principal := auth.PrincipalFromContext(req.Context())
+	if principal == nil {
+		return addresses
+	}
+
+	return slices.DeleteFunc(slices.Clone(addresses), func(address *domain.Address) bool {
+		return !principal.AllowsAddress(address)
+	})
+}
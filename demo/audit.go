@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/ivxivx/demo-blockchain/audit"
+)
+
+type AuditVerification struct {
+	Valid bool `json:"valid"`
+	*audit.Report
+	Error string `json:"error,omitempty"`
+}
+
+func exportAudit(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("Content-Type", "application/x-ndjson")
+
+		// the status is sent with the first entry, so a failure can only be logged
+		if err := audit.Export(req.Context(), demoContext.auditStore, resp); err != nil {
+			slog.Log(req.Context(), slog.LevelError, "failed to export audit log:", "err", err)
+		}
+	}
+}
+
+func verifyAudit(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		report, err := audit.Verify(req.Context(), demoContext.auditStore)
+
+		var brokenErr audit.ChainBrokenError
+		if err != nil && !errors.As(err, &brokenErr) {
+			slog.Log(req.Context(), slog.LevelError, "failed to verify audit log:", "err", err)
+			writeError(resp, req, http.StatusInternalServerError, "failed to verify audit log")
+
+			return
+		}
+
+		verification := AuditVerification{Valid: err == nil, Report: report}
+		if err != nil {
+			verification.Error = err.Error()
+		}
+
+		writeJSON(resp, req, http.StatusOK, verification)
+	}
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ivxivx/demo-blockchain/audit"
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm/hsm"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/provider/local"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/retry"
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/repo"
+	"github.com/ivxivx/demo-blockchain/repo/sqlite"
+	"github.com/ivxivx/demo-blockchain/sse"
+	"github.com/ivxivx/demo-blockchain/webhook"
+)
+
+// storage bundles the repositories selected by StorageConfig.
+type storage struct {
+	walletRepo  domain.WalletRepo
+	addressRepo domain.AddressRepo
+	apiKeyRepo  domain.APIKeyRepo
+	webhookRepo domain.WebhookSubscriptionRepo
+	deadLetters domain.WebhookDeadLetterRepo
+	auditStore  audit.Store
+	newOutbox   func(providerID string, networkCode string) (transaction.Outbox, error)
+}
+
+// evmPipeline builds, signs and broadcasts the transfers of one network of a
+// provider.
+type evmPipeline struct {
+	builder     transaction.Builder
+	signer      transaction.Signer
+	broadcaster evmBroadcaster
+}
+
+// evmBroadcaster also follows what it broadcast, for retries and the
+// rebroadcaster.
+type evmBroadcaster interface {
+	transaction.Broadcaster
+	transaction.OutboxChecker
+	transaction.KnownChecker
+}
+
+func newDemoContext(ctx context.Context, config *DemoConfig) (*DemoContext, error) {
+	keyCipher, err := config.Auth.keyCipher()
+	if err != nil {
+		return nil, fmt.Errorf("auth: master_key: %w", err)
+	}
+
+	store, err := newStorage(ctx, config.Storage)
+	if err != nil {
+
+		return nil, err
+	}
+
+	apiKeys := auth.NewKeyService(store.apiKeyRepo, keyCipher)
+
+	if err := seed(ctx, config, store, apiKeys); err != nil {
+		return nil, err
+	}
+
+	dispatcher := webhook.NewDispatcher(store.webhookRepo, store.deadLetters, webhook.DefaultConfig())
+	hub := sse.NewHub(sse.DefaultHistorySize, sse.DefaultClientBuffer)
+
+	demoContext := &DemoContext{
+		walletRepo:  store.walletRepo,
+		addressRepo: store.addressRepo,
+		webhookRepo: store.webhookRepo,
+		dispatcher:  dispatcher,
+		hub:         hub,
+		notifier:    &eventNotifier{dispatcher: dispatcher, hub: hub},
+		clients:     evm.NewClientRegistry(),
+		providers:   make(map[string]*Provider),
+		generators:  make(map[string]domain.AddressGenerator),
+		apiKeys:     apiKeys,
+		auditStore:  store.auditStore,
+		auditLog:    audit.NewLog(store.auditStore),
+	}
+
+	// the registry only resolves clients, which the providers close
+	demoContext.closers = append(demoContext.closers, dispatcher.Close)
+
+	if !config.Auth.Disabled {
+		demoContext.authenticator = auth.NewAuthenticator(store.apiKeyRepo, keyCipher)
+	}
+
+	transferorMap := make(map[string]transaction.Transferor)
+
+	for _, provider := range config.Providers {
+		demoContext.providers[provider.ID] = provider
+
+		transferor, errP := newProvider(ctx, config, provider, store, demoContext)
+		if errP != nil {
+			// release what the providers before it opened
+			demoContext.Close()
+
+			return nil, errP
+		}
+
+		transferorMap[provider.ID] = transferor
+	}
+
+	demoContext.txmgr = transaction.NewManager(store.addressRepo, store.walletRepo, transferorMap)
+	demoContext.txmgr.Auditor = demoContext.auditLog
+
+	return demoContext, nil
+}
+
+// Close releases what the demo holds, in reverse order of acquisition.
+func (demoContext *DemoContext) Close() {
+	for index := len(demoContext.closers) - 1; index >= 0; index-- {
+		demoContext.closers[index]()
+	}
+
+	demoContext.closers = nil
+}
+
+// newProvider builds the transferor of a provider according to its type.
+func newProvider(
+	ctx context.Context,
+	config *DemoConfig,
+	provider *Provider,
+	store *storage,
+	demoContext *DemoContext,
+) (transaction.Transferor, error) {
+	switch provider.Type {
+	case ProviderTypeLocal:
+		return newLocalProvider(ctx, config, provider, store, demoContext)
+	case ProviderTypeSafe:
+		return newSafeProvider(ctx, config, provider, store, demoContext)
+	case ProviderTypeERC4337:
+		return newERC4337Provider(ctx, config, provider, store, demoContext)
+	case ProviderTypeHSM:
+		return newHSMProvider(ctx, config, provider, store, demoContext)
+	default:
+		return nil, fmt.Errorf("provider %s: unsupported type %q", provider.ID, provider.Type)
+	}
+}
+
+func newStorage(ctx context.Context, config StorageConfig) (*storage, error) {
+	if config.SQLitePath == "" {
+		auditStore, err := repo.NewFileAuditStore(config.AuditFile)
+		if err != nil {
+
+			return nil, err
+		}
+
+		walletRepo := repo.NewWalletRepo()
+
+		return &storage{
+			auditStore:  auditStore,
+			walletRepo:  walletRepo,
+			addressRepo: repo.NewAddressRepo(walletRepo),
+			apiKeyRepo:  repo.NewAPIKeyRepo(),
+			webhookRepo: repo.NewWebhookSubscriptionRepo(),
+			deadLetters: repo.NewWebhookDeadLetterRepo(),
+			newOutbox: func(providerID string, networkCode string) (transaction.Outbox, error) {
+				return repo.NewFileOutbox(filepath.Join(config.OutboxDir, providerID, networkCode))
+			},
+		}, nil
+	}
+
+	db, err := sqlite.Open(ctx, config.SQLitePath)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &storage{
+		walletRepo:  sqlite.NewWalletRepo(db),
+		addressRepo: sqlite.NewAddressRepo(db),
+		apiKeyRepo:  sqlite.NewAPIKeyRepo(db),
+		webhookRepo: sqlite.NewWebhookSubscriptionRepo(db),
+		deadLetters: sqlite.NewWebhookDeadLetterRepo(db),
+		auditStore:  sqlite.NewAuditStore(db),
+		newOutbox: func(providerID string, networkCode string) (transaction.Outbox, error) {
+			return sqlite.NewOutbox(db, providerID+"/"+networkCode), nil
+		},
+	}, nil
+}
+
+// seed creates the configured wallets and addresses, skipping the ones a
+// persistent storage already has from an earlier run.
+func seed(ctx context.Context, config *DemoConfig, store *storage, apiKeys *auth.KeyService) error {
+	for _, wallet := range config.Wallets {
+		if _, err := store.walletRepo.GetWallet(ctx, wallet.ID); err == nil {
+			continue
+		}
+
+		_, err := store.walletRepo.CreateWallet(ctx, &domain.CreateWalletPayload{
+			ID:         wallet.ID,
+			ProviderID: wallet.ProviderID,
+		})
+		if err != nil {
+
+			return err
+		}
+	}
+
+	for _, addr := range config.Addresses {
+		_, err := store.addressRepo.CreateAddress(ctx, &domain.CreateAddressPayload{
+			Address:     addr.Address,
+			NetworkCode: addr.NetworkCode,
+			WalletID:    addr.WalletID,
+		})
+
+		var existsErr domain.AddressAlreadyExistsError
+		if err != nil && !errors.As(err, &existsErr) {
+			return err
+		}
+	}
+
+	return seedAPIKeys(ctx, config.Auth.Keys, store.apiKeyRepo, apiKeys)
+}
+
+// seedAPIKeys provisions the configured keys. A key that already exists takes
+// the secret of the configured token, so that changing the token in the
+// config rotates the key. Keys stored without the signing key of the token,
+// encrypted with the current master key, get it as well.
+func seedAPIKeys(
+	ctx context.Context,
+	keys []*APIKeyConfig,
+	apiKeyRepo domain.APIKeyRepo,
+	apiKeys *auth.KeyService,
+) error {
+	for _, key := range keys {
+		keyID, secret, err := auth.ParseToken(key.Token)
+		if err != nil {
+
+			return err
+		}
+
+		existing, err := apiKeyRepo.GetAPIKey(ctx, keyID)
+
+		var notFoundErr domain.APIKeyNotFoundError
+
+		switch {
+		case errors.As(err, &notFoundErr):
+			_, err = apiKeyRepo.CreateAPIKey(ctx, &domain.CreateAPIKeyPayload{
+				ID:           keyID,
+				Name:         key.Name,
+				SecretHash:   auth.HashSecret(secret),
+				SigningKey:   apiKeys.SealSigningKey(keyID, secret),
+				Scopes:       key.Scopes,
+				WalletIDs:    key.WalletIDs,
+				NetworkCodes: key.NetworkCodes,
+			})
+		case err == nil && !apiKeys.HoldsSecret(existing, secret):
+			_, err = apiKeyRepo.RotateAPIKey(ctx, keyID, auth.HashSecret(secret), apiKeys.SealSigningKey(keyID, secret))
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to provision api key %s: %w", keyID, err)
+		}
+	}
+
+	return nil
+}
+
+// newLocalProvider builds a transferor for every network of a provider whose
+// keys are held in memory by this process. It generates addresses only when
+// it has a keystore_dir to keep the new keys in.
+func newLocalProvider(
+	ctx context.Context,
+	config *DemoConfig,
+	provider *Provider,
+	store *storage,
+	demoContext *DemoContext,
+) (transaction.Transferor, error) {
+	keyStore := evm.NewKeyStore()
+
+	if provider.KeystoreDir != "" {
+		var err error
+
+		keyStore, err = evm.OpenKeyStore(provider.KeystoreDir, provider.KeystorePassword)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", provider.ID, err)
+		}
+
+		demoContext.generators[provider.ID] = keyStore
+	}
+
+	if err := importKeys(config, provider, keyStore); err != nil {
+		return nil, err
+	}
+
+	signer := evm.NewKeyStoreTransactionSigner(keyStore)
+
+	return newEvmProvider(ctx, provider, store, demoContext,
+		func(_ context.Context, client *evm.Client, network *ProviderNetwork) (*evmPipeline, error) {
+			evmBuilder := evm.NewTransactionBuilder(client)
+			if network.RelayerAddress != "" {
+				evmBuilder.Relayer = network.RelayerAddress
+				evmBuilder.Authorizer = signer
+			}
+
+			return newTransactionPipeline(client, evmBuilder, signer), nil
+		})
+}
+
+// newSafeProvider builds a transferor for every network of a provider whose
+// wallets are Safe contracts. The keys of the provider's wallets sign as the
+// owners of the Safes and as the executor, and its addresses are Safes, so it
+// does not generate addresses.
+func newSafeProvider(
+	ctx context.Context,
+	config *DemoConfig,
+	provider *Provider,
+	store *storage,
+	demoContext *DemoContext,
+) (transaction.Transferor, error) {
+	keyStore := evm.NewKeyStore()
+
+	if err := importKeys(config, provider, keyStore); err != nil {
+		return nil, err
+	}
+
+	signer := evm.NewKeyStoreTransactionSigner(keyStore)
+
+	return newEvmProvider(ctx, provider, store, demoContext,
+		func(_ context.Context, client *evm.Client, network *ProviderNetwork) (*evmPipeline, error) {
+			safeBuilder := evm.NewSafeTransactionBuilder(client, network.ExecutorAddress, network.Owners, signer)
+
+			return newTransactionPipeline(client, safeBuilder, signer), nil
+		})
+}
+
+// newERC4337Provider builds a transferor for every network of a provider whose
+// wallets are ERC-4337 smart accounts. The keys of the provider's wallets sign
+// user operations as the owners of the accounts, and its addresses are
+// accounts, so it does not generate addresses.
+func newERC4337Provider(
+	ctx context.Context,
+	config *DemoConfig,
+	provider *Provider,
+	store *storage,
+	demoContext *DemoContext,
+) (transaction.Transferor, error) {
+	keyStore := evm.NewKeyStore()
+
+	if err := importKeys(config, provider, keyStore); err != nil {
+		return nil, err
+	}
+
+	signer := evm.NewKeyStoreTransactionSigner(keyStore)
+
+	return newEvmProvider(ctx, provider, store, demoContext,
+		func(ctx context.Context, client *evm.Client, network *ProviderNetwork) (*evmPipeline, error) {
+			bundler, err := evm.NewBundler(ctx, network.BundlerURL)
+			if err != nil {
+
+				return nil, err
+			}
+
+			demoContext.closers = append(demoContext.closers, bundler.Close)
+
+			entryPoint := network.EntryPoint
+			if entryPoint == "" {
+				entryPoint = evm.EntryPointV06
+			}
+
+			userOpBuilder := evm.NewUserOperationBuilder(client, bundler, entryPoint)
+			if network.PaymasterAddress != "" {
+				userOpBuilder.Paymaster = &evm.StaticPaymaster{
+					Address: common.HexToAddress(network.PaymasterAddress),
+					Params:  common.FromHex(network.PaymasterParams),
+				}
+			}
+
+			return &evmPipeline{
+				builder:     userOpBuilder,
+				signer:      evm.NewUserOperationSigner(signer),
+				broadcaster: evm.NewUserOperationBroadcaster(client, bundler),
+			}, nil
+		})
+}
+
+// newHSMProvider builds a transferor for every network of a provider whose
+// wallets' keys are held by a PKCS#11 token. It logs the address of each
+// wallet's key, and does not generate addresses.
+func newHSMProvider(
+	ctx context.Context,
+	config *DemoConfig,
+	provider *Provider,
+	store *storage,
+	demoContext *DemoContext,
+) (transaction.Transferor, error) {
+	hsmSigner, err := hsm.Open(hsm.Config{
+		ModulePath: provider.HSM.ModulePath,
+		TokenLabel: provider.HSM.TokenLabel,
+		PIN:        provider.HSM.PIN,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", provider.ID, err)
+	}
+
+	for _, wallet := range config.Wallets {
+		if wallet.ProviderID != provider.ID {
+			continue
+		}
+
+		address, err := hsmSigner.AddKey(wallet.HSMKeyLabel)
+		if err != nil {
+			hsmSigner.Close()
+
+			return nil, fmt.Errorf("failed to load key of wallet %s: %w", wallet.ID, err)
+		}
+
+		slog.Log(ctx, slog.LevelInfo, "loaded HSM key:", "wallet", wallet.ID, "address", address.Hex())
+	}
+
+	signer := evm.NewDigestTransactionSigner(hsmSigner)
+
+	transferor, err := newEvmProvider(ctx, provider, store, demoContext,
+		func(_ context.Context, client *evm.Client, _ *ProviderNetwork) (*evmPipeline, error) {
+			return newTransactionPipeline(client, evm.NewTransactionBuilder(client), signer), nil
+		})
+	if err != nil {
+		hsmSigner.Close()
+
+		return nil, err
+	}
+
+	// the session stays logged in until the demo shuts down
+	demoContext.closers = append(demoContext.closers, hsmSigner.Close)
+
+	return transferor, nil
+}
+
+// newTransactionPipeline signs the transactions of builder with signer and
+// broadcasts them to the node.
+func newTransactionPipeline(
+	client *evm.Client,
+	builder transaction.Builder,
+	signer transaction.Signer,
+) *evmPipeline {
+	return &evmPipeline{
+		builder:     builder,
+		signer:      signer,
+		broadcaster: evm.NewTransactionBroadcaster(client),
+	}
+}
+
+// importKeys adds the configured keys of the provider's wallets to the key store.
+func importKeys(config *DemoConfig, provider *Provider, keyStore *evm.KeyStore) error {
+	for _, wallet := range config.Wallets {
+		if wallet.ProviderID != provider.ID || wallet.PrivateKey == "" {
+			continue
+		}
+
+		if _, err := keyStore.Import(wallet.PrivateKey); err != nil {
+			return fmt.Errorf("failed to import key of wallet %s: %w", wallet.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// newEvmProvider connects every network of a provider and wraps the pipeline
+// returned by newPipeline with retries, an outbox and a rebroadcaster.
+func newEvmProvider(
+	ctx context.Context,
+	provider *Provider,
+	store *storage,
+	demoContext *DemoContext,
+	newPipeline func(context.Context, *evm.Client, *ProviderNetwork) (*evmPipeline, error),
+) (transaction.Transferor, error) {
+	delegates := make(map[string]transaction.Transferor, len(provider.Networks))
+
+	for networkCode, network := range provider.Networks {
+		client, err := evm.NewClient(ctx, network.NodeURL)
+		if err != nil {
+
+			return nil, err
+		}
+
+		// closed with the demo, also when a later step fails, as the caller
+		// then closes the demo
+		demoContext.closers = append(demoContext.closers, client.Close)
+
+		if _, err := demoContext.clients.Get(networkCode); err != nil {
+			demoContext.clients.Register(networkCode, client)
+		}
+
+		outbox, err := store.newOutbox(provider.ID, networkCode)
+		if err != nil {
+
+			return nil, err
+		}
+
+		policy := retry.DefaultPolicy()
+		breaker := retry.NewBreaker(provider.ID+"/"+networkCode, retry.DefaultFailureThreshold, retry.DefaultCooldown)
+
+		pipeline, err := newPipeline(ctx, client, network)
+		if err != nil {
+
+			return nil, err
+		}
+
+		evmBroadcaster := pipeline.broadcaster
+
+		// the breaker guards the node, so it wraps the node calls of building
+		// and broadcasting. The signer is retried without it, as its failures
+		// say nothing about the node.
+		builder := retry.NewBuilder(pipeline.builder, policy, breaker)
+		signer := retry.NewSigner(pipeline.signer, policy, nil)
+		broadcaster := retry.NewBroadcaster(evmBroadcaster, evmBroadcaster, policy, breaker)
+
+		transferor := transaction.NewGenericTransferor(builder, signer, broadcaster)
+		transferor.Notifier = demoContext.notifier
+		transferor.Outbox = outbox
+		transferor.Auditor = demoContext.auditLog
+
+		rebroadcaster := transaction.NewRebroadcaster(
+			outbox, broadcaster, evmBroadcaster, transaction.DefaultRebroadcastInterval,
+		)
+		rebroadcaster.Auditor = demoContext.auditLog
+		rebroadcaster.Notifier = demoContext.notifier
+		go rebroadcaster.Run(ctx)
+
+		delegates[networkCode] = transferor
+	}
+
+	return local.NewTransactionTranferor(delegates), nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+type ContractCallBody struct {
+	ID              string          `json:"id,omitempty"`
+	SourceAddress   string          `json:"source_address"`
+	NetworkCode     string          `json:"network_code"`
+	ContractAddress string          `json:"contract_address"`
+	ABI             json.RawMessage `json:"abi,omitempty"`
+	Method          string          `json:"method,omitempty"`
+	Args            []any           `json:"args,omitempty"`
+	// Data is hex encoded calldata, sent instead of method and args.
+	Data  string `json:"data,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+type ContractCallResponse struct {
+	ID         string                   `json:"id"`
+	Hash       string                   `json:"hash"`
+	State      string                   `json:"state"`
+	ProviderID string                   `json:"provider_id"`
+	Call       *transaction.DecodedCall `json:"call"`
+	URL        string                   `json:"url"`
+}
+
+func createContractCall(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var body ContractCallBody
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		param, fieldErrs := validateContractCall(&body)
+		if len(fieldErrs) > 0 {
+			writeJSON(resp, req, http.StatusUnprocessableEntity, ErrorResponse{
+				Error:  "validation failed",
+				Fields: fieldErrs,
+			})
+
+			return
+		}
+
+		param.Principal = auth.PrincipalFromContext(req.Context())
+
+		// a client that goes away must not abort the call halfway
+		ctx := context.WithoutCancel(req.Context())
+
+		payload, err := demoContext.txmgr.Call(ctx, param)
+		if err != nil {
+			writeTransferError(resp, req, "failed to call contract", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusAccepted, ContractCallResponse{
+			ID:         param.ID,
+			Hash:       payload.ID,
+			State:      string(transaction.EventBroadcast),
+			ProviderID: payload.ProviderID,
+			Call:       payload.Call,
+			URL:        "/transactions/" + param.NetworkCode + "/" + payload.ID,
+		})
+
+	}
+}
+
+func validateContractCall(body *ContractCallBody) (*transaction.ContractCallRequest, FieldErrors) {
+	var fieldErrs FieldErrors
+
+	network, err := domain.GetNetwork(body.NetworkCode)
+	if err != nil {
+		return nil, append(fieldErrs, FieldError{Field: "network_code", Message: "unknown network"})
+	}
+
+	nativeCurrency, err := domain.NewNetworkCurrency(network.NativeToken)
+	if err != nil {
+		return nil, append(fieldErrs, FieldError{Field: "network_code", Message: "has no native currency"})
+	}
+
+	sourceAddress, fieldErrs := validateAddress(fieldErrs, "source_address", nativeCurrency, body.SourceAddress)
+	contractAddress, fieldErrs := validateAddress(fieldErrs, "contract_address", nativeCurrency, body.ContractAddress)
+
+	value, fieldErrs := validateValue(fieldErrs, nativeCurrency, body.Value)
+
+	call := &transaction.ContractCall{
+		ABI:    body.ABI,
+		Method: body.Method,
+		Args:   body.Args,
+	}
+
+	switch {
+	case body.Data != "" && body.Method != "":
+		fieldErrs = append(fieldErrs, FieldError{Field: "data", Message: "cannot be combined with method"})
+	case body.Data != "":
+		call.Data, err = hexutil.Decode(body.Data)
+		if err != nil {
+			fieldErrs = append(fieldErrs, FieldError{Field: "data", Message: "is not a hex string"})
+		}
+	case body.Method == "":
+		fieldErrs = append(fieldErrs, FieldError{Field: "method", Message: "is required without data"})
+	case len(body.ABI) == 0:
+		fieldErrs = append(fieldErrs, FieldError{Field: "abi", Message: "is required with method"})
+	default:
+		if _, err := evm.EncodeCall(call); err != nil {
+			fieldErrs = append(fieldErrs, FieldError{Field: "args", Message: err.Error()})
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		return nil, fieldErrs
+	}
+
+	return &transaction.ContractCallRequest{
+		ID:              body.ID,
+		SourceAddress:   sourceAddress,
+		NetworkCode:     network.Code,
+		ContractAddress: contractAddress,
+		ABI:             call.ABI,
+		Method:          call.Method,
+		Args:            call.Args,
+		Data:            call.Data,
+		Value:           value,
+	}, nil
+}
+
+// validateValue parses the optional amount of the native currency sent along
+// with a contract call or deployment.
+func validateValue(
+	fieldErrs FieldErrors,
+	nativeCurrency *domain.NetworkCurrency,
+	text string,
+) (decimal.Decimal, FieldErrors) {
+	if text == "" {
+		return decimal.Zero, fieldErrs
+	}
+
+	value, err := decimal.NewFromString(strings.TrimSpace(text))
+
+	switch {
+	case err != nil:
+		fieldErrs = append(fieldErrs, FieldError{Field: "value", Message: "is not a decimal number"})
+	case value.IsNegative():
+		fieldErrs = append(fieldErrs, FieldError{Field: "value", Message: "must not be negative"})
+	case -value.Exponent() > int32(nativeCurrency.Scale):
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   "value",
+			Message: "has more than " + strconv.Itoa(nativeCurrency.Scale) + " decimal places",
+		})
+	}
+
+	return value, fieldErrs
+}
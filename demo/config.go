@@ -0,0 +1,511 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const (
+	ProviderTypeLocal   = "local"
+	ProviderTypeSafe    = "safe"
+	ProviderTypeERC4337 = "erc4337"
+	ProviderTypeHSM     = "hsm"
+
+	defaultConfigPath = "demo/config.json"
+	defaultListenAddr = ":9111"
+	defaultOutboxDir  = "outbox"
+	defaultAuditFile  = "auditlog/audit.jsonl"
+
+	envConfigPath = "DEMO_CONFIG"
+	envListenAddr = "DEMO_LISTEN_ADDR"
+	envOutboxDir  = "DEMO_OUTBOX_DIR"
+	envAuditFile  = "DEMO_AUDIT_FILE"
+	envSQLitePath = "DEMO_SQLITE_PATH"
+	// envNodeURLFormat overrides the node URL of a provider network, e.g.
+	// DEMO_LOCAL_TESTETH_NODE_URL for network TestEth of provider Local.
+	envNodeURLFormat = "DEMO_%s_%s_NODE_URL"
+)
+
+// placeholderPattern matches ${NAME} and ${NAME:-default}.
+var placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+type ProviderNotFoundError struct {
+	ProviderID string
+}
+
+func (e ProviderNotFoundError) Error() string {
+	return "provider not found for ID " + e.ProviderID
+}
+
+type ProviderNetwork struct {
+	NodeURL string `json:"node_url"`
+	// RelayerAddress sends and pays for relayed transfers. Its key must belong
+	// to a wallet of the provider.
+	RelayerAddress string `json:"relayer_address,omitempty"`
+	// ExecutorAddress submits the transactions of the Safes of a safe
+	// provider, and Owners sign them in order until a Safe's threshold is
+	// met. Their keys must belong to wallets of the provider.
+	ExecutorAddress string   `json:"executor_address,omitempty"`
+	Owners          []string `json:"owners,omitempty"`
+	// BundlerURL receives the user operations of an erc4337 provider, for
+	// EntryPoint, the v0.6 EntryPoint by default. PaymasterAddress sponsors
+	// their gas with PaymasterParams, hex encoded, as paymaster data.
+	BundlerURL       string `json:"bundler_url,omitempty"`
+	EntryPoint       string `json:"entry_point,omitempty"`
+	PaymasterAddress string `json:"paymaster_address,omitempty"`
+	PaymasterParams  string `json:"paymaster_params,omitempty"`
+}
+
+// HSMConfig selects the PKCS#11 token that holds the keys of an hsm provider.
+type HSMConfig struct {
+	ModulePath string `json:"module_path"`
+	TokenLabel string `json:"token_label"`
+	PIN        string `json:"pin"`
+}
+
+type Provider struct {
+	ID       string                      `json:"id"`
+	Type     string                      `json:"type"`
+	Params   map[string]string           `json:"params,omitempty"`
+	Networks map[string]*ProviderNetwork `json:"networks"`
+	HSM      *HSMConfig                  `json:"hsm,omitempty"`
+	// KeystoreDir is where a local provider keeps the keys it generates,
+	// encrypted with KeystorePassword. Without it the provider does not
+	// generate addresses.
+	KeystoreDir      string `json:"keystore_dir,omitempty"`
+	KeystorePassword string `json:"keystore_password,omitempty"`
+}
+
+type Wallet struct {
+	ID         uuid.UUID `json:"id"`
+	ProviderID string    `json:"provider_id"`
+	PrivateKey string    `json:"private_key"`
+	// HSMKeyLabel is the label of the wallet's key pair on the token of an
+	// hsm provider, used instead of PrivateKey.
+	HSMKeyLabel string `json:"hsm_key_label,omitempty"`
+}
+
+// StorageConfig selects where wallets, addresses, the outbox and the audit log
+// are kept. Without SQLitePath they live in memory and in files under
+// OutboxDir and AuditFile.
+type StorageConfig struct {
+	SQLitePath string `json:"sqlite_path,omitempty"`
+	OutboxDir  string `json:"outbox_dir,omitempty"`
+	AuditFile  string `json:"audit_file,omitempty"`
+}
+
+// APIKeyConfig provisions an API key with a token known in advance, so that
+// the first admin key exists before anyone can call the key endpoints.
+type APIKeyConfig struct {
+	Name         string         `json:"name"`
+	Token        string         `json:"token"`
+	Scopes       []domain.Scope `json:"scopes"`
+	WalletIDs    []uuid.UUID    `json:"wallet_ids,omitempty"`
+	NetworkCodes []string       `json:"network_codes,omitempty"`
+}
+
+type AuthConfig struct {
+	Disabled bool `json:"disabled,omitempty"`
+	// MasterKey is the hex encoded AES-256 key that encrypts the signing keys
+	// of API keys at rest, usually a ${...} placeholder.
+	MasterKey string          `json:"master_key,omitempty"`
+	Keys      []*APIKeyConfig `json:"keys,omitempty"`
+}
+
+type TelemetryConfig struct {
+	// LogSpans writes every finished span to the log.
+	LogSpans bool `json:"log_spans,omitempty"`
+}
+
+type DemoConfig struct {
+	ListenAddr string            `json:"listen_addr"`
+	Storage    StorageConfig     `json:"storage"`
+	Auth       AuthConfig        `json:"auth"`
+	Telemetry  TelemetryConfig   `json:"telemetry"`
+	Addresses  []*domain.Address `json:"addresses"`
+	Providers  []*Provider       `json:"providers"`
+	Wallets    []*Wallet         `json:"wallets"`
+}
+
+// configPath returns the config file given by the -config flag, the
+// DEMO_CONFIG environment variable or the default, in that order.
+func configPath(args []string) (string, error) {
+	flags := flag.NewFlagSet("demo", flag.ContinueOnError)
+	path := flags.String("config", "", "path to the config file (env "+envConfigPath+")")
+
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	if *path != "" {
+		return *path, nil
+	}
+
+	if env := os.Getenv(envConfigPath); env != "" {
+		return env, nil
+	}
+
+	return defaultConfigPath, nil
+}
+
+func loadConfig(path string) (*DemoConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config (%s): %w", path, err)
+	}
+
+	content, err = interpolate(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config (%s): %w", path, err)
+	}
+
+	config := DemoConfig{
+		ListenAddr: defaultListenAddr,
+		Storage: StorageConfig{
+			OutboxDir: defaultOutboxDir,
+			AuditFile: defaultAuditFile,
+		},
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config (%s): %w", path, err)
+	}
+
+	config.applyEnv()
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config (%s): %w", path, err)
+	}
+
+	return &config, nil
+}
+
+// interpolate replaces ${NAME} placeholders with environment variables so that
+// secrets such as private keys stay out of the file. A placeholder without a
+// default fails when the variable is unset.
+func interpolate(content []byte) ([]byte, error) {
+	var missing []string
+
+	result := placeholderPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		groups := placeholderPattern.FindSubmatch(match)
+		name := string(groups[1])
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			if !strings.Contains(string(match), ":-") {
+				missing = append(missing, name)
+
+				return match
+			}
+
+			value = string(groups[2])
+		}
+
+		// the value is embedded in a JSON string, so it must be escaped as one
+		quoted, _ := json.Marshal(value)
+
+		return quoted[1 : len(quoted)-1]
+	})
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+
+	return result, nil
+}
+
+func (config *DemoConfig) applyEnv() {
+	if value := os.Getenv(envListenAddr); value != "" {
+		config.ListenAddr = value
+	}
+
+	if value := os.Getenv(envOutboxDir); value != "" {
+		config.Storage.OutboxDir = value
+	}
+
+	if value := os.Getenv(envAuditFile); value != "" {
+		config.Storage.AuditFile = value
+	}
+
+	if value := os.Getenv(envSQLitePath); value != "" {
+		config.Storage.SQLitePath = value
+	}
+
+	for _, provider := range config.Providers {
+		for networkCode, network := range provider.Networks {
+			name := fmt.Sprintf(envNodeURLFormat, envName(provider.ID), envName(networkCode))
+
+			if value := os.Getenv(name); value != "" && network != nil {
+				network.NodeURL = value
+			}
+		}
+	}
+}
+
+// validate checks the config as a whole and reports every problem at once.
+func (config *DemoConfig) validate() error {
+	var errs []error
+
+	if config.ListenAddr == "" {
+		errs = append(errs, errors.New("listen_addr is required"))
+	}
+
+	providers := make(map[string]*Provider, len(config.Providers))
+
+	for index, provider := range config.Providers {
+		switch {
+		case provider.ID == "":
+			errs = append(errs, fmt.Errorf("providers[%d]: id is required", index))
+
+			continue
+		case providers[provider.ID] != nil:
+			errs = append(errs, fmt.Errorf("providers[%d]: duplicate id %s", index, provider.ID))
+
+			continue
+		}
+
+		providers[provider.ID] = provider
+
+		if provider.KeystoreDir != "" {
+			switch {
+			case provider.Type != ProviderTypeLocal:
+				errs = append(errs, fmt.Errorf("provider %s: keystore_dir requires type %s", provider.ID, ProviderTypeLocal))
+			case provider.KeystorePassword == "":
+				errs = append(errs, fmt.Errorf("provider %s: keystore_password is required with keystore_dir", provider.ID))
+			}
+		}
+
+		switch provider.Type {
+		case ProviderTypeLocal, ProviderTypeSafe, ProviderTypeERC4337:
+		case ProviderTypeHSM:
+			if provider.HSM == nil || provider.HSM.ModulePath == "" || provider.HSM.TokenLabel == "" {
+				errs = append(errs, fmt.Errorf("provider %s: hsm.module_path and hsm.token_label are required", provider.ID))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("provider %s: unsupported type %q", provider.ID, provider.Type))
+		}
+
+		for networkCode, network := range provider.Networks {
+			if _, err := domain.GetNetwork(networkCode); err != nil {
+				errs = append(errs, fmt.Errorf("provider %s: %w", provider.ID, err))
+			}
+
+			if network == nil || network.NodeURL == "" {
+				errs = append(errs, fmt.Errorf("provider %s: network %s: node_url is required", provider.ID, networkCode))
+			}
+
+			if network != nil && network.RelayerAddress != "" {
+				if _, err := domain.NormalizeAddress(networkCode, network.RelayerAddress); err != nil {
+					errs = append(errs, fmt.Errorf("provider %s: network %s: relayer_address: %w",
+						provider.ID, networkCode, err))
+				}
+			}
+
+			if network != nil && provider.Type == ProviderTypeSafe {
+				errs = append(errs, network.validateSafe(provider.ID, networkCode)...)
+			}
+
+			if network != nil && provider.Type == ProviderTypeERC4337 {
+				errs = append(errs, network.validateERC4337(provider.ID, networkCode)...)
+			}
+		}
+	}
+
+	wallets := make(map[uuid.UUID]*Wallet, len(config.Wallets))
+
+	for index, wallet := range config.Wallets {
+		if wallet.ID == uuid.Nil {
+			errs = append(errs, fmt.Errorf("wallets[%d]: id is required", index))
+
+			continue
+		}
+
+		if wallets[wallet.ID] != nil {
+			errs = append(errs, fmt.Errorf("wallets[%d]: duplicate id %s", index, wallet.ID))
+
+			continue
+		}
+
+		wallets[wallet.ID] = wallet
+
+		provider := providers[wallet.ProviderID]
+
+		switch {
+		case provider == nil:
+			errs = append(errs, fmt.Errorf("wallet %s: %w", wallet.ID, ProviderNotFoundError{ProviderID: wallet.ProviderID}))
+		case provider.Type == ProviderTypeHSM && (wallet.HSMKeyLabel == "" || wallet.PrivateKey != ""):
+			errs = append(errs, fmt.Errorf("wallet %s: wallets of hsm providers require hsm_key_label and no private_key",
+				wallet.ID))
+		case provider.Type != ProviderTypeHSM && wallet.HSMKeyLabel != "":
+			errs = append(errs, fmt.Errorf("wallet %s: hsm_key_label requires an hsm provider", wallet.ID))
+		}
+	}
+
+	for index, address := range config.Addresses {
+		wallet := wallets[address.WalletID]
+		if wallet == nil {
+			errs = append(errs, fmt.Errorf("addresses[%d]: %w", index, domain.WalletNotFoundError{WalletID: address.WalletID}))
+
+			continue
+		}
+
+		if _, err := domain.NormalizeAddress(address.NetworkCode, address.Address); err != nil {
+			errs = append(errs, fmt.Errorf("addresses[%d]: %w", index, err))
+		}
+
+		provider := providers[wallet.ProviderID]
+		if provider != nil && provider.Networks[address.NetworkCode] == nil {
+			errs = append(errs, fmt.Errorf("addresses[%d]: provider %s does not support network %s",
+				index, provider.ID, address.NetworkCode))
+		}
+	}
+
+	errs = append(errs, config.Auth.validate()...)
+
+	return errors.Join(errs...)
+}
+
+func (network *ProviderNetwork) validateSafe(providerID string, networkCode string) []error {
+	var errs []error
+
+	if _, err := domain.NormalizeAddress(networkCode, network.ExecutorAddress); err != nil {
+		errs = append(errs, fmt.Errorf("provider %s: network %s: executor_address: %w", providerID, networkCode, err))
+	}
+
+	if len(network.Owners) == 0 {
+		errs = append(errs, fmt.Errorf("provider %s: network %s: owners are required", providerID, networkCode))
+	}
+
+	for index, owner := range network.Owners {
+		if _, err := domain.NormalizeAddress(networkCode, owner); err != nil {
+			errs = append(errs, fmt.Errorf("provider %s: network %s: owners[%d]: %w", providerID, networkCode, index, err))
+		}
+	}
+
+	return errs
+}
+
+func (network *ProviderNetwork) validateERC4337(providerID string, networkCode string) []error {
+	var errs []error
+
+	if network.BundlerURL == "" {
+		errs = append(errs, fmt.Errorf("provider %s: network %s: bundler_url is required", providerID, networkCode))
+	}
+
+	addresses := []struct {
+		field   string
+		address string
+	}{
+		{"entry_point", network.EntryPoint},
+		{"paymaster_address", network.PaymasterAddress},
+	}
+
+	for _, address := range addresses {
+		if address.address == "" {
+			continue
+		}
+
+		if _, err := domain.NormalizeAddress(networkCode, address.address); err != nil {
+			errs = append(errs, fmt.Errorf("provider %s: network %s: %s: %w", providerID, networkCode, address.field, err))
+		}
+	}
+
+	if network.PaymasterParams != "" {
+		if _, err := hexutil.Decode(network.PaymasterParams); err != nil || network.PaymasterAddress == "" {
+			errs = append(errs, fmt.Errorf(
+				"provider %s: network %s: paymaster_params must be 0x-prefixed hex and requires paymaster_address",
+				providerID, networkCode))
+		}
+	}
+
+	return errs
+}
+
+func (config *AuthConfig) validate() []error {
+	var errs []error
+
+	if !config.Disabled && len(config.Keys) == 0 {
+		errs = append(errs, errors.New("auth: at least one key is required unless auth is disabled"))
+	}
+
+	if !config.Disabled || config.MasterKey != "" {
+		if _, err := config.keyCipher(); err != nil {
+			errs = append(errs, fmt.Errorf("auth: master_key: %w", err))
+		}
+	}
+
+	keyIDs := make(map[uuid.UUID]bool, len(config.Keys))
+
+	for index, key := range config.Keys {
+		keyID, _, err := auth.ParseToken(key.Token)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("auth.keys[%d]: token: %w", index, err))
+		} else if keyIDs[keyID] {
+			errs = append(errs, fmt.Errorf("auth.keys[%d]: duplicate key id %s", index, keyID))
+		}
+
+		keyIDs[keyID] = true
+
+		if len(key.Scopes) == 0 {
+			errs = append(errs, fmt.Errorf("auth.keys[%d]: scopes are required", index))
+		}
+
+		for _, scope := range key.Scopes {
+			if _, err := domain.ParseScope(string(scope)); err != nil {
+				errs = append(errs, fmt.Errorf("auth.keys[%d]: %w", index, err))
+			}
+		}
+
+		for _, networkCode := range key.NetworkCodes {
+			if _, err := domain.GetNetwork(networkCode); err != nil {
+				errs = append(errs, fmt.Errorf("auth.keys[%d]: %w", index, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// keyCipher returns the cipher of the signing keys, or nil without a master
+// key.
+func (config *AuthConfig) keyCipher() (*auth.KeyCipher, error) {
+	if config.MasterKey == "" {
+		if !config.Disabled {
+			return nil, errors.New("is required unless auth is disabled")
+		}
+
+		return nil, nil
+	}
+
+	masterKey, err := hex.DecodeString(strings.TrimPrefix(config.MasterKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("must be hex: %w", err)
+	}
+
+	return auth.NewKeyCipher(masterKey)
+}
+
+func envName(value string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+
+		return '_'
+	}, value))
+}
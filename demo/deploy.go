@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+type ContractDeployBody struct {
+	ID            string `json:"id,omitempty"`
+	SourceAddress string `json:"source_address"`
+	NetworkCode   string `json:"network_code"`
+	// Bytecode is the hex encoded creation code of the contract.
+	Bytecode string          `json:"bytecode"`
+	ABI      json.RawMessage `json:"abi,omitempty"`
+	Args     []any           `json:"args,omitempty"`
+	Value    string          `json:"value,omitempty"`
+}
+
+type ContractDeployResponse struct {
+	ID              string                   `json:"id"`
+	Hash            string                   `json:"hash"`
+	State           string                   `json:"state"`
+	ProviderID      string                   `json:"provider_id"`
+	ContractAddress string                   `json:"contract_address"`
+	Constructor     *transaction.DecodedCall `json:"constructor"`
+	URL             string                   `json:"url"`
+}
+
+func createContractDeployment(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var body ContractDeployBody
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		param, fieldErrs := validateContractDeploy(&body)
+		if len(fieldErrs) > 0 {
+			writeJSON(resp, req, http.StatusUnprocessableEntity, ErrorResponse{
+				Error:  "validation failed",
+				Fields: fieldErrs,
+			})
+
+			return
+		}
+
+		param.Principal = auth.PrincipalFromContext(req.Context())
+
+		// a client that goes away must not abort the deployment halfway
+		ctx := context.WithoutCancel(req.Context())
+
+		payload, err := demoContext.txmgr.Deploy(ctx, param)
+		if err != nil {
+			writeTransferError(resp, req, "failed to deploy contract", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusAccepted, ContractDeployResponse{
+			ID:              param.ID,
+			Hash:            payload.ID,
+			State:           string(transaction.EventBroadcast),
+			ProviderID:      payload.ProviderID,
+			ContractAddress: payload.ContractAddress,
+			Constructor:     payload.Call,
+			URL:             "/transactions/" + param.NetworkCode + "/" + payload.ID,
+		})
+
+	}
+}
+
+func validateContractDeploy(body *ContractDeployBody) (*transaction.ContractDeployRequest, FieldErrors) {
+	var fieldErrs FieldErrors
+
+	network, err := domain.GetNetwork(body.NetworkCode)
+	if err != nil {
+		return nil, append(fieldErrs, FieldError{Field: "network_code", Message: "unknown network"})
+	}
+
+	nativeCurrency, err := domain.NewNetworkCurrency(network.NativeToken)
+	if err != nil {
+		return nil, append(fieldErrs, FieldError{Field: "network_code", Message: "has no native currency"})
+	}
+
+	sourceAddress, fieldErrs := validateAddress(fieldErrs, "source_address", nativeCurrency, body.SourceAddress)
+	value, fieldErrs := validateValue(fieldErrs, nativeCurrency, body.Value)
+
+	deploy := &transaction.ContractDeployment{
+		ABI:  body.ABI,
+		Args: body.Args,
+	}
+
+	deploy.Bytecode, err = hexutil.Decode(body.Bytecode)
+
+	switch {
+	case body.Bytecode == "":
+		fieldErrs = append(fieldErrs, FieldError{Field: "bytecode", Message: "is required"})
+	case err != nil:
+		fieldErrs = append(fieldErrs, FieldError{Field: "bytecode", Message: "is not a hex string"})
+	default:
+		if _, err := evm.EncodeDeployment(deploy); err != nil {
+			fieldErrs = append(fieldErrs, FieldError{Field: "args", Message: err.Error()})
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		return nil, fieldErrs
+	}
+
+	return &transaction.ContractDeployRequest{
+		ID:            body.ID,
+		SourceAddress: sourceAddress,
+		NetworkCode:   network.Code,
+		Bytecode:      deploy.Bytecode,
+		ABI:           deploy.ABI,
+		Args:          deploy.Args,
+		Value:         value,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ivxivx/demo-blockchain/blockchain"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/retry"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+// transferErrorStatus maps errors returned by the transaction manager to HTTP status codes.
+func transferErrorStatus(err error) int {
+	var (
+		insufficientFundsErr   blockchain.InsufficientFundsError
+		intrinsicGasErr        blockchain.IntrinsicGasTooLowError
+		nonceTooLowErr         blockchain.NonceTooLowError
+		replacementErr         blockchain.ReplacementUnderpricedError
+		feeCapTooLowErr        blockchain.FeeCapTooLowError
+		transientErr           blockchain.TransientNetworkError
+		circuitOpenErr         retry.CircuitOpenError
+		networkNotSupportedErr *blockchain.NetworkNotSupportedError
+		addressNotFoundErr     domain.AddressNotFoundError
+		walletNotFoundErr      domain.WalletNotFoundError
+		transferorNotFoundErr  transaction.TransferorNotFoundError
+		quoteNotSupportedErr   transaction.QuoteNotSupportedError
+		quoteNotFoundErr       transaction.QuoteNotFoundError
+		quoteExpiredErr        transaction.QuoteExpiredError
+		quoteMismatchErr       transaction.QuoteMismatchError
+		permissionErr          domain.PermissionDeniedError
+		offlineNotSupportedErr transaction.OfflineNotSupportedError
+		messageUnsupportedErr  transaction.MessageSigningNotSupportedError
+		keyNotFoundErr         evm.KeyNotFoundError
+		bundleNotFoundErr      transaction.BundleNotFoundError
+		bundleMismatchErr      transaction.BundleMismatchError
+		modeNotSupportedErr    transaction.TransferModeNotSupportedError
+		nftNotOwnedErr         transaction.NFTNotOwnedError
+		nftNotApprovedErr      transaction.NFTNotApprovedError
+		safeThresholdErr       evm.SafeThresholdNotMetError
+	)
+
+	switch {
+	case errors.As(err, &insufficientFundsErr),
+		errors.As(err, &intrinsicGasErr),
+		errors.As(err, &quoteMismatchErr),
+		errors.As(err, &bundleMismatchErr),
+		errors.As(err, &modeNotSupportedErr),
+		errors.As(err, &nftNotOwnedErr),
+		errors.As(err, &nftNotApprovedErr),
+		errors.As(err, &safeThresholdErr):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &nonceTooLowErr),
+		errors.As(err, &replacementErr),
+		errors.As(err, &feeCapTooLowErr):
+		return http.StatusConflict
+	case errors.As(err, &permissionErr):
+		return http.StatusForbidden
+	case errors.As(err, &transientErr), errors.As(err, &circuitOpenErr):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &networkNotSupportedErr):
+		return http.StatusBadRequest
+	case errors.As(err, &addressNotFoundErr),
+		errors.As(err, &walletNotFoundErr),
+		errors.As(err, &quoteNotFoundErr),
+		errors.As(err, &bundleNotFoundErr),
+		errors.As(err, &keyNotFoundErr):
+		return http.StatusNotFound
+	case errors.As(err, &quoteExpiredErr):
+		return http.StatusGone
+	case errors.As(err, &transferorNotFoundErr),
+		errors.As(err, &quoteNotSupportedErr),
+		errors.As(err, &offlineNotSupportedErr),
+		errors.As(err, &messageUnsupportedErr):
+		return http.StatusNotImplemented
+	}
+
+	return http.StatusInternalServerError
+}
+
+// writeTransferError responds with the status of a transaction manager error.
+func writeTransferError(resp http.ResponseWriter, req *http.Request, message string, err error) {
+	status := transferErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		writeRepoError(resp, req, message, err)
+
+		return
+	}
+
+	writeError(resp, req, status, err.Error())
+}
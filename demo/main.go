@@ -4,30 +4,30 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/google/uuid"
-	"github.com/shopspring/decimal"
 
+	"github.com/ivxivx/demo-blockchain/audit"
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain"
 	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
 	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
-	"github.com/ivxivx/demo-blockchain/blockchain/transaction/provider/local"
 	"github.com/ivxivx/demo-blockchain/domain"
-	"github.com/ivxivx/demo-blockchain/repo"
-)
-
-const (
-	providerIDLocal = "Local"
-
-	walletIDEvmLocalTestnet = "018ee4c9-5161-7fa2-b280-20573311aab4"
-
-	paramEvmLocalTestnetURL = "evm_local_testnet_url"
+	"github.com/ivxivx/demo-blockchain/sse"
+	"github.com/ivxivx/demo-blockchain/telemetry"
+	"github.com/ivxivx/demo-blockchain/webhook"
 )
 
 type Currency struct {
@@ -43,17 +43,6 @@ type Address struct {
 	ProviderID  string    `json:"provider_id"`
 }
 
-type Provider struct {
-	ID     string            `json:"id"`
-	Params map[string]string `json:"params"`
-}
-
-type Wallet struct {
-	ID         uuid.UUID `json:"id"`
-	ProviderID string    `json:"provider_id"`
-	PrivateKey string    `json:"private_key"`
-}
-
 type Network struct {
 	Currencies []*Currency `json:"currencies"`
 	Addresses  []*Address  `json:"addresses"`
@@ -73,16 +62,29 @@ type Transaction struct {
 	ProviderID            string `json:"provider_id"`
 }
 
-type DemoConfig struct {
-	Addresses []*domain.Address `json:"addresses"`
-	Providers []*Provider       `json:"providers"`
-	Wallets   []*Wallet         `json:"wallets"`
-}
-
 type DemoContext struct {
 	txmgr       *transaction.Manager
 	walletRepo  domain.WalletRepo
 	addressRepo domain.AddressRepo
+	webhookRepo domain.WebhookSubscriptionRepo
+	dispatcher  *webhook.Dispatcher
+	hub         *sse.Hub
+	notifier    transaction.Notifier
+	clients     *evm.ClientRegistry
+	providers   map[string]*Provider
+	// generators holds the providers that can generate addresses.
+	generators map[string]domain.AddressGenerator
+
+	auditStore audit.Store
+	auditLog   *audit.Log
+
+	apiKeys *auth.KeyService
+	// authenticator is nil when authentication is disabled.
+	authenticator *auth.Authenticator
+
+	// closers release what the demo holds, e.g. the sessions of HSM
+	// providers, on shutdown.
+	closers []func()
 }
 
 type TransactionUpdatedMessage struct {
@@ -90,80 +92,121 @@ type TransactionUpdatedMessage struct {
 	NetworkTransactionID string `json:"network_transaction_id,omitempty"`
 }
 
-type ProviderNotFoundError struct {
-	ProviderID string
-}
-
-func (e ProviderNotFoundError) Error() string {
-	return "provider not found for ID " + e.ProviderID
-}
-
 //go:embed index.html
 var indexHtml embed.FS
 
-//go:embed config.json
-var configFile embed.FS
-
-var testEthClient *evm.Client
-
 func main() {
-	ctx := context.Background()
+	// the rebroadcasters run until the demo is told to stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	contentFS, err := fs.Sub(indexHtml, ".")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	config, err := loadConfig()
+	path, err := configPath(os.Args[1:])
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	demoContext, err := newTransferors(ctx, config)
+	config, err := loadConfig(path)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	chSse := make(chan json.RawMessage)
+	demoContext, err := newDemoContext(ctx, config)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	http.Handle("/", http.FileServer(http.FS(contentFS)))
+	if config.Telemetry.LogSpans {
+		telemetry.SetExporter(&telemetry.LogExporter{Level: slog.LevelInfo})
+	}
 
-	http.HandleFunc("GET /demo/networks", getNetwork(config))
-	http.HandleFunc("POST /demo/payouts", createPayout(config, demoContext, chSse))
-	http.HandleFunc("GET /demo/transactions", getTransaction)
-	http.HandleFunc("/demo/sse", handleEvents(chSse))
+	http.Handle("/", http.FileServer(http.FS(contentFS)))
 
-	const readerHeaderTimeout = 5 * time.Second
+	read, transfer, admin := domain.ScopeRead, domain.ScopeTransfer, domain.ScopeAdmin
+
+	handle("GET /demo/networks", demoContext.require(read, getNetwork(demoContext)))
+	handle("POST /demo/payouts", demoContext.require(transfer, createPayout(config, demoContext)))
+	handle("POST /demo/quotes", demoContext.require(transfer, createQuote(demoContext)))
+	handle("POST /api/v1/transfers", demoContext.require(transfer, createTransfer(demoContext)))
+	handle("POST /api/v1/calls", demoContext.require(transfer, createContractCall(demoContext)))
+	handle("POST /api/v1/deployments", demoContext.require(transfer, createContractDeployment(demoContext)))
+	handle("POST /api/v1/nft-transfers", demoContext.require(transfer, createNFTTransfer(demoContext)))
+	handle("POST /api/v1/transfers/offline", demoContext.require(transfer, exportTransfer(demoContext)))
+	handle("POST /api/v1/transfers/offline/submit", demoContext.require(transfer, submitTransfer(demoContext)))
+	handle("POST /api/v1/messages/personal", demoContext.require(transfer, signMessage(demoContext, personalMessage)))
+	handle("POST /api/v1/messages/typed-data",
+		demoContext.require(transfer, signMessage(demoContext, typedDataMessage)))
+	handle("POST /api/v1/messages/verify", demoContext.require(read, verifyMessage()))
+	handle("GET /transactions/{network}/{hash}", demoContext.require(read, getTransaction(demoContext)))
+	handle("POST /wallets", demoContext.require(admin, createWallet(demoContext)))
+	handle("GET /wallets", demoContext.require(read, getWallets(demoContext)))
+	handle("GET /wallets/{id}", demoContext.require(read, getWalletByID(demoContext)))
+	handle("POST /wallets/{id}/addresses", demoContext.require(admin, createAddress(demoContext)))
+	handle("GET /wallets/{id}/addresses", demoContext.require(read, getWalletAddresses(demoContext)))
+	handle("GET /addresses", demoContext.require(read, listAddresses(demoContext)))
+	handle("GET /demo/sse", demoContext.requireStream(read, demoContext.hub.Handler(sse.DefaultKeepAlive, permitsEvents)))
+	handle("POST /demo/webhooks", demoContext.requireUnrestricted(admin, createWebhook(demoContext)))
+	handle("GET /demo/webhooks", demoContext.requireUnrestricted(admin, getWebhooks(demoContext)))
+	handle("GET /demo/webhooks/dead-letters", demoContext.requireUnrestricted(admin, getWebhookDeadLetters(demoContext)))
+	handle("POST /demo/webhooks/dead-letters/{id}/replay",
+		demoContext.requireUnrestricted(admin, replayWebhookDelivery(demoContext)))
+	handle("POST /api/v1/keys", demoContext.require(admin, createAPIKey(demoContext)))
+	handle("GET /api/v1/keys", demoContext.require(admin, getAPIKeys(demoContext)))
+	handle("POST /api/v1/keys/{id}/rotate", demoContext.require(admin, rotateAPIKey(demoContext)))
+	handle("POST /api/v1/keys/{id}/revoke", demoContext.require(admin, revokeAPIKey(demoContext)))
+	handle("GET /api/v1/audit", demoContext.requireUnrestricted(admin, exportAudit(demoContext)))
+	handle("GET /api/v1/audit/verify", demoContext.requireUnrestricted(admin, verifyAudit(demoContext)))
+	http.HandleFunc("GET /metrics", demoContext.require(read, telemetry.DefaultRegistry.Handler()))
+
+	const (
+		readerHeaderTimeout = 5 * time.Second
+		shutdownTimeout     = 10 * time.Second
+	)
 
 	server := &http.Server{
-		Addr:              ":9111",
+		Addr:              config.ListenAddr,
 		ReadHeaderTimeout: readerHeaderTimeout,
 	}
 
-	slog.Log(ctx, slog.LevelInfo, "Listening on :9111...")
+	slog.Log(ctx, slog.LevelInfo, "Listening on "+config.ListenAddr+"...")
+
+	served := make(chan error, 1)
+
+	go func() {
+		served <- server.ListenAndServe()
+	}()
 
-	if err := server.ListenAndServe(); err != nil {
+	select {
+	case err := <-served:
+		demoContext.Close()
 		log.Fatal(err)
+	case <-ctx.Done():
 	}
-}
 
-func loadConfig() (*DemoConfig, error) {
-	content, err := configFile.ReadFile("config.json")
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config: %w", err)
-	}
+	slog.Log(context.Background(), slog.LevelInfo, "Shutting down...")
 
-	var config DemoConfig
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 
-	err = json.Unmarshal(content, &config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// event streams stay open until their clients leave
+		server.Close()
 	}
 
-	return &config, nil
+	demoContext.Close()
 }
 
-func getNetwork(config *DemoConfig) http.HandlerFunc {
+// handle registers a route whose requests are traced and measured under the
+// route pattern.
+func handle(pattern string, handler http.HandlerFunc) {
+	http.HandleFunc(pattern, telemetry.Handler(pattern, handler))
+}
+
+func getNetwork(demoContext *DemoContext) http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
 		networkCode := req.FormValue("network")
 
@@ -184,7 +227,14 @@ func getNetwork(config *DemoConfig) http.HandlerFunc {
 			}
 		}
 
-		addrs := getAddresses(config, networkCode)
+		addrs, err := demoContext.addressRepo.GetAddressesByNetwork(req.Context(), networkCode)
+		if err != nil {
+			slog.Log(req.Context(), slog.LevelError, "failed to retrieve addresses:", "err", err)
+			http.Error(resp, "failed to retrieve addresses", http.StatusInternalServerError)
+
+			return
+		}
+
 		if len(addrs) == 0 {
 			slog.Log(req.Context(), slog.LevelError, "failed to get provider address:",
 				slog.Any("NetworkCode", networkCode))
@@ -193,10 +243,12 @@ func getNetwork(config *DemoConfig) http.HandlerFunc {
 			return
 		}
 
+		addrs = visibleAddresses(req, addrs)
+
 		addresses := make([]*Address, len(addrs))
 
 		for index, addr := range addrs {
-			wallet, errW := getWallet(config, addr.WalletID)
+			wallet, errW := demoContext.walletRepo.GetWallet(req.Context(), addr.WalletID)
 			if errW != nil {
 				slog.Log(req.Context(), slog.LevelError, "failed to retrieve wallet:", errW)
 				http.Error(resp, "failed to retrieve wallet", http.StatusInternalServerError)
@@ -237,141 +289,112 @@ func getNetwork(config *DemoConfig) http.HandlerFunc {
 	}
 }
 
-func getTransaction(resp http.ResponseWriter, req *http.Request) {
-	ctx := context.Background()
-
-	transactionID := req.FormValue("tid")
-
-	txn, _, err := testEthClient.Delegate.TransactionByHash(ctx, common.HexToHash(transactionID))
-	if err != nil {
-		slog.Log(req.Context(), slog.LevelError, "failed to retrieve transaction:", err)
-		http.Error(resp, "failed to retrieve transaction", http.StatusInternalServerError)
-
-		return
-	}
-
-	txRes := map[string]interface{}{
-		"id":        transactionID,
-		"chain_id":  txn.ChainId(),
-		"time":      txn.Time(),
-		"gas":       txn.Gas(),
-		"gas_price": txn.GasPrice(),
-		"nonce":     txn.Nonce(),
-	}
+func getTransaction(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		networkCode := req.PathValue("network")
+		hash := req.PathValue("hash")
 
-	res, err := json.MarshalIndent(txRes, "", "  ")
-	if err != nil {
-		slog.Log(req.Context(), slog.LevelError, "failed to marshall response:", err)
-		http.Error(resp, "failed to marshall response", http.StatusInternalServerError)
+		if !isTransactionHash(hash) {
+			http.Error(resp, "invalid transaction hash", http.StatusBadRequest)
 
-		return
-	}
+			return
+		}
 
-	resp.Header().Set("Content-Type", "application/json")
+		if !allowsNetwork(req, networkCode) {
+			writeError(resp, req, http.StatusForbidden, "api key is not permitted to use this network")
 
-	if _, err := resp.Write(res); err != nil {
-		slog.Log(req.Context(), slog.LevelError, "failed to writeg response:", err)
-		http.Error(resp, "failed to writeg response", http.StatusInternalServerError)
+			return
+		}
 
-		return
-	}
-}
+		client, err := demoContext.clients.Get(networkCode)
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusNotFound)
 
-func handleEvents(chSse chan json.RawMessage) http.HandlerFunc {
-	return func(resp http.ResponseWriter, req *http.Request) {
-		ctx := req.Context()
+			return
+		}
 
-		resp.Header().Set("Access-Control-Allow-Origin", "*")
-		resp.Header().Set("Content-Type", "text/event-stream")
-		resp.Header().Set("Cache-Control", "no-cache")
-		resp.Header().Set("Connection", "keep-alive")
+		details, err := client.GetTransaction(req.Context(), networkCode, hash)
+		if err != nil {
+			var notFoundErr evm.TransactionNotFoundError
+			if errors.As(err, &notFoundErr) {
+				http.Error(resp, err.Error(), http.StatusNotFound)
 
-		for {
-			select {
-			case <-ctx.Done():
 				return
-			case msg, rok := <-chSse:
-				if !rok {
-					log.Println("channel closed")
-
-					return
-				}
+			}
 
-				_, errC := fmt.Fprintf(resp, "data: %s\n\n", msg)
-				if errC != nil {
-					slog.Log(ctx, slog.LevelError, "error writing response:", "err", errC)
-					http.Error(resp, "error writing response", http.StatusInternalServerError)
+			slog.Log(req.Context(), slog.LevelError, "failed to retrieve transaction:", err)
+			http.Error(resp, "failed to retrieve transaction", http.StatusInternalServerError)
 
-					continue
-				}
+			return
+		}
 
-				if flusher, ok := resp.(http.Flusher); ok {
-					flusher.Flush()
-				}
+		if !demoContext.allowsTransaction(req, details) {
+			writeError(resp, req, http.StatusForbidden, "api key is not permitted to see this transaction")
 
-				log.Printf("sent sse: %s", msg)
-			}
+			return
 		}
+
+		writeJSON(resp, req, http.StatusOK, details)
 	}
 }
 
+func isTransactionHash(value string) bool {
+	bytes, err := hexutil.Decode(value)
+
+	return err == nil && len(bytes) == common.HashLength
+}
+
 func createPayout(
 	_ *DemoConfig,
 	demoContext *DemoContext,
-	chSse chan json.RawMessage,
 ) http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
-		ctx := context.Background()
+		// keep the trace of the request, but a client that goes away must not
+		// abort the transfer halfway
+		ctx := context.WithoutCancel(req.Context())
 
 		if err := req.ParseForm(); err != nil {
 			slog.Log(req.Context(), slog.LevelError, "failed to parse form:", err)
-			http.Error(resp, fmt.Sprintf("failed to parse form: %s", err), http.StatusInternalServerError)
+			http.Error(resp, fmt.Sprintf("failed to parse form: %s", err), http.StatusBadRequest)
 
 			return
 		}
 
-		fromAddress := req.FormValue("from")
-		toAddress := req.FormValue("to")
-		amount := req.FormValue("amount")
 		currencyCode := req.FormValue("currency")
 
-		amountDecimal, err := decimal.NewFromString(amount)
-		if err != nil {
-			slog.Log(req.Context(), slog.LevelError, "failed to parse amount:", err)
-			http.Error(resp, fmt.Sprintf("failed to parse amount: %s", err), http.StatusInternalServerError)
-
-			return
-		}
-
-		networkCurrency, err := domain.NewNetworkCurrency(currencyCode)
-		if err != nil {
+		param, fieldErrs := validateTransfer(&TransferBody{
+			SourceAddress:      req.FormValue("from"),
+			DestinationAddress: req.FormValue("to"),
+			Amount:             req.FormValue("amount"),
+			NetworkCurrencyID:  currencyCode,
+			QuoteID:            req.FormValue("quote_id"),
+			FeeTier:            req.FormValue("fee_tier"),
+			Mode:               req.FormValue("mode"),
+		})
+		if len(fieldErrs) > 0 {
+			http.Error(resp, fieldErrs.Error(), http.StatusUnprocessableEntity)
 
 			return
 		}
 
-		param := &transaction.TransferRequest{
-			SourceAddress:      fromAddress,
-			DestinationAddress: toAddress,
-			Amount:             amountDecimal,
-			NetworkCurrencyID:  networkCurrency.ID,
-		}
+		param.Principal = auth.PrincipalFromContext(req.Context())
 
 		payload, err := demoContext.txmgr.Transfer(ctx, param)
 		if err != nil {
 			slog.Log(req.Context(), slog.LevelError, "failed to create transfer:", err)
-			http.Error(resp, fmt.Sprintf("failed to create transfer: %s", err), http.StatusInternalServerError)
+			http.Error(resp, fmt.Sprintf("failed to create transfer: %s", err), transferErrorStatus(err))
 
 			return
 		}
 
-		var txExplorerURLPrefix string
+		networkCurrency, err := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
 
-		switch networkCurrency.Network.Code {
-		case domain.TestEth:
-			txExplorerURLPrefix = "http://localhost:9111/demo/transactions?tid="
+			return
 		}
 
-		txExplorerURL := txExplorerURLPrefix + payload.ID
+		txExplorerURL := "/transactions/" + networkCurrency.Network.Code + "/" + payload.ID
 
 		txRes := Transaction{
 			ID:                    payload.ID,
@@ -399,144 +422,58 @@ func createPayout(
 			return
 		}
 
-		go func() {
-			// simulate transaction confirmation after 5 seconds
-			time.Sleep(5 * time.Second)
-
-			message := TransactionUpdatedMessage{
-				Status:               "confirmed",
-				NetworkTransactionID: payload.ID,
-			}
-
-			data, err := json.Marshal(message)
-			if err != nil {
-				slog.Log(ctx, slog.LevelError, "failed to marshall message:", err)
-			} else {
-				chSse <- data
-			}
-		}()
 	}
 }
 
-func getAddresses(config *DemoConfig, network string) []*domain.Address {
-	results := make([]*domain.Address, 0)
-
-	for _, address := range config.Addresses {
-		if address.NetworkCode == network {
-			addr := address
-			results = append(results, addr)
-		}
-	}
-
-	return results
-}
-
-func getProvider(config *DemoConfig, providerID string) (*Provider, error) {
-	for _, provider := range config.Providers {
-		if provider.ID == providerID {
-			return provider, nil
-		}
-	}
-
-	return nil, &ProviderNotFoundError{ProviderID: providerID}
+// eventNotifier hands lifecycle events to the webhook dispatcher and
+// publishes the ones the chain reports, confirmations, replacements and
+// reverts, to SSE clients.
+type eventNotifier struct {
+	dispatcher *webhook.Dispatcher
+	hub        *sse.Hub
 }
 
-func getWallet(config *DemoConfig, walletID uuid.UUID) (*Wallet, error) {
-	for _, wallet := range config.Wallets {
-		if wallet.ID == walletID {
-			return wallet, nil
-		}
-	}
+func (notifier *eventNotifier) Notify(ctx context.Context, event *transaction.Event) {
+	notifier.dispatcher.Notify(ctx, event)
 
-	return nil, &domain.WalletNotFoundError{WalletID: walletID}
-}
+	var revertedErr blockchain.RevertedError
 
-func newLocalEvmTransferor(
-	ctx context.Context,
-	config *DemoConfig,
-	walletID string,
-	nodeURL string,
-) (*evm.Client, transaction.Transferor, transaction.Builder, error) {
-	wallet, err := getWallet(config, uuid.MustParse(walletID))
-	if err != nil {
-		return nil, nil, nil, err
+	switch {
+	case event.Type == transaction.EventConfirmed, event.Type == transaction.EventReplaced:
+	case event.Type == transaction.EventFailed && errors.As(event.Err, &revertedErr):
+	default:
+		return
 	}
 
-	provider, err := getProvider(config, wallet.ProviderID)
-	if err != nil {
-		return nil, nil, nil, err
+	message := TransactionUpdatedMessage{
+		Status:               string(event.Type),
+		NetworkTransactionID: event.Payload.ID,
 	}
 
-	url := provider.Params[nodeURL]
-
-	client, err := evm.NewClient(ctx, url)
+	data, err := json.Marshal(message)
 	if err != nil {
+		slog.Log(ctx, slog.LevelError, "failed to marshall message:", "err", err)
 
-		return nil, nil, nil, err
-	}
-
-	builder := evm.NewTransactionBuilder(client)
-	signer := evm.NewPrvKeyTransactionSigner(wallet.PrivateKey)
-	broadcaster := evm.NewTransactionBroadcaster(client)
-	transferor := transaction.NewGenericTransferor(builder, signer, broadcaster)
-
-	return client, transferor, builder, nil
-}
-
-func newTransferors(
-	ctx context.Context,
-	config *DemoConfig,
-) (*DemoContext, error) {
-	walletRepo := repo.NewWalletRepo()
-
-	for _, wallet := range config.Wallets {
-		walletPayload := &domain.CreateWalletPayload{
-			ID:         wallet.ID,
-			ProviderID: wallet.ProviderID,
-		}
-
-		_, err := walletRepo.CreateWallet(ctx, walletPayload)
-		if err != nil {
-
-			return nil, err
-		}
+		return
 	}
 
-	addressRepo := repo.NewAddressRepo()
+	var addresses []string
 
-	for _, addr := range config.Addresses {
-		address := &domain.CreateAddressPayload{
-			Address:     addr.Address,
-			NetworkCode: addr.NetworkCode,
-			WalletID:    addr.WalletID,
-		}
+	var networkCode string
 
-		_, err := addressRepo.CreateAddress(ctx, address)
-		if err != nil {
+	if event.Payload.Req != nil {
+		addresses = []string{event.Payload.Req.SourceAddress, event.Payload.Req.DestinationAddress}
 
-			return nil, err
+		if networkCurrency, err := domain.NewNetworkCurrency(event.Payload.Req.NetworkCurrencyID); err == nil {
+			networkCode = networkCurrency.Network.Code
 		}
 	}
 
-	testEthC, testEthTransferor, _, err := newLocalEvmTransferor(ctx, config, walletIDEvmLocalTestnet, paramEvmLocalTestnetURL)
-	if err != nil {
-		return nil, err
-	}
-
-	testEthClient = testEthC
-
-	localTransferors := map[string]transaction.Transferor{
-		domain.TestEth: testEthTransferor,
-	}
-
-	transferorMap := make(map[string]transaction.Transferor)
-	transferorMap[providerIDLocal] = local.NewTransactionTranferor(localTransferors)
-
-	txmgr := transaction.NewManager(addressRepo, walletRepo, transferorMap)
-
-	return &DemoContext{
-		txmgr,
-		walletRepo,
-		addressRepo,
-	}, nil
+	notifier.hub.Publish(&sse.Event{
+		TransactionID: event.Payload.ID,
+		Addresses:     addresses,
+		WalletID:      event.Payload.SourceWalletID,
+		NetworkCode:   networkCode,
+		Data:          data,
+	})
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction/chain/evm"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+type MessageBody struct {
+	Address     string `json:"address"`
+	NetworkCode string `json:"network_code"`
+	// Message is the text signed by personal_sign.
+	Message string `json:"message,omitempty"`
+	// TypedData is the EIP-712 document in the format of eth_signTypedData_v4.
+	TypedData json.RawMessage `json:"typed_data,omitempty"`
+}
+
+type SignatureResponse struct {
+	ID        string `json:"id"`
+	Address   string `json:"address"`
+	Digest    string `json:"digest"`
+	Signature string `json:"signature"`
+}
+
+type VerifyMessageBody struct {
+	MessageBody
+	Signature string `json:"signature"`
+}
+
+type VerifyMessageResponse struct {
+	Valid     bool   `json:"valid"`
+	Recovered string `json:"recovered,omitempty"`
+}
+
+type messageKind int
+
+const (
+	personalMessage messageKind = iota
+	typedDataMessage
+)
+
+func signMessage(demoContext *DemoContext, kind messageKind) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var body MessageBody
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		param, fieldErrs := validateMessage(&body, kind)
+		if len(fieldErrs) > 0 {
+			writeJSON(resp, req, http.StatusUnprocessableEntity, ErrorResponse{
+				Error:  "validation failed",
+				Fields: fieldErrs,
+			})
+
+			return
+		}
+
+		param.Principal = auth.PrincipalFromContext(req.Context())
+
+		var (
+			signature *transaction.MessageSignature
+			err       error
+		)
+
+		if kind == personalMessage {
+			signature, err = demoContext.txmgr.SignPersonal(req.Context(), param)
+		} else {
+			signature, err = demoContext.txmgr.SignTypedData(req.Context(), param)
+		}
+
+		if err != nil {
+			writeTransferError(resp, req, "failed to sign message", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusOK, SignatureResponse{
+			ID:        param.ID,
+			Address:   signature.Address,
+			Digest:    hexutil.Encode(signature.Digest),
+			Signature: hexutil.Encode(signature.Signature),
+		})
+	}
+}
+
+// verifyMessage recovers the signer of a personal message or, if typed_data
+// is set, of a typed data document and compares it with the address.
+func verifyMessage() http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var body VerifyMessageBody
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		kind := personalMessage
+		if len(body.TypedData) > 0 {
+			kind = typedDataMessage
+		}
+
+		param, fieldErrs := validateMessage(&body.MessageBody, kind)
+
+		signature, err := hexutil.Decode(body.Signature)
+		if err != nil {
+			fieldErrs = append(fieldErrs, FieldError{Field: "signature", Message: "is not a hex string"})
+		}
+
+		if len(fieldErrs) > 0 {
+			writeJSON(resp, req, http.StatusUnprocessableEntity, ErrorResponse{
+				Error:  "validation failed",
+				Fields: fieldErrs,
+			})
+
+			return
+		}
+
+		if kind == personalMessage {
+			err = evm.VerifyPersonal(param.Address, param.Message, signature)
+		} else {
+			err = evm.VerifyTypedData(param.Address, param.TypedData, signature)
+		}
+
+		var mismatchErr evm.SignatureMismatchError
+
+		switch {
+		case err == nil:
+			writeJSON(resp, req, http.StatusOK, VerifyMessageResponse{Valid: true, Recovered: param.Address})
+		case errors.As(err, &mismatchErr):
+			writeJSON(resp, req, http.StatusOK, VerifyMessageResponse{Recovered: mismatchErr.Recovered})
+		default:
+			writeError(resp, req, http.StatusUnprocessableEntity, err.Error())
+		}
+	}
+}
+
+func validateMessage(body *MessageBody, kind messageKind) (*transaction.MessageRequest, FieldErrors) {
+	var fieldErrs FieldErrors
+
+	network, err := domain.GetNetwork(body.NetworkCode)
+	if err != nil {
+		fieldErrs = append(fieldErrs, FieldError{Field: "network_code", Message: "unknown network"})
+	}
+
+	var address string
+
+	switch {
+	case body.Address == "":
+		fieldErrs = append(fieldErrs, FieldError{Field: "address", Message: "is required"})
+	case network != nil:
+		address, err = domain.NormalizeAddress(network.Code, body.Address)
+		if err != nil {
+			fieldErrs = append(fieldErrs, FieldError{
+				Field:   "address",
+				Message: "is not a valid " + network.Code + " address",
+			})
+		}
+	}
+
+	switch kind {
+	case personalMessage:
+		if body.Message == "" {
+			fieldErrs = append(fieldErrs, FieldError{Field: "message", Message: "is required"})
+		}
+	case typedDataMessage:
+		if len(body.TypedData) == 0 {
+			fieldErrs = append(fieldErrs, FieldError{Field: "typed_data", Message: "is required"})
+		} else if _, err := evm.TypedDataHash(body.TypedData); err != nil {
+			fieldErrs = append(fieldErrs, FieldError{Field: "typed_data", Message: err.Error()})
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		return nil, fieldErrs
+	}
+
+	return &transaction.MessageRequest{
+		Address:     address,
+		NetworkCode: network.Code,
+		Message:     []byte(body.Message),
+		TypedData:   body.TypedData,
+	}, nil
+}
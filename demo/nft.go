@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+type NFTTransferBody struct {
+	ID                 string `json:"id,omitempty"`
+	SourceAddress      string `json:"source_address"`
+	DestinationAddress string `json:"destination_address"`
+	NetworkCode        string `json:"network_code"`
+	Contract           string `json:"contract"`
+	Standard           string `json:"standard"`
+	// TokenID and Amount are decimal strings, as token IDs are often beyond
+	// the precision of JSON numbers.
+	TokenID string `json:"token_id"`
+	Amount  string `json:"amount,omitempty"`
+	// Owner is set when the source address transfers as an approved operator.
+	Owner string `json:"owner,omitempty"`
+}
+
+type NFTTransferResponse struct {
+	ID         string                   `json:"id"`
+	Hash       string                   `json:"hash"`
+	State      string                   `json:"state"`
+	ProviderID string                   `json:"provider_id"`
+	Call       *transaction.DecodedCall `json:"call"`
+	URL        string                   `json:"url"`
+}
+
+func createNFTTransfer(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var body NFTTransferBody
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		param, fieldErrs := validateNFTTransfer(&body)
+		if len(fieldErrs) > 0 {
+			writeJSON(resp, req, http.StatusUnprocessableEntity, ErrorResponse{
+				Error:  "validation failed",
+				Fields: fieldErrs,
+			})
+
+			return
+		}
+
+		param.Principal = auth.PrincipalFromContext(req.Context())
+
+		// a client that goes away must not abort the transfer halfway
+		ctx := context.WithoutCancel(req.Context())
+
+		payload, err := demoContext.txmgr.TransferNFT(ctx, param)
+		if err != nil {
+			writeTransferError(resp, req, "failed to transfer NFT", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusAccepted, NFTTransferResponse{
+			ID:         param.ID,
+			Hash:       payload.ID,
+			State:      string(transaction.EventBroadcast),
+			ProviderID: payload.ProviderID,
+			Call:       payload.Call,
+			URL:        "/transactions/" + body.NetworkCode + "/" + payload.ID,
+		})
+
+	}
+}
+
+func validateNFTTransfer(body *NFTTransferBody) (*transaction.NFTTransferRequest, FieldErrors) {
+	var fieldErrs FieldErrors
+
+	network, err := domain.GetNetwork(body.NetworkCode)
+	if err != nil {
+		return nil, append(fieldErrs, FieldError{Field: "network_code", Message: "unknown network"})
+	}
+
+	nativeCurrency, err := domain.NewNetworkCurrency(network.NativeToken)
+	if err != nil {
+		return nil, append(fieldErrs, FieldError{Field: "network_code", Message: "has no native currency"})
+	}
+
+	sourceAddress, fieldErrs := validateAddress(fieldErrs, "source_address", nativeCurrency, body.SourceAddress)
+	destinationAddress, fieldErrs := validateAddress(
+		fieldErrs, "destination_address", nativeCurrency, body.DestinationAddress,
+	)
+	contract, fieldErrs := validateAddress(fieldErrs, "contract", nativeCurrency, body.Contract)
+
+	var owner string
+	if body.Owner != "" {
+		owner, fieldErrs = validateAddress(fieldErrs, "owner", nativeCurrency, body.Owner)
+	}
+
+	standard, err := domain.ParseNFTStandard(body.Standard)
+	if err != nil {
+		fieldErrs = append(fieldErrs, FieldError{Field: "standard", Message: "must be ERC721 or ERC1155"})
+	}
+
+	tokenID, ok := new(big.Int).SetString(strings.TrimSpace(body.TokenID), 10)
+
+	switch {
+	case body.TokenID == "":
+		fieldErrs = append(fieldErrs, FieldError{Field: "token_id", Message: "is required"})
+	case !ok || tokenID.Sign() < 0:
+		fieldErrs = append(fieldErrs, FieldError{Field: "token_id", Message: "is not a non-negative integer"})
+	}
+
+	var amount *big.Int
+
+	if body.Amount != "" {
+		amount, ok = new(big.Int).SetString(strings.TrimSpace(body.Amount), 10)
+
+		switch {
+		case !ok || amount.Sign() <= 0:
+			fieldErrs = append(fieldErrs, FieldError{Field: "amount", Message: "is not a positive integer"})
+		case standard == domain.NFTStandardERC721 && amount.Cmp(big.NewInt(1)) != 0:
+			fieldErrs = append(fieldErrs, FieldError{Field: "amount", Message: "must be 1 for ERC721"})
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		return nil, fieldErrs
+	}
+
+	return &transaction.NFTTransferRequest{
+		ID:                 body.ID,
+		SourceAddress:      sourceAddress,
+		DestinationAddress: destinationAddress,
+		Asset: &domain.NFT{
+			NetworkCode: network.Code,
+			Contract:    contract,
+			Standard:    standard,
+			TokenID:     tokenID,
+		},
+		Amount: amount,
+		Owner:  owner,
+	}, nil
+}
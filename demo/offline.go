@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const maxBundleSize = 1 << 20
+
+type OfflineBundleResponse struct {
+	Bundle *transaction.Bundle `json:"bundle"`
+	// Text is the bundle on a single line, for QR codes or copy and paste.
+	Text string `json:"text"`
+}
+
+// exportTransfer builds a transfer and returns it unsigned, for signing with
+// cmd/sign on an offline machine.
+func exportTransfer(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var body TransferBody
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		param, fieldErrs := validateTransfer(&body)
+		if len(fieldErrs) > 0 {
+			writeJSON(resp, req, http.StatusUnprocessableEntity, ErrorResponse{
+				Error:  "validation failed",
+				Fields: fieldErrs,
+			})
+
+			return
+		}
+
+		param.Principal = auth.PrincipalFromContext(req.Context())
+
+		bundle, err := demoContext.txmgr.ExportTransfer(req.Context(), param)
+		if err != nil {
+			writeTransferError(resp, req, "failed to export transfer", err)
+
+			return
+		}
+
+		text, err := bundle.Text()
+		if err != nil {
+			writeRepoError(resp, req, "failed to encode bundle", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusCreated, OfflineBundleResponse{Bundle: bundle, Text: text})
+	}
+}
+
+// submitTransfer broadcasts a signed bundle. The body is the bundle in its
+// JSON or its text form.
+func submitTransfer(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		content, err := io.ReadAll(http.MaxBytesReader(resp, req.Body, maxBundleSize))
+		if err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		bundle, err := transaction.ParseBundle(content)
+		if err != nil {
+			writeError(resp, req, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		// a client that goes away must not abort the broadcast halfway
+		ctx := context.WithoutCancel(req.Context())
+
+		payload, err := demoContext.txmgr.ImportTransfer(ctx, bundle, auth.PrincipalFromContext(req.Context()))
+		if err != nil {
+			writeTransferError(resp, req, "failed to submit transfer", err)
+
+			return
+		}
+
+		networkCurrency, _ := domain.NewNetworkCurrency(bundle.NetworkCurrencyID)
+
+		writeJSON(resp, req, http.StatusAccepted, TransferResponse{
+			ID:                bundle.TransferID,
+			Hash:              payload.ID,
+			State:             string(transaction.EventBroadcast),
+			ProviderID:        payload.ProviderID,
+			NetworkCurrencyID: bundle.NetworkCurrencyID,
+			Amount:            bundle.Amount,
+			URL:               "/transactions/" + networkCurrency.Network.Code + "/" + payload.ID,
+		})
+
+	}
+}
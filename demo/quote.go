@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+func createQuote(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if err := req.ParseForm(); err != nil {
+			http.Error(resp, "failed to parse form: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		amountDecimal, err := decimal.NewFromString(req.FormValue("amount"))
+		if err != nil {
+			http.Error(resp, "failed to parse amount: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		networkCurrency, err := domain.NewNetworkCurrency(req.FormValue("currency"))
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		param := &transaction.TransferRequest{
+			SourceAddress:      req.FormValue("from"),
+			DestinationAddress: req.FormValue("to"),
+			Amount:             amountDecimal,
+			NetworkCurrencyID:  networkCurrency.ID,
+			Principal:          auth.PrincipalFromContext(req.Context()),
+		}
+
+		quote, err := demoContext.txmgr.Quote(req.Context(), param)
+		if err != nil {
+			slog.Log(req.Context(), slog.LevelError, "failed to create quote:", "err", err)
+			http.Error(resp, "failed to create quote: "+err.Error(), transferErrorStatus(err))
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusCreated, quote)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+type TransferBody struct {
+	ID                 string `json:"id,omitempty"`
+	SourceAddress      string `json:"source_address"`
+	DestinationAddress string `json:"destination_address"`
+	Amount             string `json:"amount"`
+	NetworkCurrencyID  string `json:"network_currency_id"`
+	QuoteID            string `json:"quote_id,omitempty"`
+	FeeTier            string `json:"fee_tier,omitempty"`
+	Mode               string `json:"mode,omitempty"`
+}
+
+type TransferResponse struct {
+	ID                string `json:"id"`
+	Hash              string `json:"hash"`
+	State             string `json:"state"`
+	ProviderID        string `json:"provider_id"`
+	NetworkCurrencyID string `json:"network_currency_id"`
+	Amount            string `json:"amount"`
+	URL               string `json:"url"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for index, fieldErr := range e {
+		messages[index] = fieldErr.Field + ": " + fieldErr.Message
+	}
+
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+func createTransfer(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var body TransferBody
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		param, fieldErrs := validateTransfer(&body)
+		if len(fieldErrs) > 0 {
+			writeJSON(resp, req, http.StatusUnprocessableEntity, ErrorResponse{
+				Error:  "validation failed",
+				Fields: fieldErrs,
+			})
+
+			return
+		}
+
+		param.Principal = auth.PrincipalFromContext(req.Context())
+
+		// a client that goes away must not abort the transfer halfway
+		ctx := context.WithoutCancel(req.Context())
+
+		payload, err := demoContext.txmgr.Transfer(ctx, param)
+		if err != nil {
+			writeTransferError(resp, req, "failed to create transfer", err)
+
+			return
+		}
+
+		networkCurrency, _ := domain.NewNetworkCurrency(param.NetworkCurrencyID)
+
+		writeJSON(resp, req, http.StatusAccepted, TransferResponse{
+			ID:                param.ID,
+			Hash:              payload.ID,
+			State:             string(transaction.EventBroadcast),
+			ProviderID:        payload.ProviderID,
+			NetworkCurrencyID: param.NetworkCurrencyID,
+			Amount:            param.Amount.String(),
+			URL:               "/transactions/" + networkCurrency.Network.Code + "/" + payload.ID,
+		})
+
+	}
+}
+
+// validateTransfer checks every field of the body and reports all problems
+// together, so a client can fix its request in one round trip.
+func validateTransfer(body *TransferBody) (*transaction.TransferRequest, FieldErrors) {
+	var fieldErrs FieldErrors
+
+	networkCurrency, err := domain.NewNetworkCurrency(body.NetworkCurrencyID)
+	if err != nil {
+		fieldErrs = append(fieldErrs, FieldError{Field: "network_currency_id", Message: "unknown currency"})
+	}
+
+	amount, err := decimal.NewFromString(strings.TrimSpace(body.Amount))
+
+	switch {
+	case body.Amount == "":
+		fieldErrs = append(fieldErrs, FieldError{Field: "amount", Message: "is required"})
+	case err != nil:
+		fieldErrs = append(fieldErrs, FieldError{Field: "amount", Message: "is not a decimal number"})
+	case !amount.IsPositive():
+		fieldErrs = append(fieldErrs, FieldError{Field: "amount", Message: "must be positive"})
+	case networkCurrency != nil && -amount.Exponent() > int32(networkCurrency.Scale):
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   "amount",
+			Message: "has more than " + strconv.Itoa(networkCurrency.Scale) + " decimal places",
+		})
+	}
+
+	var sourceAddress, destinationAddress string
+
+	if networkCurrency != nil {
+		sourceAddress, fieldErrs = validateAddress(fieldErrs, "source_address", networkCurrency, body.SourceAddress)
+		destinationAddress, fieldErrs = validateAddress(
+			fieldErrs, "destination_address", networkCurrency, body.DestinationAddress,
+		)
+
+		if sourceAddress != "" && sourceAddress == destinationAddress {
+			fieldErrs = append(fieldErrs, FieldError{
+				Field:   "destination_address",
+				Message: "must differ from source_address",
+			})
+		}
+	}
+
+	switch transaction.FeeTier(body.FeeTier) {
+	case "", transaction.FeeTierSlow, transaction.FeeTierStandard, transaction.FeeTierFast:
+	default:
+		fieldErrs = append(fieldErrs, FieldError{Field: "fee_tier", Message: "must be slow, standard or fast"})
+	}
+
+	if body.FeeTier != "" && body.QuoteID == "" {
+		fieldErrs = append(fieldErrs, FieldError{Field: "fee_tier", Message: "requires quote_id"})
+	}
+
+	mode := transaction.TransferMode(body.Mode)
+
+	switch mode {
+	case "", transaction.TransferModeDirect:
+	case transaction.TransferModePermit, transaction.TransferModeAuthorization:
+		if networkCurrency != nil && networkCurrency.Address == "" {
+			fieldErrs = append(fieldErrs, FieldError{Field: "mode", Message: "requires a token currency"})
+		}
+
+		if body.QuoteID != "" {
+			fieldErrs = append(fieldErrs, FieldError{Field: "mode", Message: "cannot be combined with quote_id"})
+		}
+	default:
+		fieldErrs = append(fieldErrs, FieldError{Field: "mode", Message: "must be direct, permit or authorization"})
+	}
+
+	if len(fieldErrs) > 0 {
+		return nil, fieldErrs
+	}
+
+	return &transaction.TransferRequest{
+		ID:                 body.ID,
+		SourceAddress:      sourceAddress,
+		DestinationAddress: destinationAddress,
+		Amount:             amount.Truncate(int32(networkCurrency.Scale)),
+		NetworkCurrencyID:  networkCurrency.ID,
+		QuoteID:            body.QuoteID,
+		FeeTier:            transaction.FeeTier(body.FeeTier),
+		Mode:               mode,
+	}, nil
+}
+
+func validateAddress(
+	fieldErrs FieldErrors,
+	field string,
+	networkCurrency *domain.NetworkCurrency,
+	value string,
+) (string, FieldErrors) {
+	if value == "" {
+		return "", append(fieldErrs, FieldError{Field: field, Message: "is required"})
+	}
+
+	normalized, err := domain.NormalizeAddress(networkCurrency.Network.Code, value)
+	if err != nil {
+		return "", append(fieldErrs, FieldError{
+			Field:   field,
+			Message: "is not a valid " + networkCurrency.Network.Code + " address",
+		})
+	}
+
+	return normalized, fieldErrs
+}
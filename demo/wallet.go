@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/auth"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+type CreateWalletRequest struct {
+	ProviderID string `json:"provider_id"`
+}
+
+// CreateAddressRequest imports Address when it is set, and otherwise has the
+// wallet's provider generate a new address.
+type CreateAddressRequest struct {
+	NetworkCode string `json:"network_code"`
+	Address     string `json:"address,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+func createWallet(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var body CreateWalletRequest
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		if body.ProviderID == "" {
+			writeError(resp, req, http.StatusBadRequest, "provider_id is required")
+
+			return
+		}
+
+		if _, ok := demoContext.providers[body.ProviderID]; !ok {
+			writeError(resp, req, http.StatusUnprocessableEntity, "unknown provider: "+body.ProviderID)
+
+			return
+		}
+
+		if _, ok := demoContext.generators[body.ProviderID]; !ok {
+			writeError(resp, req, http.StatusUnprocessableEntity,
+				"provider does not support address generation: "+body.ProviderID)
+
+			return
+		}
+
+		wallet, err := demoContext.walletRepo.CreateWallet(req.Context(), &domain.CreateWalletPayload{
+			ProviderID: body.ProviderID,
+		})
+		if err != nil {
+			writeRepoError(resp, req, "failed to create wallet", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusCreated, wallet)
+	}
+}
+
+func getWallets(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		wallets, err := demoContext.walletRepo.GetWallets(req.Context())
+		if err != nil {
+			writeRepoError(resp, req, "failed to retrieve wallets", err)
+
+			return
+		}
+
+		wallets = slices.DeleteFunc(wallets, func(wallet *domain.Wallet) bool {
+			return !allowsWallet(req, wallet.ID)
+		})
+
+		if wallets == nil {
+			wallets = []*domain.Wallet{}
+		}
+
+		writeJSON(resp, req, http.StatusOK, wallets)
+	}
+}
+
+func getWalletByID(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		walletID, err := uuid.Parse(req.PathValue("id"))
+		if err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid wallet id")
+
+			return
+		}
+
+		if !allowsWallet(req, walletID) {
+			writeRepoError(resp, req, "failed to retrieve wallet", domain.WalletNotFoundError{WalletID: walletID})
+
+			return
+		}
+
+		wallet, err := demoContext.walletRepo.GetWallet(req.Context(), walletID)
+		if err != nil {
+			writeRepoError(resp, req, "failed to retrieve wallet", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusOK, wallet)
+	}
+}
+
+func createAddress(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		walletID, err := uuid.Parse(req.PathValue("id"))
+		if err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid wallet id")
+
+			return
+		}
+
+		var body CreateAddressRequest
+
+		if err := decodeJSON(req, &body); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+
+			return
+		}
+
+		if _, err := domain.GetNetwork(body.NetworkCode); err != nil {
+			writeError(resp, req, http.StatusBadRequest, "unknown network_code: "+body.NetworkCode)
+
+			return
+		}
+
+		if !allowsWallet(req, walletID) || !allowsNetwork(req, body.NetworkCode) {
+			writeError(resp, req, http.StatusForbidden, "api key is not permitted to use this wallet or network")
+
+			return
+		}
+
+		wallet, err := demoContext.walletRepo.GetWallet(ctx, walletID)
+		if err != nil {
+			writeRepoError(resp, req, "failed to retrieve wallet", err)
+
+			return
+		}
+
+		address := body.Address
+
+		if address == "" {
+			generator, ok := demoContext.generators[wallet.ProviderID]
+			if !ok {
+				writeError(resp, req, http.StatusUnprocessableEntity,
+					"provider does not support address generation: "+wallet.ProviderID)
+
+				return
+			}
+
+			address, err = generator.GenerateAddress(ctx, body.NetworkCode)
+			if err != nil {
+				writeRepoError(resp, req, "failed to generate address", err)
+
+				return
+			}
+		}
+
+		created, err := demoContext.addressRepo.CreateAddress(ctx, &domain.CreateAddressPayload{
+			Address:     address,
+			NetworkCode: body.NetworkCode,
+			WalletID:    wallet.ID,
+		})
+		if err != nil {
+			writeRepoError(resp, req, "failed to create address", err)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusCreated, created)
+	}
+}
+
+func getWalletAddresses(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		writeWalletAddresses(resp, req, demoContext, req.PathValue("id"))
+	}
+}
+
+func listAddresses(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+
+		if walletID := query.Get("wallet_id"); walletID != "" {
+			writeWalletAddresses(resp, req, demoContext, walletID)
+
+			return
+		}
+
+		networkCode := query.Get("network")
+		if networkCode == "" {
+			writeError(resp, req, http.StatusBadRequest, "network or wallet_id query parameter is required")
+
+			return
+		}
+
+		addresses, err := demoContext.addressRepo.GetAddressesByNetwork(req.Context(), networkCode)
+		if err != nil {
+			writeRepoError(resp, req, "failed to retrieve addresses", err)
+
+			return
+		}
+
+		writeAddresses(resp, req, visibleAddresses(req, addresses))
+	}
+}
+
+func writeWalletAddresses(resp http.ResponseWriter, req *http.Request, demoContext *DemoContext, value string) {
+	walletID, err := uuid.Parse(value)
+	if err != nil {
+		writeError(resp, req, http.StatusBadRequest, "invalid wallet id")
+
+		return
+	}
+
+	if !allowsWallet(req, walletID) {
+		writeRepoError(resp, req, "failed to retrieve wallet", domain.WalletNotFoundError{WalletID: walletID})
+
+		return
+	}
+
+	if _, err := demoContext.walletRepo.GetWallet(req.Context(), walletID); err != nil {
+		writeRepoError(resp, req, "failed to retrieve wallet", err)
+
+		return
+	}
+
+	addresses, err := demoContext.addressRepo.GetAddressesByWallet(req.Context(), walletID)
+	if err != nil {
+		writeRepoError(resp, req, "failed to retrieve addresses", err)
+
+		return
+	}
+
+	writeAddresses(resp, req, visibleAddresses(req, addresses))
+}
+
+func writeAddresses(resp http.ResponseWriter, req *http.Request, addresses []*domain.Address) {
+	if addresses == nil {
+		addresses = []*domain.Address{}
+	}
+
+	writeJSON(resp, req, http.StatusOK, addresses)
+}
+
+func decodeJSON(req *http.Request, target any) error {
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(target)
+}
+
+func writeError(resp http.ResponseWriter, req *http.Request, status int, message string) {
+	writeJSON(resp, req, status, ErrorResponse{Error: message})
+}
+
+// writeRepoError maps the typed errors of the domain repositories to 4xx codes
+// and anything else to an internal server error.
+func writeRepoError(resp http.ResponseWriter, req *http.Request, message string, err error) {
+	var (
+		walletNotFoundErr  domain.WalletNotFoundError
+		addressNotFoundErr domain.AddressNotFoundError
+		addressExistsErr   domain.AddressAlreadyExistsError
+		walletExistsErr    domain.WalletAlreadyExistsError
+		invalidAddressErr  domain.InvalidAddressError
+		apiKeyNotFoundErr  domain.APIKeyNotFoundError
+		permissionErr      domain.PermissionDeniedError
+		revokedKeyErr      auth.RevokedKeyError
+	)
+
+	switch {
+	case errors.As(err, &walletNotFoundErr), errors.As(err, &addressNotFoundErr), errors.As(err, &apiKeyNotFoundErr):
+		writeError(resp, req, http.StatusNotFound, err.Error())
+	case errors.As(err, &permissionErr):
+		writeError(resp, req, http.StatusForbidden, err.Error())
+	case errors.As(err, &addressExistsErr), errors.As(err, &walletExistsErr), errors.As(err, &revokedKeyErr):
+		writeError(resp, req, http.StatusConflict, err.Error())
+	case errors.As(err, &invalidAddressErr):
+		writeError(resp, req, http.StatusBadRequest, err.Error())
+	default:
+		slog.Log(req.Context(), slog.LevelError, message+":", "err", err)
+		writeError(resp, req, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+func createWebhook(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var payload domain.CreateWebhookSubscriptionPayload
+
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			http.Error(resp, "failed to parse request: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		parsedURL, err := url.Parse(payload.URL)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			http.Error(resp, "url must be an absolute http(s) URL", http.StatusBadRequest)
+
+			return
+		}
+
+		if payload.Secret == "" {
+			http.Error(resp, "secret is required", http.StatusBadRequest)
+
+			return
+		}
+
+		if len(payload.EventTypes) == 0 {
+			http.Error(resp, "at least one event type is required", http.StatusBadRequest)
+
+			return
+		}
+
+		for _, eventType := range payload.EventTypes {
+			if _, ok := transaction.ParseEventType(eventType); !ok {
+				http.Error(resp, "unknown event type: "+eventType, http.StatusBadRequest)
+
+				return
+			}
+		}
+
+		subscription, err := demoContext.webhookRepo.CreateSubscription(req.Context(), &payload)
+		if err != nil {
+			slog.Log(req.Context(), slog.LevelError, "failed to create webhook subscription:", "err", err)
+			http.Error(resp, "failed to create webhook subscription", http.StatusInternalServerError)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusCreated, subscription)
+	}
+}
+
+func getWebhooks(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		subscriptions, err := demoContext.webhookRepo.GetSubscriptions(req.Context())
+		if err != nil {
+			slog.Log(req.Context(), slog.LevelError, "failed to retrieve webhook subscriptions:", "err", err)
+			http.Error(resp, "failed to retrieve webhook subscriptions", http.StatusInternalServerError)
+
+			return
+		}
+
+		if subscriptions == nil {
+			subscriptions = []*domain.WebhookSubscription{}
+		}
+
+		writeJSON(resp, req, http.StatusOK, subscriptions)
+	}
+}
+
+func getWebhookDeadLetters(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		deliveries, err := demoContext.dispatcher.DeadLetters(req.Context())
+		if err != nil {
+			slog.Log(req.Context(), slog.LevelError, "failed to retrieve dead letters:", "err", err)
+			http.Error(resp, "failed to retrieve dead letters", http.StatusInternalServerError)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusOK, deliveries)
+	}
+}
+
+func replayWebhookDelivery(demoContext *DemoContext) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		deliveryID, err := uuid.Parse(req.PathValue("id"))
+		if err != nil {
+			http.Error(resp, "invalid delivery id", http.StatusBadRequest)
+
+			return
+		}
+
+		delivery, err := demoContext.dispatcher.Replay(req.Context(), deliveryID)
+		if err != nil {
+			var deliveryNotFoundErr domain.WebhookDeliveryNotFoundError
+
+			var subscriptionNotFoundErr domain.WebhookSubscriptionNotFoundError
+
+			if errors.As(err, &deliveryNotFoundErr) || errors.As(err, &subscriptionNotFoundErr) {
+				http.Error(resp, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			slog.Log(req.Context(), slog.LevelError, "failed to replay webhook delivery:", "err", err)
+			http.Error(resp, "failed to replay webhook delivery", http.StatusInternalServerError)
+
+			return
+		}
+
+		writeJSON(resp, req, http.StatusAccepted, delivery)
+	}
+}
+
+func writeJSON(resp http.ResponseWriter, req *http.Request, status int, body any) {
+	res, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		slog.Log(req.Context(), slog.LevelError, "failed to marshall response:", "err", err)
+		http.Error(resp, "failed to marshall response", http.StatusInternalServerError)
+
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+
+	if _, err := resp.Write(res); err != nil {
+		slog.Log(req.Context(), slog.LevelError, "failed to write response:", "err", err)
+	}
+}
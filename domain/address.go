@@ -2,7 +2,9 @@ package domain
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/google/uuid"
 )
 
@@ -19,6 +21,24 @@ func (e AddressNotFoundError) Error() string {
 	return fmt.Sprintf("address %s not found", e.Address)
 }
 
+type AddressAlreadyExistsError struct {
+	Address     string
+	NetworkCode string
+}
+
+func (e AddressAlreadyExistsError) Error() string {
+	return fmt.Sprintf("address %s already exists for network %s", e.Address, e.NetworkCode)
+}
+
+type InvalidAddressError struct {
+	Address     string
+	NetworkCode string
+}
+
+func (e InvalidAddressError) Error() string {
+	return fmt.Sprintf("address %s is not valid for network %s", e.Address, e.NetworkCode)
+}
+
 type Address struct {
 	ID          uuid.UUID `json:"id"`
 	Address     string    `json:"address"`
@@ -31,3 +51,27 @@ type CreateAddressPayload struct {
 	NetworkCode string    `json:"network_code"`
 	WalletID    uuid.UUID `json:"wallet_id"`
 }
+
+// NormalizeAddress returns the canonical form of an address on a network, so
+// that equivalent spellings compare equal. EVM addresses are converted to their
+// EIP-55 checksum form; addresses of unknown networks are only trimmed.
+func NormalizeAddress(networkCode string, address string) (string, error) {
+	address = strings.TrimSpace(address)
+
+	network, err := GetNetwork(networkCode)
+	if err != nil {
+		// unknown networks have no address format to enforce
+		network = &Network{Code: networkCode}
+	}
+
+	switch network.Family {
+	case FamilyEvm:
+		if !common.IsHexAddress(address) {
+			return "", InvalidAddressError{Address: address, NetworkCode: networkCode}
+		}
+
+		return common.HexToAddress(address).Hex(), nil
+	}
+
+	return address, nil
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Scope string
+
+const (
+	ScopeRead     Scope = "read"
+	ScopeTransfer Scope = "transfer"
+	ScopeAdmin    Scope = "admin"
+)
+
+// Scopes lists the scopes from the least to the most privileged; each scope
+// includes the ones before it.
+var Scopes = []Scope{ScopeRead, ScopeTransfer, ScopeAdmin}
+
+func ParseScope(value string) (Scope, error) {
+	for _, scope := range Scopes {
+		if string(scope) == value {
+			return scope, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid scope: %s", value)
+}
+
+// Includes reports whether a key granted scope s may act with scope required.
+func (s Scope) Includes(required Scope) bool {
+	granted, needed := slices.Index(Scopes, s), slices.Index(Scopes, required)
+
+	return granted >= 0 && needed >= 0 && granted >= needed
+}
+
+type APIKeyNotFoundError struct {
+	KeyID uuid.UUID
+}
+
+func (e APIKeyNotFoundError) Error() string {
+	return fmt.Sprintf("api key %s not found", e.KeyID)
+}
+
+type PermissionDeniedError struct {
+	KeyID  uuid.UUID
+	Reason string
+}
+
+func (e PermissionDeniedError) Error() string {
+	return fmt.Sprintf("api key %s is not permitted to %s", e.KeyID, e.Reason)
+}
+
+// APIKey is an API credential. Only the hash of its secret and the key that
+// checks signed requests, encrypted, are stored; the secret itself is handed
+// out once on creation and rotation.
+type APIKey struct {
+	ID           uuid.UUID   `json:"id"`
+	Name         string      `json:"name"`
+	SecretHash   []byte      `json:"-"`
+	SigningKey   []byte      `json:"-"`
+	Scopes       []Scope     `json:"scopes"`
+	WalletIDs    []uuid.UUID `json:"wallet_ids,omitempty"`
+	NetworkCodes []string    `json:"network_codes,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	RotatedAt    *time.Time  `json:"rotated_at,omitempty"`
+	RevokedAt    *time.Time  `json:"revoked_at,omitempty"`
+}
+
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+func (k *APIKey) Principal() *Principal {
+	return &Principal{
+		KeyID:        k.ID,
+		Name:         k.Name,
+		Scopes:       slices.Clone(k.Scopes),
+		WalletIDs:    slices.Clone(k.WalletIDs),
+		NetworkCodes: slices.Clone(k.NetworkCodes),
+	}
+}
+
+type CreateAPIKeyPayload struct {
+	ID           uuid.UUID
+	Name         string
+	SecretHash   []byte
+	SigningKey   []byte
+	Scopes       []Scope
+	WalletIDs    []uuid.UUID
+	NetworkCodes []string
+}
+
+// Principal is the authenticated caller of a request. Empty WalletIDs or
+// NetworkCodes leave the key unrestricted in that dimension.
+type Principal struct {
+	KeyID        uuid.UUID   `json:"key_id"`
+	Name         string      `json:"name"`
+	Scopes       []Scope     `json:"scopes"`
+	WalletIDs    []uuid.UUID `json:"wallet_ids,omitempty"`
+	NetworkCodes []string    `json:"network_codes,omitempty"`
+}
+
+func (p *Principal) HasScope(required Scope) bool {
+	for _, scope := range p.Scopes {
+		if scope.Includes(required) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Principal) AllowsWallet(walletID uuid.UUID) bool {
+	return len(p.WalletIDs) == 0 || slices.Contains(p.WalletIDs, walletID)
+}
+
+func (p *Principal) AllowsNetwork(networkCode string) bool {
+	return len(p.NetworkCodes) == 0 || slices.Contains(p.NetworkCodes, networkCode)
+}
+
+// Restricted reports whether the principal is limited to some wallets or
+// networks.
+func (p *Principal) Restricted() bool {
+	return len(p.WalletIDs) > 0 || len(p.NetworkCodes) > 0
+}
+
+// AllowsAddress reports whether the address belongs to a wallet and network
+// the principal may use.
+func (p *Principal) AllowsAddress(address *Address) bool {
+	return p.AllowsWallet(address.WalletID) && p.AllowsNetwork(address.NetworkCode)
+}
+
+// Covers reports whether other grants nothing beyond p: each of its scopes is
+// included in one of p's, and where p is restricted to wallets or networks,
+// other is restricted to some of them.
+func (p *Principal) Covers(other *Principal) bool {
+	for _, scope := range other.Scopes {
+		if !p.HasScope(scope) {
+			return false
+		}
+	}
+
+	if len(p.WalletIDs) > 0 && (len(other.WalletIDs) == 0 || !subset(other.WalletIDs, p.WalletIDs)) {
+		return false
+	}
+
+	if len(p.NetworkCodes) > 0 && (len(other.NetworkCodes) == 0 || !subset(other.NetworkCodes, p.NetworkCodes)) {
+		return false
+	}
+
+	return true
+}
+
+func subset[T comparable](values []T, of []T) bool {
+	for _, value := range values {
+		if !slices.Contains(of, value) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
 
 const (
 	TestEth string = "TestEth"
@@ -14,9 +18,12 @@ type Currency struct {
 	Code string
 }
 
+const FamilyEvm = "EVM"
+
 type Network struct {
 	Code        string
 	NativeToken string
+	Family      string
 }
 
 type NetworkCurrency struct {
@@ -27,10 +34,45 @@ type NetworkCurrency struct {
 	Address  string
 }
 
+var (
+	tokenMutex sync.RWMutex
+	tokens     = make(map[string]NetworkCurrency)
+)
+
+// RegisterToken adds a token currency, e.g. an ERC-20 contract at Address,
+// to the currencies of its network. Tokens follow the native currency in
+// GetNetworkCurrencies.
+func RegisterToken(token *NetworkCurrency) {
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+
+	tokens[token.ID] = *token
+}
+
+func networkTokens(networkCode string) []*NetworkCurrency {
+	tokenMutex.RLock()
+	defer tokenMutex.RUnlock()
+
+	var result []*NetworkCurrency
+
+	for _, token := range tokens {
+		if token.Network.Code == networkCode {
+			copied := token
+			result = append(result, &copied)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
 func GetNetworkCurrencies(networkCode string) ([]*NetworkCurrency, error) {
 	switch networkCode {
 	case "TestEth":
-		return []*NetworkCurrency{
+		return append([]*NetworkCurrency{
 			{
 				ID:      TestETH,
 				Scale:   18,
@@ -38,12 +80,13 @@ func GetNetworkCurrencies(networkCode string) ([]*NetworkCurrency, error) {
 				Network: Network{
 					Code:        TestEth,
 					NativeToken: TestETH,
+					Family:      FamilyEvm,
 				},
 				Currency: Currency{
 					Code: ETH,
 				},
 			},
-		}, nil
+		}, networkTokens(networkCode)...), nil
 	}
 
 	return nil, fmt.Errorf("invalid network: %s", networkCode)
@@ -59,11 +102,30 @@ func NewNetworkCurrency(networkCurrencyID string) (*NetworkCurrency, error) {
 			Network: Network{
 				Code:        TestEth,
 				NativeToken: TestETH,
+				Family:      FamilyEvm,
 			},
 			Currency: Currency{
 				Code: ETH,
 			},
 		}, nil
 	}
+
+	tokenMutex.RLock()
+	defer tokenMutex.RUnlock()
+
+	if token, ok := tokens[networkCurrencyID]; ok {
+		return &token, nil
+	}
+
 	return nil, fmt.Errorf("invalid currency: %s", networkCurrencyID)
 }
+
+func GetNetwork(networkCode string) (*Network, error) {
+	networkCurrencies, err := GetNetworkCurrencies(networkCode)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &networkCurrencies[0].Network, nil
+}
@@ -9,10 +9,41 @@ import (
 type WalletRepo interface {
 	CreateWallet(context.Context, *CreateWalletPayload) (*Wallet, error)
 	GetWallet(context.Context, uuid.UUID) (*Wallet, error)
+	GetWallets(context.Context) ([]*Wallet, error)
 }
 
 type AddressRepo interface {
 	CreateAddress(context.Context, *CreateAddressPayload) (*Address, error)
 	GetAddressByValue(context.Context, string, string) (*Address, error)
 	GetAddressesByNetwork(context.Context, string) ([]*Address, error)
+	GetAddressesByWallet(context.Context, uuid.UUID) ([]*Address, error)
+}
+
+// AddressGenerator creates new addresses whose keys are held by a provider.
+type AddressGenerator interface {
+	GenerateAddress(ctx context.Context, networkCode string) (string, error)
+}
+
+type WebhookSubscriptionRepo interface {
+	CreateSubscription(context.Context, *CreateWebhookSubscriptionPayload) (*WebhookSubscription, error)
+	GetSubscription(context.Context, uuid.UUID) (*WebhookSubscription, error)
+	GetSubscriptions(context.Context) ([]*WebhookSubscription, error)
+	GetSubscriptionsByEventType(context.Context, string) ([]*WebhookSubscription, error)
+}
+
+// WebhookDeadLetterRepo keeps the deliveries that exhausted their attempts.
+// TakeDeadLetter removes the delivery it returns, so that a delivery is
+// replayed at most once.
+type WebhookDeadLetterRepo interface {
+	PutDeadLetter(context.Context, *WebhookDelivery) error
+	GetDeadLetters(context.Context) ([]*WebhookDelivery, error)
+	TakeDeadLetter(context.Context, uuid.UUID) (*WebhookDelivery, error)
+}
+
+type APIKeyRepo interface {
+	CreateAPIKey(context.Context, *CreateAPIKeyPayload) (*APIKey, error)
+	GetAPIKey(context.Context, uuid.UUID) (*APIKey, error)
+	GetAPIKeys(context.Context) ([]*APIKey, error)
+	RotateAPIKey(ctx context.Context, keyID uuid.UUID, secretHash []byte, signingKey []byte) (*APIKey, error)
+	RevokeAPIKey(context.Context, uuid.UUID) (*APIKey, error)
 }
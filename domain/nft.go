@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+)
+
+type NFTStandard string
+
+const (
+	NFTStandardERC721  NFTStandard = "ERC721"
+	NFTStandardERC1155 NFTStandard = "ERC1155"
+)
+
+func ParseNFTStandard(value string) (NFTStandard, error) {
+	switch standard := NFTStandard(value); standard {
+	case NFTStandardERC721, NFTStandardERC1155:
+		return standard, nil
+	}
+
+	return "", fmt.Errorf("invalid NFT standard: %s", value)
+}
+
+// NFT is one token of a non-fungible (ERC-721) or multi-token (ERC-1155)
+// contract. Unlike a NetworkCurrency it has no scale: tokens are counted in
+// whole units, and an ERC-721 token exists once.
+type NFT struct {
+	NetworkCode string
+	Contract    string
+	Standard    NFTStandard
+	TokenID     *big.Int
+}
+
+func (nft *NFT) String() string {
+	return fmt.Sprintf("%s token %s of %s", nft.Standard, nft.TokenID, nft.Contract)
+}
@@ -14,6 +14,14 @@ func (e WalletNotFoundError) Error() string {
 	return fmt.Sprintf("wallet %s not found", e.WalletID)
 }
 
+type WalletAlreadyExistsError struct {
+	WalletID uuid.UUID
+}
+
+func (e WalletAlreadyExistsError) Error() string {
+	return fmt.Sprintf("wallet %s already exists", e.WalletID)
+}
+
 type Wallet struct {
 	ID               uuid.UUID `json:"id"`
 	ProviderID       string    `json:"provider_id"`
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookSubscriptionNotFoundError struct {
+	SubscriptionID uuid.UUID
+}
+
+func (e WebhookSubscriptionNotFoundError) Error() string {
+	return fmt.Sprintf("webhook subscription %s not found", e.SubscriptionID)
+}
+
+type WebhookDeliveryNotFoundError struct {
+	DeliveryID uuid.UUID
+}
+
+func (e WebhookDeliveryNotFoundError) Error() string {
+	return fmt.Sprintf("dead-lettered delivery %s not found", e.DeliveryID)
+}
+
+type WebhookSubscription struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (s *WebhookSubscription) Accepts(eventType string) bool {
+	for _, et := range s.EventTypes {
+		if et == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type CreateWebhookSubscriptionPayload struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+// WebhookDelivery is one event sent to one subscription, kept once its
+// attempts are exhausted so that it can be replayed.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventType      string          `json:"event_type"`
+	Body           json.RawMessage `json:"body"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeadLetteredAt *time.Time      `json:"dead_lettered_at,omitempty"`
+}
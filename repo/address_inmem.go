@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"github.com/google/uuid"
@@ -11,25 +12,64 @@ import (
 
 var _ domain.AddressRepo = (*AddressRepo)(nil)
 
+type addressKey struct {
+	networkCode string
+	address     string
+}
+
+// AddressRepo keeps addresses in their normalized form, indexed by network and
+// address and by wallet, so lookups do not scan every address. Like a foreign
+// key, it only accepts addresses of the wallets that wallets holds.
 type AddressRepo struct {
-	storage sync.Map
+	wallets   domain.WalletRepo
+	mutex     sync.RWMutex
+	byValue   map[addressKey]*domain.Address
+	byWallet  map[uuid.UUID][]*domain.Address
+	byNetwork map[string][]*domain.Address
 }
 
-func NewAddressRepo() *AddressRepo {
+func NewAddressRepo(wallets domain.WalletRepo) *AddressRepo {
 	return &AddressRepo{
-		storage: sync.Map{},
+		wallets:   wallets,
+		byValue:   make(map[addressKey]*domain.Address),
+		byWallet:  make(map[uuid.UUID][]*domain.Address),
+		byNetwork: make(map[string][]*domain.Address),
 	}
 }
 
-func (repo *AddressRepo) CreateAddress(_ context.Context, cdp *domain.CreateAddressPayload) (*domain.Address, error) {
+func (repo *AddressRepo) CreateAddress(ctx context.Context, cdp *domain.CreateAddressPayload) (*domain.Address, error) {
+	normalized, err := domain.NormalizeAddress(cdp.NetworkCode, cdp.Address)
+	if err != nil {
+
+		return nil, err
+	}
+
+	// wallets are never deleted, so the wallet cannot go away before the
+	// address is stored
+	if _, err := repo.wallets.GetWallet(ctx, cdp.WalletID); err != nil {
+
+		return nil, err
+	}
+
+	key := addressKey{networkCode: cdp.NetworkCode, address: normalized}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, ok := repo.byValue[key]; ok {
+		return nil, domain.AddressAlreadyExistsError{Address: normalized, NetworkCode: cdp.NetworkCode}
+	}
+
 	address := &domain.Address{
 		ID:          uuid.Must(uuid.NewV7()),
-		Address:     cdp.Address,
+		Address:     normalized,
 		NetworkCode: cdp.NetworkCode,
 		WalletID:    cdp.WalletID,
 	}
 
-	repo.storage.Store(address.ID, address)
+	repo.byValue[key] = address
+	repo.byWallet[address.WalletID] = append(repo.byWallet[address.WalletID], address)
+	repo.byNetwork[address.NetworkCode] = append(repo.byNetwork[address.NetworkCode], address)
 
 	return address, nil
 }
@@ -39,48 +79,55 @@ func (repo *AddressRepo) GetAddressByValue(
 	address string,
 	networkCode string,
 ) (*domain.Address, error) {
-	var addrTyped *domain.Address
-
-	repo.storage.Range(func(_, value interface{}) bool {
-		addr, ok := value.(*domain.Address)
-		if !ok {
-			return false
-		}
-
-		if addr.Address == address && addr.NetworkCode == networkCode {
-			addrTyped = addr
+	normalized, err := domain.NormalizeAddress(networkCode, address)
+	if err != nil {
+		return nil, domain.AddressNotFoundError{Address: address}
+	}
 
-			return false
-		}
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
 
-		return true
-	})
-
-	if addrTyped == nil {
+	addr, ok := repo.byValue[addressKey{networkCode: networkCode, address: normalized}]
+	if !ok {
 		return nil, domain.AddressNotFoundError{Address: address}
 	}
 
-	return addrTyped, nil
+	return addr, nil
 }
 
 func (repo *AddressRepo) GetAddressesByNetwork(
 	_ context.Context,
 	networkCode string,
 ) ([]*domain.Address, error) {
-	var addresses []*domain.Address
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	return sortedAddresses(repo.byNetwork[networkCode]), nil
+}
 
-	repo.storage.Range(func(_, value interface{}) bool {
-		addr, ok := value.(*domain.Address)
-		if !ok {
-			return false
-		}
+func (repo *AddressRepo) GetAddressesByWallet(
+	_ context.Context,
+	walletID uuid.UUID,
+) ([]*domain.Address, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	return sortedAddresses(repo.byWallet[walletID]), nil
+}
+
+// sortedAddresses copies the index entries in creation order, which UUIDv7
+// IDs preserve, so callers cannot modify the index.
+func sortedAddresses(indexed []*domain.Address) []*domain.Address {
+	if len(indexed) == 0 {
+		return nil
+	}
 
-		if addr.NetworkCode == networkCode {
-			addresses = append(addresses, addr)
-		}
+	addresses := make([]*domain.Address, len(indexed))
+	copy(addresses, indexed)
 
-		return true
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].ID.String() < addresses[j].ID.String()
 	})
 
-	return addresses, nil
+	return addresses
 }
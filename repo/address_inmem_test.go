@@ -0,0 +1,17 @@
+package repo_test
+
+import (
+	"testing"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/repo"
+	"github.com/ivxivx/demo-blockchain/repo/repotest"
+)
+
+func TestInMemoryRepos(t *testing.T) {
+	repotest.Run(t, func(*testing.T) (domain.WalletRepo, domain.AddressRepo) {
+		walletRepo := repo.NewWalletRepo()
+
+		return walletRepo, repo.NewAddressRepo(walletRepo)
+	})
+}
@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+var _ domain.APIKeyRepo = (*APIKeyRepo)(nil)
+
+// APIKeyRepo keeps API keys in memory. Keys are handed out as copies, since
+// rotation and revocation change them after creation.
+type APIKeyRepo struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]*domain.APIKey
+}
+
+func NewAPIKeyRepo() *APIKeyRepo {
+	return &APIKeyRepo{
+		keys: make(map[uuid.UUID]*domain.APIKey),
+	}
+}
+
+func (repo *APIKeyRepo) CreateAPIKey(_ context.Context, cakp *domain.CreateAPIKeyPayload) (*domain.APIKey, error) {
+	keyID := cakp.ID
+	if keyID == uuid.Nil {
+		keyID = uuid.Must(uuid.NewV7())
+	}
+
+	key := &domain.APIKey{
+		ID:           keyID,
+		Name:         cakp.Name,
+		SecretHash:   slices.Clone(cakp.SecretHash),
+		SigningKey:   slices.Clone(cakp.SigningKey),
+		Scopes:       slices.Clone(cakp.Scopes),
+		WalletIDs:    slices.Clone(cakp.WalletIDs),
+		NetworkCodes: slices.Clone(cakp.NetworkCodes),
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.keys[key.ID] = key
+
+	return copyAPIKey(key), nil
+}
+
+func (repo *APIKeyRepo) GetAPIKey(_ context.Context, keyID uuid.UUID) (*domain.APIKey, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	key, ok := repo.keys[keyID]
+	if !ok {
+		return nil, domain.APIKeyNotFoundError{KeyID: keyID}
+	}
+
+	return copyAPIKey(key), nil
+}
+
+func (repo *APIKeyRepo) GetAPIKeys(_ context.Context) ([]*domain.APIKey, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	keys := make([]*domain.APIKey, 0, len(repo.keys))
+	for _, key := range repo.keys {
+		keys = append(keys, copyAPIKey(key))
+	}
+
+	// UUIDv7 IDs are time ordered, so sorting by ID keeps creation order.
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID.String() < keys[j].ID.String()
+	})
+
+	return keys, nil
+}
+
+func (repo *APIKeyRepo) RotateAPIKey(
+	_ context.Context,
+	keyID uuid.UUID,
+	secretHash []byte,
+	signingKey []byte,
+) (*domain.APIKey, error) {
+	return repo.update(keyID, func(key *domain.APIKey, now time.Time) {
+		key.SecretHash = slices.Clone(secretHash)
+		key.SigningKey = slices.Clone(signingKey)
+		key.RotatedAt = &now
+	})
+}
+
+func (repo *APIKeyRepo) RevokeAPIKey(_ context.Context, keyID uuid.UUID) (*domain.APIKey, error) {
+	return repo.update(keyID, func(key *domain.APIKey, now time.Time) {
+		if key.RevokedAt == nil {
+			key.RevokedAt = &now
+		}
+	})
+}
+
+func (repo *APIKeyRepo) update(keyID uuid.UUID, change func(*domain.APIKey, time.Time)) (*domain.APIKey, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key, ok := repo.keys[keyID]
+	if !ok {
+		return nil, domain.APIKeyNotFoundError{KeyID: keyID}
+	}
+
+	change(key, time.Now().UTC())
+
+	return copyAPIKey(key), nil
+}
+
+func copyAPIKey(key *domain.APIKey) *domain.APIKey {
+	copied := *key
+	copied.SecretHash = slices.Clone(key.SecretHash)
+	copied.SigningKey = slices.Clone(key.SigningKey)
+	copied.Scopes = slices.Clone(key.Scopes)
+	copied.WalletIDs = slices.Clone(key.WalletIDs)
+	copied.NetworkCodes = slices.Clone(key.NetworkCodes)
+
+	return &copied
+}
@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ivxivx/demo-blockchain/audit"
+)
+
+const (
+	auditDirPerm  = 0o700
+	auditFilePerm = 0o600
+)
+
+var _ audit.Store = (*FileAuditStore)(nil)
+
+// FileAuditStore appends entries as JSON lines to a single file, which is
+// synced after every entry. The file doubles as the JSON Lines export.
+type FileAuditStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+func NewFileAuditStore(path string) (*FileAuditStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), auditDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory (%s): %w", filepath.Dir(path), err)
+	}
+
+	return &FileAuditStore{
+		path: path,
+	}, nil
+}
+
+func (store *FileAuditStore) Append(_ context.Context, entry *audit.Entry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshall audit entry: %w", err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	file, err := os.OpenFile(store.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, auditFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log (%s): %w", store.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(content, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+
+	return file.Close()
+}
+
+func (store *FileAuditStore) Last(ctx context.Context) (*audit.Entry, error) {
+	var last *audit.Entry
+
+	err := store.Walk(ctx, func(entry *audit.Entry) error {
+		last = entry
+
+		return nil
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	return last, nil
+}
+
+func (store *FileAuditStore) Walk(_ context.Context, fn func(*audit.Entry) error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	file, err := os.Open(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open audit log (%s): %w", store.path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, bufio.MaxScanTokenSize*16)
+
+	for line := 1; scanner.Scan(); line++ {
+		var entry audit.Entry
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("failed to parse audit log line %d: %w", line, err)
+		}
+
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log (%s): %w", store.path, err)
+	}
+
+	return nil
+}
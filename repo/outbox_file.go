@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+const (
+	outboxFileExt  = ".json"
+	outboxDirPerm  = 0o700
+	outboxFilePerm = 0o600
+)
+
+var _ transaction.Outbox = (*FileOutbox)(nil)
+
+// FileOutbox keeps one JSON file per entry in a directory. Writes go to a
+// temporary file that is synced and then renamed, so an entry is either fully
+// written or absent after a crash.
+type FileOutbox struct {
+	dir string
+}
+
+func NewFileOutbox(dir string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, outboxDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory (%s): %w", dir, err)
+	}
+
+	return &FileOutbox{
+		dir: dir,
+	}, nil
+}
+
+func (outbox *FileOutbox) Put(_ context.Context, entry *transaction.OutboxEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshall outbox entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(outbox.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create outbox entry: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to sync outbox entry: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close outbox entry: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), outboxFilePerm); err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), outbox.path(entry.Payload.ID)); err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+
+	return outbox.syncDir()
+}
+
+func (outbox *FileOutbox) Remove(_ context.Context, id string) error {
+	err := os.Remove(outbox.path(id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove outbox entry (%s): %w", id, err)
+	}
+
+	return outbox.syncDir()
+}
+
+func (outbox *FileOutbox) List(_ context.Context) ([]*transaction.OutboxEntry, error) {
+	files, err := os.ReadDir(outbox.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox directory (%s): %w", outbox.dir, err)
+	}
+
+	entries := make([]*transaction.OutboxEntry, 0, len(files))
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), outboxFileExt) {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(outbox.dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read outbox entry (%s): %w", file.Name(), err)
+		}
+
+		var entry transaction.OutboxEntry
+
+		if err := json.Unmarshal(content, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse outbox entry (%s): %w", file.Name(), err)
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+
+	return entries, nil
+}
+
+func (outbox *FileOutbox) path(id string) string {
+	return filepath.Join(outbox.dir, filepath.Base(id)+outboxFileExt)
+}
+
+// syncDir makes renames and removals in the directory durable.
+func (outbox *FileOutbox) syncDir() error {
+	dir, err := os.Open(outbox.dir)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox directory (%s): %w", outbox.dir, err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync outbox directory (%s): %w", outbox.dir, err)
+	}
+
+	return nil
+}
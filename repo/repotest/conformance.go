@@ -0,0 +1,359 @@
+// Package repotest holds the conformance suite that every implementation of
+// the domain repositories must pass, whatever its storage.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const (
+	networkA = domain.TestEth
+	networkB = "NetworkB"
+
+	addressA1 = "0x04d4f8BDfC79f9fb1B92c9cd702040E6A4BD14B7"
+	addressA2 = "0x7947bF7E54d5692C0B615512A228e3c1580D7420"
+	addressB1 = "0x9E5c1c3C5C8E7E1fB8C6d0E4A0B2d6A5E9F0a1B2"
+)
+
+// Factory returns empty repositories backed by the same storage, so that
+// addresses can reference wallets.
+type Factory func(t *testing.T) (domain.WalletRepo, domain.AddressRepo)
+
+func Run(t *testing.T, factory Factory) {
+	t.Helper()
+
+	t.Run("WalletRoundTrip", func(t *testing.T) { testWalletRoundTrip(t, factory) })
+	t.Run("WalletGeneratedID", func(t *testing.T) { testWalletGeneratedID(t, factory) })
+	t.Run("WalletNotFound", func(t *testing.T) { testWalletNotFound(t, factory) })
+	t.Run("WalletDuplicate", func(t *testing.T) { testWalletDuplicate(t, factory) })
+	t.Run("Wallets", func(t *testing.T) { testWallets(t, factory) })
+	t.Run("AddressByValue", func(t *testing.T) { testAddressByValue(t, factory) })
+	t.Run("AddressNotFound", func(t *testing.T) { testAddressNotFound(t, factory) })
+	t.Run("AddressesByNetwork", func(t *testing.T) { testAddressesByNetwork(t, factory) })
+	t.Run("AddressesByWallet", func(t *testing.T) { testAddressesByWallet(t, factory) })
+	t.Run("AddressNormalized", func(t *testing.T) { testAddressNormalized(t, factory) })
+	t.Run("AddressDuplicate", func(t *testing.T) { testAddressDuplicate(t, factory) })
+	t.Run("AddressUnknownWallet", func(t *testing.T) { testAddressUnknownWallet(t, factory) })
+}
+
+func testWalletRoundTrip(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	walletRepo, _ := factory(t)
+
+	walletID := uuid.Must(uuid.NewV7())
+
+	created, err := walletRepo.CreateWallet(ctx, &domain.CreateWalletPayload{ID: walletID, ProviderID: "Local"})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	if created.ID != walletID || created.ProviderID != "Local" {
+		t.Fatalf("CreateWallet returned %+v", created)
+	}
+
+	loaded, err := walletRepo.GetWallet(ctx, walletID)
+	if err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+
+	if *loaded != *created {
+		t.Fatalf("GetWallet returned %+v, want %+v", loaded, created)
+	}
+}
+
+func testWalletGeneratedID(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	walletRepo, _ := factory(t)
+
+	created, err := walletRepo.CreateWallet(ctx, &domain.CreateWalletPayload{ProviderID: "Local"})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	if created.ID == uuid.Nil {
+		t.Fatal("CreateWallet did not generate an ID")
+	}
+
+	if _, err := walletRepo.GetWallet(ctx, created.ID); err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+}
+
+func testWalletNotFound(t *testing.T, factory Factory) {
+	walletRepo, _ := factory(t)
+
+	walletID := uuid.Must(uuid.NewV7())
+
+	_, err := walletRepo.GetWallet(context.Background(), walletID)
+
+	var notFoundErr domain.WalletNotFoundError
+	if !errors.As(err, &notFoundErr) || notFoundErr.WalletID != walletID {
+		t.Fatalf("GetWallet returned %v, want WalletNotFoundError", err)
+	}
+}
+
+func testWalletDuplicate(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	walletRepo, _ := factory(t)
+
+	wallet := createWallet(t, walletRepo)
+
+	_, err := walletRepo.CreateWallet(ctx, &domain.CreateWalletPayload{ID: wallet.ID, ProviderID: "Other"})
+
+	var existsErr domain.WalletAlreadyExistsError
+	if !errors.As(err, &existsErr) || existsErr.WalletID != wallet.ID {
+		t.Fatalf("CreateWallet returned %v, want WalletAlreadyExistsError", err)
+	}
+
+	// the existing wallet is kept as it was
+	loaded, err := walletRepo.GetWallet(ctx, wallet.ID)
+	if err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+
+	if *loaded != *wallet {
+		t.Fatalf("GetWallet returned %+v, want %+v", loaded, wallet)
+	}
+}
+
+func testWallets(t *testing.T, factory Factory) {
+	walletRepo, _ := factory(t)
+
+	empty, err := walletRepo.GetWallets(context.Background())
+	if err != nil {
+		t.Fatalf("GetWallets: %v", err)
+	}
+
+	if len(empty) != 0 {
+		t.Fatalf("GetWallets returned %d wallets for an empty repo", len(empty))
+	}
+
+	first := createWallet(t, walletRepo)
+	second := createWallet(t, walletRepo)
+
+	wallets, err := walletRepo.GetWallets(context.Background())
+	if err != nil {
+		t.Fatalf("GetWallets: %v", err)
+	}
+
+	if len(wallets) != 2 || wallets[0].ID != first.ID || wallets[1].ID != second.ID {
+		t.Fatalf("GetWallets returned %v, want wallets in creation order", wallets)
+	}
+}
+
+func testAddressByValue(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	walletRepo, addressRepo := factory(t)
+
+	wallet := createWallet(t, walletRepo)
+
+	created, err := addressRepo.CreateAddress(ctx, &domain.CreateAddressPayload{
+		Address:     addressA1,
+		NetworkCode: networkA,
+		WalletID:    wallet.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateAddress: %v", err)
+	}
+
+	if created.ID == uuid.Nil || created.WalletID != wallet.ID {
+		t.Fatalf("CreateAddress returned %+v", created)
+	}
+
+	loaded, err := addressRepo.GetAddressByValue(ctx, addressA1, networkA)
+	if err != nil {
+		t.Fatalf("GetAddressByValue: %v", err)
+	}
+
+	if *loaded != *created {
+		t.Fatalf("GetAddressByValue returned %+v, want %+v", loaded, created)
+	}
+}
+
+func testAddressNotFound(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	walletRepo, addressRepo := factory(t)
+
+	wallet := createWallet(t, walletRepo)
+	createAddress(t, addressRepo, wallet.ID, addressA1, networkA)
+
+	// the same address on another network is a different address
+	_, err := addressRepo.GetAddressByValue(ctx, addressA1, networkB)
+
+	var notFoundErr domain.AddressNotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Fatalf("GetAddressByValue returned %v, want AddressNotFoundError", err)
+	}
+}
+
+func testAddressesByNetwork(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	walletRepo, addressRepo := factory(t)
+
+	wallet := createWallet(t, walletRepo)
+	createAddress(t, addressRepo, wallet.ID, addressA1, networkA)
+	createAddress(t, addressRepo, wallet.ID, addressA2, networkA)
+	createAddress(t, addressRepo, wallet.ID, addressB1, networkB)
+
+	addresses, err := addressRepo.GetAddressesByNetwork(ctx, networkA)
+	if err != nil {
+		t.Fatalf("GetAddressesByNetwork: %v", err)
+	}
+
+	values := make([]string, len(addresses))
+	for index, address := range addresses {
+		values[index] = address.Address
+	}
+
+	sort.Strings(values)
+
+	if len(values) != 2 || values[0] != addressA1 || values[1] != addressA2 {
+		t.Fatalf("GetAddressesByNetwork returned %v", values)
+	}
+
+	empty, err := addressRepo.GetAddressesByNetwork(ctx, "Unknown")
+	if err != nil {
+		t.Fatalf("GetAddressesByNetwork: %v", err)
+	}
+
+	if len(empty) != 0 {
+		t.Fatalf("GetAddressesByNetwork returned %d addresses for an unknown network", len(empty))
+	}
+}
+
+func testAddressesByWallet(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	walletRepo, addressRepo := factory(t)
+
+	wallet := createWallet(t, walletRepo)
+	other := createWallet(t, walletRepo)
+
+	first := createAddress(t, addressRepo, wallet.ID, addressA1, networkA)
+	second := createAddress(t, addressRepo, wallet.ID, addressB1, networkB)
+	createAddress(t, addressRepo, other.ID, addressA2, networkA)
+
+	addresses, err := addressRepo.GetAddressesByWallet(ctx, wallet.ID)
+	if err != nil {
+		t.Fatalf("GetAddressesByWallet: %v", err)
+	}
+
+	if len(addresses) != 2 || addresses[0].ID != first.ID || addresses[1].ID != second.ID {
+		t.Fatalf("GetAddressesByWallet returned %v, want addresses in creation order", addresses)
+	}
+
+	empty, err := addressRepo.GetAddressesByWallet(ctx, uuid.Must(uuid.NewV7()))
+	if err != nil {
+		t.Fatalf("GetAddressesByWallet: %v", err)
+	}
+
+	if len(empty) != 0 {
+		t.Fatalf("GetAddressesByWallet returned %d addresses for an unknown wallet", len(empty))
+	}
+}
+
+func testAddressNormalized(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	walletRepo, addressRepo := factory(t)
+
+	wallet := createWallet(t, walletRepo)
+
+	created := createAddress(t, addressRepo, wallet.ID, strings.ToLower(addressA1), networkA)
+	if created.Address != addressA1 {
+		t.Fatalf("CreateAddress stored %s, want checksummed %s", created.Address, addressA1)
+	}
+
+	for _, value := range []string{addressA1, strings.ToLower(addressA1), "0x" + strings.ToUpper(addressA1[2:])} {
+		loaded, err := addressRepo.GetAddressByValue(ctx, value, networkA)
+		if err != nil {
+			t.Fatalf("GetAddressByValue(%s): %v", value, err)
+		}
+
+		if loaded.ID != created.ID {
+			t.Fatalf("GetAddressByValue(%s) returned %+v, want %+v", value, loaded, created)
+		}
+	}
+}
+
+func testAddressDuplicate(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	walletRepo, addressRepo := factory(t)
+
+	wallet := createWallet(t, walletRepo)
+	createAddress(t, addressRepo, wallet.ID, addressA1, networkA)
+
+	_, err := addressRepo.CreateAddress(ctx, &domain.CreateAddressPayload{
+		Address:     strings.ToLower(addressA1),
+		NetworkCode: networkA,
+		WalletID:    wallet.ID,
+	})
+
+	var existsErr domain.AddressAlreadyExistsError
+	if !errors.As(err, &existsErr) {
+		t.Fatalf("CreateAddress returned %v, want AddressAlreadyExistsError", err)
+	}
+
+	// the same address on another network is not a duplicate
+	createAddress(t, addressRepo, wallet.ID, addressA1, networkB)
+}
+
+func testAddressUnknownWallet(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	_, addressRepo := factory(t)
+
+	walletID := uuid.Must(uuid.NewV7())
+
+	_, err := addressRepo.CreateAddress(ctx, &domain.CreateAddressPayload{
+		Address:     addressA1,
+		NetworkCode: networkA,
+		WalletID:    walletID,
+	})
+
+	var notFoundErr domain.WalletNotFoundError
+	if !errors.As(err, &notFoundErr) || notFoundErr.WalletID != walletID {
+		t.Fatalf("CreateAddress returned %v, want WalletNotFoundError", err)
+	}
+
+	if _, err := addressRepo.GetAddressByValue(ctx, addressA1, networkA); err == nil {
+		t.Fatal("GetAddressByValue found the address of an unknown wallet")
+	}
+}
+
+func createWallet(t *testing.T, walletRepo domain.WalletRepo) *domain.Wallet {
+	t.Helper()
+
+	wallet, err := walletRepo.CreateWallet(context.Background(), &domain.CreateWalletPayload{ProviderID: "Local"})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	return wallet
+}
+
+func createAddress(
+	t *testing.T,
+	addressRepo domain.AddressRepo,
+	walletID uuid.UUID,
+	value string,
+	networkCode string,
+) *domain.Address {
+	t.Helper()
+
+	address, err := addressRepo.CreateAddress(context.Background(), &domain.CreateAddressPayload{
+		Address:     value,
+		NetworkCode: networkCode,
+		WalletID:    walletID,
+	})
+	if err != nil {
+		t.Fatalf("CreateAddress: %v", err)
+	}
+
+	return address
+}
@@ -0,0 +1,246 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const selectAddress = "SELECT id, address, network_code, wallet_id FROM addresses"
+
+var _ domain.AddressRepo = (*AddressRepo)(nil)
+
+type AddressRepo struct {
+	db *DB
+}
+
+func NewAddressRepo(db *DB) *AddressRepo {
+	return &AddressRepo{
+		db: db,
+	}
+}
+
+func (repo *AddressRepo) CreateAddress(
+	ctx context.Context,
+	cdp *domain.CreateAddressPayload,
+) (*domain.Address, error) {
+	var address *domain.Address
+
+	err := repo.db.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		address, err = insertAddress(ctx, tx, cdp)
+
+		return err
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	return address, nil
+}
+
+func (repo *AddressRepo) GetAddressByValue(
+	ctx context.Context,
+	address string,
+	networkCode string,
+) (*domain.Address, error) {
+	normalized, err := domain.NormalizeAddress(networkCode, address)
+	if err != nil {
+		return nil, domain.AddressNotFoundError{Address: address}
+	}
+
+	row := repo.db.QueryRowContext(ctx, selectAddress+" WHERE network_code = ? AND address = ?",
+		networkCode, normalized)
+
+	addr, err := scanAddress(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.AddressNotFoundError{Address: address}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve address (%s): %w", address, err)
+	}
+
+	return addr, nil
+}
+
+func (repo *AddressRepo) GetAddressesByNetwork(
+	ctx context.Context,
+	networkCode string,
+) ([]*domain.Address, error) {
+	addresses, err := repo.query(ctx, selectAddress+" WHERE network_code = ? ORDER BY id", networkCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve addresses for network (%s): %w", networkCode, err)
+	}
+
+	return addresses, nil
+}
+
+func (repo *AddressRepo) GetAddressesByWallet(
+	ctx context.Context,
+	walletID uuid.UUID,
+) ([]*domain.Address, error) {
+	addresses, err := repo.query(ctx, selectAddress+" WHERE wallet_id = ? ORDER BY id", walletID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve addresses for wallet (%s): %w", walletID, err)
+	}
+
+	return addresses, nil
+}
+
+func (repo *AddressRepo) query(ctx context.Context, query string, args ...any) ([]*domain.Address, error) {
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []*domain.Address
+
+	for rows.Next() {
+		addr, err := scanAddress(rows)
+		if err != nil {
+
+			return nil, err
+		}
+
+		addresses = append(addresses, addr)
+	}
+
+	return addresses, rows.Err()
+}
+
+func insertAddress(ctx context.Context, tx *sql.Tx, cdp *domain.CreateAddressPayload) (*domain.Address, error) {
+	normalized, err := domain.NormalizeAddress(cdp.NetworkCode, cdp.Address)
+	if err != nil {
+
+		return nil, err
+	}
+
+	var existing int
+
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM addresses WHERE network_code = ? AND address = ?",
+		cdp.NetworkCode, normalized).Scan(&existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check address (%s): %w", normalized, err)
+	}
+
+	if existing > 0 {
+		return nil, domain.AddressAlreadyExistsError{Address: normalized, NetworkCode: cdp.NetworkCode}
+	}
+
+	// reported like the in-memory repository rather than as a failed
+	// foreign key
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM wallets WHERE id = ?", cdp.WalletID.String()).
+		Scan(&existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check wallet (%s): %w", cdp.WalletID, err)
+	}
+
+	if existing == 0 {
+		return nil, domain.WalletNotFoundError{WalletID: cdp.WalletID}
+	}
+
+	address := &domain.Address{
+		ID:          uuid.Must(uuid.NewV7()),
+		Address:     normalized,
+		NetworkCode: cdp.NetworkCode,
+		WalletID:    cdp.WalletID,
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO addresses (id, address, network_code, wallet_id) VALUES (?, ?, ?, ?)",
+		address.ID.String(), address.Address, address.NetworkCode, address.WalletID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address (%s): %w", address.Address, err)
+	}
+
+	return address, nil
+}
+
+// normalizeAddresses rewrites the addresses stored before they were normalized
+// on insert. Two rows that turn out to hold the same address fail the
+// migration, as only an operator can tell which wallet owns it. Addresses that
+// do not parse are left as they are.
+func normalizeAddresses(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, selectAddress+" ORDER BY id")
+	if err != nil {
+
+		return err
+	}
+
+	var addresses []*domain.Address
+
+	for rows.Next() {
+		addr, err := scanAddress(rows)
+		if err != nil {
+			rows.Close()
+
+			return err
+		}
+
+		addresses = append(addresses, addr)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	normalized := make(map[string]string, len(addresses))
+	owners := make(map[[2]string]*domain.Address, len(addresses))
+
+	for _, addr := range addresses {
+		value, err := domain.NormalizeAddress(addr.NetworkCode, addr.Address)
+		if err != nil {
+			value = addr.Address
+		}
+
+		key := [2]string{addr.NetworkCode, value}
+		if owner, ok := owners[key]; ok {
+			return fmt.Errorf("addresses %s (%s) and %s (%s) are the same address on network %s",
+				owner.ID, owner.Address, addr.ID, addr.Address, addr.NetworkCode)
+		}
+
+		owners[key] = addr
+		normalized[addr.ID.String()] = value
+	}
+
+	for _, addr := range addresses {
+		value := normalized[addr.ID.String()]
+		if value == addr.Address {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, "UPDATE addresses SET address = ? WHERE id = ?", value, addr.ID.String())
+		if err != nil {
+			return fmt.Errorf("failed to normalize address (%s): %w", addr.Address, err)
+		}
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAddress(row scanner) (*domain.Address, error) {
+	address := &domain.Address{}
+
+	err := row.Scan(&address.ID, &address.Address, &address.NetworkCode, &address.WalletID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return address, nil
+}
@@ -0,0 +1,23 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/repo/repotest"
+	"github.com/ivxivx/demo-blockchain/repo/sqlite"
+)
+
+func TestSQLiteRepos(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) (domain.WalletRepo, domain.AddressRepo) {
+		db, err := sqlite.Open(context.Background(), ":memory:")
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+
+		t.Cleanup(func() { db.Close() })
+
+		return sqlite.NewWalletRepo(db), sqlite.NewAddressRepo(db)
+	})
+}
@@ -0,0 +1,216 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const selectAPIKey = `SELECT id, name, secret_hash, signing_key, scopes, wallet_ids, network_codes,
+	created_at, rotated_at, revoked_at FROM api_keys`
+
+var _ domain.APIKeyRepo = (*APIKeyRepo)(nil)
+
+type APIKeyRepo struct {
+	db *DB
+}
+
+func NewAPIKeyRepo(db *DB) *APIKeyRepo {
+	return &APIKeyRepo{
+		db: db,
+	}
+}
+
+func (repo *APIKeyRepo) CreateAPIKey(ctx context.Context, cakp *domain.CreateAPIKeyPayload) (*domain.APIKey, error) {
+	keyID := cakp.ID
+	if keyID == uuid.Nil {
+		keyID = uuid.Must(uuid.NewV7())
+	}
+
+	key := &domain.APIKey{
+		ID:           keyID,
+		Name:         cakp.Name,
+		SecretHash:   cakp.SecretHash,
+		SigningKey:   cakp.SigningKey,
+		Scopes:       cakp.Scopes,
+		WalletIDs:    cakp.WalletIDs,
+		NetworkCodes: cakp.NetworkCodes,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	scopes, walletIDs, networkCodes, err := marshalRestrictions(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key (%s): %w", key.ID, err)
+	}
+
+	_, err = repo.db.ExecContext(ctx,
+		`INSERT INTO api_keys (id, name, secret_hash, signing_key, scopes, wallet_ids, network_codes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID.String(), key.Name, key.SecretHash, key.SigningKey, scopes, walletIDs, networkCodes,
+		formatTime(key.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key (%s): %w", key.ID, err)
+	}
+
+	return key, nil
+}
+
+func (repo *APIKeyRepo) GetAPIKey(ctx context.Context, keyID uuid.UUID) (*domain.APIKey, error) {
+	key, err := scanAPIKey(repo.db.QueryRowContext(ctx, selectAPIKey+" WHERE id = ?", keyID.String()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.APIKeyNotFoundError{KeyID: keyID}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve api key (%s): %w", keyID, err)
+	}
+
+	return key, nil
+}
+
+func (repo *APIKeyRepo) GetAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	rows, err := repo.db.QueryContext(ctx, selectAPIKey+" ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*domain.APIKey
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve api keys: %w", err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (repo *APIKeyRepo) RotateAPIKey(
+	ctx context.Context,
+	keyID uuid.UUID,
+	secretHash []byte,
+	signingKey []byte,
+) (*domain.APIKey, error) {
+	return repo.update(ctx, keyID,
+		"UPDATE api_keys SET secret_hash = ?, signing_key = ?, rotated_at = ? WHERE id = ?",
+		secretHash, signingKey, formatTime(time.Now().UTC()), keyID.String())
+}
+
+func (repo *APIKeyRepo) RevokeAPIKey(ctx context.Context, keyID uuid.UUID) (*domain.APIKey, error) {
+	return repo.update(ctx, keyID, "UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?",
+		formatTime(time.Now().UTC()), keyID.String())
+}
+
+func (repo *APIKeyRepo) update(
+	ctx context.Context,
+	keyID uuid.UUID,
+	query string,
+	args ...any,
+) (*domain.APIKey, error) {
+	result, err := repo.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update api key (%s): %w", keyID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update api key (%s): %w", keyID, err)
+	}
+
+	if affected == 0 {
+		return nil, domain.APIKeyNotFoundError{KeyID: keyID}
+	}
+
+	return repo.GetAPIKey(ctx, keyID)
+}
+
+func marshalRestrictions(key *domain.APIKey) (string, string, string, error) {
+	var encoded [3]string
+
+	for index, value := range []any{key.Scopes, key.WalletIDs, key.NetworkCodes} {
+		content, err := json.Marshal(value)
+		if err != nil {
+
+			return "", "", "", err
+		}
+
+		encoded[index] = string(content)
+	}
+
+	return encoded[0], encoded[1], encoded[2], nil
+}
+
+func scanAPIKey(row scanner) (*domain.APIKey, error) {
+	key := &domain.APIKey{}
+
+	var (
+		scopes, walletIDs, networkCodes, createdAt string
+		rotatedAt, revokedAt                       sql.NullString
+	)
+
+	err := row.Scan(&key.ID, &key.Name, &key.SecretHash, &key.SigningKey, &scopes, &walletIDs, &networkCodes,
+		&createdAt, &rotatedAt, &revokedAt)
+	if err != nil {
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(walletIDs), &key.WalletIDs); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(networkCodes), &key.NetworkCodes); err != nil {
+		return nil, err
+	}
+
+	if key.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, err
+	}
+
+	if key.RotatedAt, err = parseNullTime(rotatedAt); err != nil {
+		return nil, err
+	}
+
+	if key.RevokedAt, err = parseNullTime(revokedAt); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func formatTime(value time.Time) string {
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
+func parseNullTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, value.String)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return &parsed, nil
+}
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ivxivx/demo-blockchain/audit"
+)
+
+var _ audit.Store = (*AuditStore)(nil)
+
+// AuditStore keeps each entry as its JSON encoding rather than in columns, so
+// that verification sees exactly the fields that were hashed.
+type AuditStore struct {
+	db *DB
+}
+
+func NewAuditStore(db *DB) *AuditStore {
+	return &AuditStore{
+		db: db,
+	}
+}
+
+func (store *AuditStore) Append(ctx context.Context, entry *audit.Entry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshall audit entry: %w", err)
+	}
+
+	_, err = store.db.ExecContext(ctx, "INSERT INTO audit_log (sequence, hash, entry) VALUES (?, ?, ?)",
+		entry.Sequence, entry.Hash, string(content))
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry (%d): %w", entry.Sequence, err)
+	}
+
+	return nil
+}
+
+func (store *AuditStore) Last(ctx context.Context) (*audit.Entry, error) {
+	var content string
+
+	err := store.db.QueryRowContext(ctx, "SELECT entry FROM audit_log ORDER BY sequence DESC LIMIT 1").
+		Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve last audit entry: %w", err)
+	}
+
+	entry := &audit.Entry{}
+
+	if err := json.Unmarshal([]byte(content), entry); err != nil {
+		return nil, fmt.Errorf("failed to parse audit entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+func (store *AuditStore) Walk(ctx context.Context, fn func(*audit.Entry) error) error {
+	rows, err := store.db.QueryContext(ctx, "SELECT entry FROM audit_log ORDER BY sequence")
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var content string
+
+		if err := rows.Scan(&content); err != nil {
+			return fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		entry := &audit.Entry{}
+
+		if err := json.Unmarshal([]byte(content), entry); err != nil {
+			return fmt.Errorf("failed to parse audit entry: %w", err)
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// registers the pure Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const pragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+type DB struct {
+	*sql.DB
+}
+
+// Open opens the SQLite database at path, creating it if needed, and applies
+// pending migrations. Use ":memory:" for a database that lives as long as the
+// returned DB.
+func Open(ctx context.Context, path string) (*DB, error) {
+	dsn := "file:" + path + "?" + pragmas
+
+	sqlDB, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database (%s): %w", path, err)
+	}
+
+	// SQLite serializes writers anyway, and an in-memory database only exists
+	// within its single connection.
+	sqlDB.SetMaxOpenConns(1)
+
+	db := &DB{DB: sqlDB}
+
+	if err := db.migrate(ctx); err != nil {
+		sqlDB.Close()
+
+		return nil, err
+	}
+
+	return db, nil
+}
+
+type migration struct {
+	version int
+	name    string
+}
+
+// migrationSteps run in Go after the SQL of their version, in the same
+// transaction, for changes SQL cannot express.
+var migrationSteps = map[int]func(ctx context.Context, tx *sql.Tx) error{
+	6: normalizeAddresses,
+	9: convertOutboxCreatedAt,
+}
+
+func (db *DB) migrate(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	migrations, err := listMigrations()
+	if err != nil {
+
+		return err
+	}
+
+	for _, m := range migrations {
+		if err := db.apply(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) apply(ctx context.Context, m migration) error {
+	content, err := migrationFiles.ReadFile("migrations/" + m.name)
+	if err != nil {
+		return fmt.Errorf("failed to read migration (%s): %w", m.name, err)
+	}
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		var applied int
+
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = ?", m.version).
+			Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to check migration (%s): %w", m.name, err)
+		}
+
+		if applied > 0 {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, string(content)); err != nil {
+			return fmt.Errorf("failed to apply migration (%s): %w", m.name, err)
+		}
+
+		if step, ok := migrationSteps[m.version]; ok {
+			if err := step(ctx, tx); err != nil {
+				return fmt.Errorf("failed to apply migration (%s): %w", m.name, err)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)",
+			m.version, time.Now().UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return fmt.Errorf("failed to record migration (%s): %w", m.name, err)
+		}
+
+		return nil
+	})
+}
+
+func (db *DB) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// listMigrations returns the embedded migrations ordered by the numeric
+// prefix of their file name, e.g. 0001_create_wallets_addresses.sql.
+func listMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	migrations := make([]migration, 0, len(entries))
+
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration name: %s", entry.Name())
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration name: %s", entry.Name())
+		}
+
+		migrations = append(migrations, migration{version: version, name: entry.Name()})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
@@ -0,0 +1,172 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/repo/sqlite"
+)
+
+func TestNormalizeAddressesMigration(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "demo.db")
+
+	db, err := sqlite.Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	wallet, err := sqlite.NewWalletRepo(db).CreateWallet(ctx, &domain.CreateWalletPayload{ProviderID: "Local"})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	// rows as stored before addresses were normalized on insert
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO addresses (id, address, network_code, wallet_id) VALUES
+		('018ee4c9-5161-7fa2-b280-2057331100a1', '0x04d4f8bdfc79f9fb1b92c9cd702040e6a4bd14b7', ?, ?),
+		('018ee4c9-5161-7fa2-b280-2057331100a2', ' 0X7947BF7E54D5692C0B615512A228E3C1580D7420', ?, ?)`,
+		domain.TestEth, wallet.ID.String(), domain.TestEth, wallet.ID.String())
+	if err != nil {
+		t.Fatalf("insert addresses: %v", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = 6"); err != nil {
+		t.Fatalf("reset migration: %v", err)
+	}
+
+	db.Close()
+
+	db, err = sqlite.Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	addressRepo := sqlite.NewAddressRepo(db)
+
+	for _, value := range []string{
+		"0x04d4f8BDfC79f9fb1B92c9cd702040E6A4BD14B7",
+		"0x7947bF7E54d5692C0B615512A228e3c1580D7420",
+	} {
+		address, err := addressRepo.GetAddressByValue(ctx, value, domain.TestEth)
+		if err != nil {
+			t.Fatalf("GetAddressByValue(%s): %v", value, err)
+		}
+
+		if address.Address != value {
+			t.Fatalf("GetAddressByValue(%s) returned %s", value, address.Address)
+		}
+	}
+}
+
+func TestNormalizeAddressesMigrationConflict(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "demo.db")
+
+	db, err := sqlite.Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	wallet, err := sqlite.NewWalletRepo(db).CreateWallet(ctx, &domain.CreateWalletPayload{ProviderID: "Local"})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO addresses (id, address, network_code, wallet_id) VALUES
+		('018ee4c9-5161-7fa2-b280-2057331100a1', '0x04d4f8bdfc79f9fb1b92c9cd702040e6a4bd14b7', ?, ?),
+		('018ee4c9-5161-7fa2-b280-2057331100a2', '0x04D4F8BDFC79F9FB1B92C9CD702040E6A4BD14B7', ?, ?)`,
+		domain.TestEth, wallet.ID.String(), domain.TestEth, wallet.ID.String())
+	if err != nil {
+		t.Fatalf("insert addresses: %v", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = 6"); err != nil {
+		t.Fatalf("reset migration: %v", err)
+	}
+
+	db.Close()
+
+	if db, err := sqlite.Open(ctx, path); err == nil {
+		db.Close()
+		t.Fatal("Open succeeded with two spellings of one address")
+	}
+}
+
+func TestOutboxCreatedAtMigration(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "demo.db")
+
+	db, err := sqlite.Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	// the table as it was before, with rows whose text sorts out of order
+	for _, statement := range []string{
+		"ALTER TABLE outbox DROP COLUMN created_at",
+		"ALTER TABLE outbox ADD COLUMN created_at TEXT NOT NULL DEFAULT ''",
+		`INSERT INTO outbox (scope, id, payload, created_at) VALUES
+		('local/TestEth', '0x03', '{"ID":"0x03"}', '2024-04-01T12:00:01Z'),
+		('local/TestEth', '0x02', '{"ID":"0x02"}', '2024-04-01T12:00:00.15Z'),
+		('local/TestEth', '0x01', '{"ID":"0x01"}', '2024-04-01T12:00:00.1Z')`,
+		"DELETE FROM schema_migrations WHERE version = 9",
+	} {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+
+	db.Close()
+
+	db, err = sqlite.Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	outbox := sqlite.NewOutbox(db, "local/TestEth")
+
+	// a whole second sorted after its fractions as text
+	err = outbox.Put(ctx, &transaction.OutboxEntry{
+		Payload:   &transaction.TransferPayload{ID: "0x04"},
+		CreatedAt: time.Date(2024, 4, 1, 12, 0, 2, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	err = outbox.Put(ctx, &transaction.OutboxEntry{
+		Payload:   &transaction.TransferPayload{ID: "0x05"},
+		CreatedAt: time.Date(2024, 4, 1, 12, 0, 2, 500_000_000, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	entries, err := outbox.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	ids := make([]string, len(entries))
+	for index, entry := range entries {
+		ids[index] = entry.Payload.ID
+	}
+
+	if strings.Join(ids, ",") != "0x01,0x02,0x03,0x04,0x05" {
+		t.Fatalf("List returned %v, want entries in creation order", ids)
+	}
+
+	want := time.Date(2024, 4, 1, 12, 0, 0, 150_000_000, time.UTC)
+	if !entries[1].CreatedAt.Equal(want) {
+		t.Fatalf("List returned created at %s, want %s", entries[1].CreatedAt, want)
+	}
+}
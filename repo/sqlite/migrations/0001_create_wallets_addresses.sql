@@ -0,0 +1,15 @@
+CREATE TABLE wallets (
+    id          TEXT PRIMARY KEY,
+    provider_id TEXT NOT NULL
+);
+
+CREATE TABLE addresses (
+    id           TEXT PRIMARY KEY,
+    address      TEXT NOT NULL,
+    network_code TEXT NOT NULL,
+    wallet_id    TEXT NOT NULL REFERENCES wallets (id)
+);
+
+CREATE UNIQUE INDEX addresses_network_code_address ON addresses (network_code, address);
+
+CREATE INDEX addresses_wallet_id ON addresses (wallet_id);
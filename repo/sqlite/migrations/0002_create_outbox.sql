@@ -0,0 +1,7 @@
+CREATE TABLE outbox (
+    scope      TEXT NOT NULL,
+    id         TEXT NOT NULL,
+    payload    TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (scope, id)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE api_keys (
+    id            TEXT PRIMARY KEY,
+    name          TEXT NOT NULL,
+    secret_hash   BLOB NOT NULL,
+    scopes        TEXT NOT NULL,
+    wallet_ids    TEXT NOT NULL,
+    network_codes TEXT NOT NULL,
+    created_at    TEXT NOT NULL,
+    rotated_at    TEXT,
+    revoked_at    TEXT
+);
@@ -0,0 +1,16 @@
+CREATE TABLE audit_log (
+    sequence INTEGER PRIMARY KEY,
+    hash     TEXT NOT NULL,
+    entry    TEXT NOT NULL
+);
+
+-- the log is append-only; the hash chain detects changes made around these
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
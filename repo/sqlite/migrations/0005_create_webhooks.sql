@@ -0,0 +1,18 @@
+CREATE TABLE webhook_subscriptions (
+    id          TEXT PRIMARY KEY,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    event_types TEXT NOT NULL,
+    created_at  TEXT NOT NULL
+);
+
+CREATE TABLE webhook_dead_letters (
+    id               TEXT PRIMARY KEY,
+    subscription_id  TEXT NOT NULL,
+    event_type       TEXT NOT NULL,
+    body             BLOB NOT NULL,
+    attempts         INTEGER NOT NULL,
+    last_error       TEXT NOT NULL,
+    created_at       TEXT NOT NULL,
+    dead_lettered_at TEXT
+);
@@ -0,0 +1,4 @@
+-- Rewrites stored addresses to the form domain.NormalizeAddress returns, e.g.
+-- EIP-55 checksums for EVM addresses. The checksum needs Keccak-256, which
+-- SQLite lacks, so the rows are rewritten by normalizeAddresses once this
+-- file has run.
@@ -0,0 +1,4 @@
+-- Signed requests are checked with the public key of a signing key derived
+-- from the secret, so that the stored columns cannot sign. The keys stored
+-- before have none and accept bearer tokens only until they are rotated.
+ALTER TABLE api_keys ADD COLUMN verify_key BLOB;
@@ -0,0 +1,6 @@
+-- Signed requests are HMACs with a key derived from the secret, stored
+-- encrypted under a master key kept out of the database. The public keys
+-- stored before cannot check them, so those keys accept bearer tokens only
+-- until they are rotated.
+ALTER TABLE api_keys RENAME COLUMN verify_key TO signing_key;
+UPDATE api_keys SET signing_key = NULL;
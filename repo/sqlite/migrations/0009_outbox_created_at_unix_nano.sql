@@ -0,0 +1,6 @@
+-- Stores the creation time of outbox entries as Unix nanoseconds, so that
+-- entries are listed in the order they were created. The RFC 3339 text stored
+-- before drops the trailing zeros of the fraction, so it does not sort by
+-- time. The text is converted by convertOutboxCreatedAt once this file has
+-- run, which then replaces the text column.
+ALTER TABLE outbox ADD COLUMN created_at_unix_nano INTEGER NOT NULL DEFAULT 0;
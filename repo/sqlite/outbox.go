@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+var _ transaction.Outbox = (*Outbox)(nil)
+
+// Outbox stores the outbox entries of one transferor. Transferors sharing a
+// database use distinct scopes, since each Rebroadcaster only knows how to
+// send the entries of its own network.
+type Outbox struct {
+	db    *DB
+	scope string
+}
+
+func NewOutbox(db *DB, scope string) *Outbox {
+	return &Outbox{
+		db:    db,
+		scope: scope,
+	}
+}
+
+func (outbox *Outbox) Put(ctx context.Context, entry *transaction.OutboxEntry) error {
+	content, err := json.Marshal(entry.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshall outbox entry: %w", err)
+	}
+
+	_, err = outbox.db.ExecContext(ctx,
+		`INSERT INTO outbox (scope, id, payload, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (scope, id) DO UPDATE SET payload = excluded.payload`,
+		outbox.scope, entry.Payload.ID, string(content), entry.CreatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to write outbox entry (%s): %w", entry.Payload.ID, err)
+	}
+
+	return nil
+}
+
+func (outbox *Outbox) Remove(ctx context.Context, id string) error {
+	_, err := outbox.db.ExecContext(ctx, "DELETE FROM outbox WHERE scope = ? AND id = ?", outbox.scope, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove outbox entry (%s): %w", id, err)
+	}
+
+	return nil
+}
+
+func (outbox *Outbox) List(ctx context.Context) ([]*transaction.OutboxEntry, error) {
+	rows, err := outbox.db.QueryContext(ctx,
+		"SELECT payload, created_at FROM outbox WHERE scope = ? ORDER BY created_at, id", outbox.scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*transaction.OutboxEntry
+
+	for rows.Next() {
+		var (
+			content   string
+			createdAt int64
+		)
+
+		if err := rows.Scan(&content, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to list outbox entries: %w", err)
+		}
+
+		entry := &transaction.OutboxEntry{}
+
+		if err := json.Unmarshal([]byte(content), &entry.Payload); err != nil {
+			return nil, fmt.Errorf("failed to parse outbox entry: %w", err)
+		}
+
+		entry.CreatedAt = time.Unix(0, createdAt).UTC()
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list outbox entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// convertOutboxCreatedAt fills created_at_unix_nano from the RFC 3339 text of
+// created_at, and then puts the number in place of the text.
+func convertOutboxCreatedAt(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "SELECT scope, id, created_at FROM outbox")
+	if err != nil {
+
+		return err
+	}
+
+	type outboxKey struct {
+		scope string
+		id    string
+	}
+
+	createdAts := make(map[outboxKey]time.Time)
+
+	for rows.Next() {
+		var key outboxKey
+		var createdAt string
+
+		if err := rows.Scan(&key.scope, &key.id, &createdAt); err != nil {
+			rows.Close()
+
+			return err
+		}
+
+		parsed, err := time.Parse(time.RFC3339Nano, createdAt)
+		if err != nil {
+			rows.Close()
+
+			return fmt.Errorf("outbox entry %s of %s: %w", key.id, key.scope, err)
+		}
+
+		createdAts[key] = parsed
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for key, createdAt := range createdAts {
+		_, err := tx.ExecContext(ctx, "UPDATE outbox SET created_at_unix_nano = ? WHERE scope = ? AND id = ?",
+			createdAt.UnixNano(), key.scope, key.id)
+		if err != nil {
+
+			return err
+		}
+	}
+
+	for _, statement := range []string{
+		"ALTER TABLE outbox DROP COLUMN created_at",
+		"ALTER TABLE outbox RENAME COLUMN created_at_unix_nano TO created_at",
+	} {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+
+			return err
+		}
+	}
+
+	return nil
+}
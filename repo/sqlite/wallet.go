@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+var _ domain.WalletRepo = (*WalletRepo)(nil)
+
+type WalletRepo struct {
+	db *DB
+}
+
+func NewWalletRepo(db *DB) *WalletRepo {
+	return &WalletRepo{
+		db: db,
+	}
+}
+
+func (repo *WalletRepo) CreateWallet(ctx context.Context, cwp *domain.CreateWalletPayload) (*domain.Wallet, error) {
+	var wallet *domain.Wallet
+
+	err := repo.db.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		wallet, err = insertWallet(ctx, tx, cwp)
+
+		return err
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+func (repo *WalletRepo) GetWallet(ctx context.Context, walletID uuid.UUID) (*domain.Wallet, error) {
+	wallet := &domain.Wallet{}
+
+	err := repo.db.QueryRowContext(ctx, "SELECT id, provider_id FROM wallets WHERE id = ?", walletID.String()).
+		Scan(&wallet.ID, &wallet.ProviderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.WalletNotFoundError{WalletID: walletID}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve wallet (%s): %w", walletID, err)
+	}
+
+	return wallet, nil
+}
+
+func (repo *WalletRepo) GetWallets(ctx context.Context) ([]*domain.Wallet, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT id, provider_id FROM wallets ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve wallets: %w", err)
+	}
+	defer rows.Close()
+
+	var wallets []*domain.Wallet
+
+	for rows.Next() {
+		wallet := &domain.Wallet{}
+
+		if err := rows.Scan(&wallet.ID, &wallet.ProviderID); err != nil {
+			return nil, fmt.Errorf("failed to retrieve wallets: %w", err)
+		}
+
+		wallets = append(wallets, wallet)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve wallets: %w", err)
+	}
+
+	return wallets, nil
+}
+
+func insertWallet(ctx context.Context, tx *sql.Tx, cwp *domain.CreateWalletPayload) (*domain.Wallet, error) {
+	walletID := cwp.ID
+	if walletID == uuid.Nil {
+		walletID = uuid.Must(uuid.NewV7())
+	}
+
+	var existing int
+
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM wallets WHERE id = ?", walletID.String()).Scan(&existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check wallet (%s): %w", walletID, err)
+	}
+
+	if existing > 0 {
+		return nil, domain.WalletAlreadyExistsError{WalletID: walletID}
+	}
+
+	wallet := &domain.Wallet{
+		ID:         walletID,
+		ProviderID: cwp.ProviderID,
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO wallets (id, provider_id) VALUES (?, ?)",
+		wallet.ID.String(), wallet.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet (%s): %w", wallet.ID, err)
+	}
+
+	return wallet, nil
+}
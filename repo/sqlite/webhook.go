@@ -0,0 +1,264 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+const (
+	selectWebhookSubscription = "SELECT id, url, secret, event_types, created_at FROM webhook_subscriptions"
+	selectWebhookDeadLetter   = `SELECT id, subscription_id, event_type, body, attempts, last_error,
+	created_at, dead_lettered_at FROM webhook_dead_letters`
+)
+
+var (
+	_ domain.WebhookSubscriptionRepo = (*WebhookSubscriptionRepo)(nil)
+	_ domain.WebhookDeadLetterRepo   = (*WebhookDeadLetterRepo)(nil)
+)
+
+type WebhookSubscriptionRepo struct {
+	db *DB
+}
+
+func NewWebhookSubscriptionRepo(db *DB) *WebhookSubscriptionRepo {
+	return &WebhookSubscriptionRepo{
+		db: db,
+	}
+}
+
+func (repo *WebhookSubscriptionRepo) CreateSubscription(
+	ctx context.Context,
+	cwsp *domain.CreateWebhookSubscriptionPayload,
+) (*domain.WebhookSubscription, error) {
+	subscription := &domain.WebhookSubscription{
+		ID:         uuid.Must(uuid.NewV7()),
+		URL:        cwsp.URL,
+		Secret:     cwsp.Secret,
+		EventTypes: slices.Clone(cwsp.EventTypes),
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	eventTypes, err := json.Marshal(subscription.EventTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription (%s): %w", subscription.ID, err)
+	}
+
+	_, err = repo.db.ExecContext(ctx,
+		"INSERT INTO webhook_subscriptions (id, url, secret, event_types, created_at) VALUES (?, ?, ?, ?, ?)",
+		subscription.ID.String(), subscription.URL, subscription.Secret, string(eventTypes),
+		formatTime(subscription.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription (%s): %w", subscription.ID, err)
+	}
+
+	return subscription, nil
+}
+
+func (repo *WebhookSubscriptionRepo) GetSubscription(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+) (*domain.WebhookSubscription, error) {
+	subscription, err := scanWebhookSubscription(
+		repo.db.QueryRowContext(ctx, selectWebhookSubscription+" WHERE id = ?", subscriptionID.String()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.WebhookSubscriptionNotFoundError{SubscriptionID: subscriptionID}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve webhook subscription (%s): %w", subscriptionID, err)
+	}
+
+	return subscription, nil
+}
+
+func (repo *WebhookSubscriptionRepo) GetSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	return repo.filter(ctx, func(*domain.WebhookSubscription) bool { return true })
+}
+
+func (repo *WebhookSubscriptionRepo) GetSubscriptionsByEventType(
+	ctx context.Context,
+	eventType string,
+) ([]*domain.WebhookSubscription, error) {
+	return repo.filter(ctx, func(subscription *domain.WebhookSubscription) bool {
+		return subscription.Accepts(eventType)
+	})
+}
+
+// filter selects subscriptions in Go, as their event types are stored as a
+// JSON array.
+func (repo *WebhookSubscriptionRepo) filter(
+	ctx context.Context,
+	predicate func(*domain.WebhookSubscription) bool,
+) ([]*domain.WebhookSubscription, error) {
+	rows, err := repo.db.QueryContext(ctx, selectWebhookSubscription+" ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []*domain.WebhookSubscription
+
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve webhook subscriptions: %w", err)
+		}
+
+		if predicate(subscription) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+type WebhookDeadLetterRepo struct {
+	db *DB
+}
+
+func NewWebhookDeadLetterRepo(db *DB) *WebhookDeadLetterRepo {
+	return &WebhookDeadLetterRepo{
+		db: db,
+	}
+}
+
+func (repo *WebhookDeadLetterRepo) PutDeadLetter(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	var deadLetteredAt sql.NullString
+	if delivery.DeadLetteredAt != nil {
+		deadLetteredAt = sql.NullString{String: formatTime(*delivery.DeadLetteredAt), Valid: true}
+	}
+
+	_, err := repo.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO webhook_dead_letters (id, subscription_id, event_type, body, attempts,
+		last_error, created_at, dead_lettered_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.ID.String(), delivery.SubscriptionID.String(), delivery.EventType, []byte(delivery.Body),
+		delivery.Attempts, delivery.LastError, formatTime(delivery.CreatedAt), deadLetteredAt)
+	if err != nil {
+		return fmt.Errorf("failed to store dead letter (%s): %w", delivery.ID, err)
+	}
+
+	return nil
+}
+
+func (repo *WebhookDeadLetterRepo) GetDeadLetters(ctx context.Context) ([]*domain.WebhookDelivery, error) {
+	rows, err := repo.db.QueryContext(ctx, selectWebhookDeadLetter+" ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve dead letters: %w", err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve dead letters: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (repo *WebhookDeadLetterRepo) TakeDeadLetter(
+	ctx context.Context,
+	deliveryID uuid.UUID,
+) (*domain.WebhookDelivery, error) {
+	var delivery *domain.WebhookDelivery
+
+	err := repo.db.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+
+		delivery, err = scanWebhookDelivery(
+			tx.QueryRowContext(ctx, selectWebhookDeadLetter+" WHERE id = ?", deliveryID.String()))
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.WebhookDeliveryNotFoundError{DeliveryID: deliveryID}
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to retrieve dead letter (%s): %w", deliveryID, err)
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM webhook_dead_letters WHERE id = ?", deliveryID.String())
+		if err != nil {
+			return fmt.Errorf("failed to remove dead letter (%s): %w", deliveryID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func scanWebhookSubscription(row scanner) (*domain.WebhookSubscription, error) {
+	subscription := &domain.WebhookSubscription{}
+
+	var eventTypes, createdAt string
+
+	err := row.Scan(&subscription.ID, &subscription.URL, &subscription.Secret, &eventTypes, &createdAt)
+	if err != nil {
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(eventTypes), &subscription.EventTypes); err != nil {
+		return nil, err
+	}
+
+	if subscription.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func scanWebhookDelivery(row scanner) (*domain.WebhookDelivery, error) {
+	delivery := &domain.WebhookDelivery{}
+
+	var (
+		body           []byte
+		createdAt      string
+		deadLetteredAt sql.NullString
+	)
+
+	err := row.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.EventType, &body, &delivery.Attempts,
+		&delivery.LastError, &createdAt, &deadLetteredAt)
+	if err != nil {
+
+		return nil, err
+	}
+
+	delivery.Body = body
+
+	if delivery.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, err
+	}
+
+	if delivery.DeadLetteredAt, err = parseNullTime(deadLetteredAt); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"github.com/google/uuid"
@@ -35,7 +36,9 @@ func (repo *WalletRepo) CreateWallet(_ context.Context, cwp *domain.CreateWallet
 		ProviderID: cwp.ProviderID,
 	}
 
-	repo.storage.Store(wallet.ID, wallet)
+	if _, loaded := repo.storage.LoadOrStore(wallet.ID, wallet); loaded {
+		return nil, domain.WalletAlreadyExistsError{WalletID: wallet.ID}
+	}
 
 	return wallet, nil
 }
@@ -53,3 +56,24 @@ func (repo *WalletRepo) GetWallet(_ context.Context, walletID uuid.UUID) (*domai
 
 	return walletTyped, nil
 }
+
+func (repo *WalletRepo) GetWallets(_ context.Context) ([]*domain.Wallet, error) {
+	var wallets []*domain.Wallet
+
+	repo.storage.Range(func(_, value interface{}) bool {
+		wallet, ok := value.(*domain.Wallet)
+		if !ok {
+			return false
+		}
+
+		wallets = append(wallets, wallet)
+
+		return true
+	})
+
+	sort.Slice(wallets, func(i, j int) bool {
+		return wallets[i].ID.String() < wallets[j].ID.String()
+	})
+
+	return wallets, nil
+}
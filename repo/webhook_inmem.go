@@ -0,0 +1,161 @@
+package repo
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/domain"
+)
+
+var _ domain.WebhookSubscriptionRepo = (*WebhookSubscriptionRepo)(nil)
+
+type WebhookSubscriptionRepo struct {
+	storage sync.Map
+}
+
+func NewWebhookSubscriptionRepo() *WebhookSubscriptionRepo {
+	return &WebhookSubscriptionRepo{
+		storage: sync.Map{},
+	}
+}
+
+func (repo *WebhookSubscriptionRepo) CreateSubscription(
+	_ context.Context,
+	cwsp *domain.CreateWebhookSubscriptionPayload,
+) (*domain.WebhookSubscription, error) {
+	eventTypes := make([]string, len(cwsp.EventTypes))
+	copy(eventTypes, cwsp.EventTypes)
+
+	subscription := &domain.WebhookSubscription{
+		ID:         uuid.Must(uuid.NewV7()),
+		URL:        cwsp.URL,
+		Secret:     cwsp.Secret,
+		EventTypes: eventTypes,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	repo.storage.Store(subscription.ID, subscription)
+
+	return subscription, nil
+}
+
+func (repo *WebhookSubscriptionRepo) GetSubscription(
+	_ context.Context,
+	subscriptionID uuid.UUID,
+) (*domain.WebhookSubscription, error) {
+	subscription, okLoad := repo.storage.Load(subscriptionID)
+	if !okLoad {
+		return nil, domain.WebhookSubscriptionNotFoundError{SubscriptionID: subscriptionID}
+	}
+
+	subscriptionTyped, ok := subscription.(*domain.WebhookSubscription)
+	if !ok {
+		return nil, domain.WebhookSubscriptionNotFoundError{SubscriptionID: subscriptionID}
+	}
+
+	return subscriptionTyped, nil
+}
+
+func (repo *WebhookSubscriptionRepo) GetSubscriptions(
+	_ context.Context,
+) ([]*domain.WebhookSubscription, error) {
+	return repo.filter(func(*domain.WebhookSubscription) bool { return true }), nil
+}
+
+func (repo *WebhookSubscriptionRepo) GetSubscriptionsByEventType(
+	_ context.Context,
+	eventType string,
+) ([]*domain.WebhookSubscription, error) {
+	return repo.filter(func(subscription *domain.WebhookSubscription) bool {
+		return subscription.Accepts(eventType)
+	}), nil
+}
+
+func (repo *WebhookSubscriptionRepo) filter(
+	predicate func(*domain.WebhookSubscription) bool,
+) []*domain.WebhookSubscription {
+	var subscriptions []*domain.WebhookSubscription
+
+	repo.storage.Range(func(_, value interface{}) bool {
+		subscription, ok := value.(*domain.WebhookSubscription)
+		if !ok {
+			return false
+		}
+
+		if predicate(subscription) {
+			subscriptions = append(subscriptions, subscription)
+		}
+
+		return true
+	})
+
+	// UUIDv7 IDs are time ordered, so sorting by ID keeps creation order.
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].ID.String() < subscriptions[j].ID.String()
+	})
+
+	return subscriptions
+}
+
+var _ domain.WebhookDeadLetterRepo = (*WebhookDeadLetterRepo)(nil)
+
+type WebhookDeadLetterRepo struct {
+	mu          sync.Mutex
+	deadLetters map[uuid.UUID]*domain.WebhookDelivery
+}
+
+func NewWebhookDeadLetterRepo() *WebhookDeadLetterRepo {
+	return &WebhookDeadLetterRepo{
+		deadLetters: make(map[uuid.UUID]*domain.WebhookDelivery),
+	}
+}
+
+func (repo *WebhookDeadLetterRepo) PutDeadLetter(_ context.Context, delivery *domain.WebhookDelivery) error {
+	stored := *delivery
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.deadLetters[stored.ID] = &stored
+
+	return nil
+}
+
+func (repo *WebhookDeadLetterRepo) GetDeadLetters(_ context.Context) ([]*domain.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	deliveries := make([]*domain.WebhookDelivery, 0, len(repo.deadLetters))
+
+	for _, delivery := range repo.deadLetters {
+		stored := *delivery
+		deliveries = append(deliveries, &stored)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+
+	return deliveries, nil
+}
+
+func (repo *WebhookDeadLetterRepo) TakeDeadLetter(
+	_ context.Context,
+	deliveryID uuid.UUID,
+) (*domain.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delivery, ok := repo.deadLetters[deliveryID]
+	if !ok {
+		return nil, domain.WebhookDeliveryNotFoundError{DeliveryID: deliveryID}
+	}
+
+	delete(repo.deadLetters, deliveryID)
+
+	return delivery, nil
+}
@@ -0,0 +1,103 @@
+package sse
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultKeepAlive = 15 * time.Second
+
+	headerLastEventID = "Last-Event-ID"
+)
+
+// Handler streams hub events to the requesting client. Clients may filter with
+// repeated "transaction_id" and "address" query parameters, and resume with
+// the Last-Event-ID header (or "last_event_id" query parameter, which
+// EventSource can set on its first connection). permits, if set, returns the
+// Filter.Permits of the client of the request.
+func (hub *Hub) Handler(keepAlive time.Duration, permits func(req *http.Request) func(event *Event) bool) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		flusher, ok := resp.(http.Flusher)
+		if !ok {
+			http.Error(resp, "streaming not supported", http.StatusInternalServerError)
+
+			return
+		}
+
+		lastEventID, err := parseLastEventID(req)
+		if err != nil {
+			http.Error(resp, "invalid last event id", http.StatusBadRequest)
+
+			return
+		}
+
+		query := req.URL.Query()
+		filter := Filter{
+			TransactionIDs: query["transaction_id"],
+			Addresses:      query["address"],
+		}
+
+		if permits != nil {
+			filter.Permits = permits(req)
+		}
+
+		client := hub.Subscribe(filter, lastEventID)
+		defer hub.Unsubscribe(client)
+
+		resp.Header().Set("Access-Control-Allow-Origin", "*")
+		resp.Header().Set("Content-Type", "text/event-stream")
+		resp.Header().Set("Cache-Control", "no-cache")
+		resp.Header().Set("Connection", "keep-alive")
+		resp.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, errW := fmt.Fprint(resp, ": keep-alive\n\n"); errW != nil {
+					return
+				}
+
+				flusher.Flush()
+			case event, rok := <-client.Events():
+				if !rok {
+					slog.Log(ctx, slog.LevelWarn, "sse client evicted for falling behind")
+
+					return
+				}
+
+				if _, errW := fmt.Fprintf(resp, "id: %d\ndata: %s\n\n", event.ID, event.Data); errW != nil {
+					slog.Log(ctx, slog.LevelError, "error writing response:", "err", errW)
+
+					return
+				}
+
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func parseLastEventID(req *http.Request) (uint64, error) {
+	value := req.Header.Get(headerLastEventID)
+	if value == "" {
+		value = req.URL.Query().Get("last_event_id")
+	}
+
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(value, 10, 64)
+}
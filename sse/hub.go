@@ -0,0 +1,198 @@
+package sse
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultHistorySize  = 256
+	DefaultClientBuffer = 32
+)
+
+type Event struct {
+	ID            uint64
+	TransactionID string
+	Addresses     []string
+	// WalletID and NetworkCode are those of the transfer, for Filter.Permits.
+	WalletID    string
+	NetworkCode string
+	Data        json.RawMessage
+}
+
+// Filter narrows the events delivered to a client. Empty fields match
+// everything; addresses are compared case-insensitively so that checksummed
+// and lowercase EVM addresses are treated alike.
+type Filter struct {
+	TransactionIDs []string
+	Addresses      []string
+	// Permits, if set, drops the events it returns false for, e.g. those of
+	// the wallets the client may not see.
+	Permits func(event *Event) bool
+}
+
+func (filter Filter) Matches(event *Event) bool {
+	if filter.Permits != nil && !filter.Permits(event) {
+		return false
+	}
+
+	if len(filter.TransactionIDs) > 0 && !containsFold(filter.TransactionIDs, event.TransactionID) {
+		return false
+	}
+
+	if len(filter.Addresses) == 0 {
+		return true
+	}
+
+	for _, address := range event.Addresses {
+		if containsFold(filter.Addresses, address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Client struct {
+	filter Filter
+	events chan *Event
+	once   sync.Once
+}
+
+// Events is closed when the client falls too far behind and is evicted by the
+// hub; it should reconnect with the last received event ID.
+func (client *Client) Events() <-chan *Event {
+	return client.events
+}
+
+func (client *Client) close() {
+	client.once.Do(func() {
+		close(client.events)
+	})
+}
+
+// Hub fans published events out to every subscribed client. It keeps the most
+// recent events in a ring buffer for Last-Event-ID replay, and Publish never
+// blocks: a client whose buffer is full is disconnected instead.
+type Hub struct {
+	mutex        sync.Mutex
+	clients      map[*Client]struct{}
+	history      []*Event
+	next         int
+	lastID       uint64
+	clientBuffer int
+}
+
+func NewHub(historySize int, clientBuffer int) *Hub {
+	return &Hub{
+		clients:      make(map[*Client]struct{}),
+		history:      make([]*Event, 0, historySize),
+		clientBuffer: clientBuffer,
+	}
+}
+
+func (hub *Hub) Publish(event *Event) *Event {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.lastID++
+
+	published := *event
+	published.ID = hub.lastID
+
+	hub.remember(&published)
+
+	for client := range hub.clients {
+		if !client.filter.Matches(&published) {
+			continue
+		}
+
+		select {
+		case client.events <- &published:
+		default:
+			delete(hub.clients, client)
+			client.close()
+		}
+	}
+
+	return &published
+}
+
+// Subscribe registers a client. Retained events newer than lastEventID that
+// match the filter are queued first; pass 0 to skip replay.
+func (hub *Hub) Subscribe(filter Filter, lastEventID uint64) *Client {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	var replay []*Event
+
+	if lastEventID > 0 {
+		for _, event := range hub.retained() {
+			if event.ID > lastEventID && filter.Matches(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	buffer := hub.clientBuffer
+	if len(replay) > buffer {
+		buffer = len(replay)
+	}
+
+	client := &Client{
+		filter: filter,
+		events: make(chan *Event, buffer),
+	}
+
+	for _, event := range replay {
+		client.events <- event
+	}
+
+	hub.clients[client] = struct{}{}
+
+	return client
+}
+
+func (hub *Hub) Unsubscribe(client *Client) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if _, ok := hub.clients[client]; ok {
+		delete(hub.clients, client)
+		client.close()
+	}
+}
+
+func (hub *Hub) remember(event *Event) {
+	if cap(hub.history) == 0 {
+		return
+	}
+
+	if len(hub.history) < cap(hub.history) {
+		hub.history = append(hub.history, event)
+
+		return
+	}
+
+	hub.history[hub.next] = event
+	hub.next = (hub.next + 1) % len(hub.history)
+}
+
+func (hub *Hub) retained() []*Event {
+	events := make([]*Event, 0, len(hub.history))
+	events = append(events, hub.history[hub.next:]...)
+	events = append(events, hub.history[:hub.next]...)
+
+	return events
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
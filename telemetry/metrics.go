@@ -0,0 +1,292 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultDurationBuckets suit operations from a millisecond to half a minute,
+// e.g. RPC calls and HTTP requests, in seconds.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// DefaultRegistry holds the metrics of all packages and is served by Handler.
+var DefaultRegistry = NewRegistry()
+
+type metric interface {
+	write(writer io.Writer) error
+}
+
+// Registry collects metrics and writes them in the Prometheus text format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]metric),
+	}
+}
+
+func (registry *Registry) register(name string, m metric) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.metrics[name]; ok {
+		panic("telemetry: metric registered twice: " + name)
+	}
+
+	registry.metrics[name] = m
+}
+
+func (registry *Registry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	counter := &Counter{
+		desc:   desc{name: name, help: help, labelNames: labelNames},
+		values: make(map[string]*counterValue),
+	}
+
+	registry.register(name, counter)
+
+	return counter
+}
+
+func (registry *Registry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	histogram := &Histogram{
+		desc:    desc{name: name, help: help, labelNames: labelNames},
+		buckets: append([]float64(nil), buckets...),
+		values:  make(map[string]*histogramValue),
+	}
+
+	sort.Float64s(histogram.buckets)
+	registry.register(name, histogram)
+
+	return histogram
+}
+
+func (registry *Registry) Write(writer io.Writer) error {
+	registry.mu.Lock()
+
+	names := make([]string, 0, len(registry.metrics))
+	for name := range registry.metrics {
+		names = append(names, name)
+	}
+
+	metrics := make([]metric, len(names))
+
+	sort.Strings(names)
+
+	for index, name := range names {
+		metrics[index] = registry.metrics[name]
+	}
+
+	registry.mu.Unlock()
+
+	for _, m := range metrics {
+		if err := m.write(writer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handler serves the registry for Prometheus to scrape.
+func (registry *Registry) Handler() http.HandlerFunc {
+	return func(resp http.ResponseWriter, _ *http.Request) {
+		resp.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		_ = registry.Write(resp)
+	}
+}
+
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+func (d *desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("telemetry: %s takes %d label values, got %d", d.name, len(d.labelNames), len(labelValues)))
+	}
+
+	return strings.Join(labelValues, "\xff")
+}
+
+func (d *desc) writeHeader(writer io.Writer, metricType string) error {
+	_, err := fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, metricType)
+
+	return err
+}
+
+// labels formats the label set of a sample, with extra pairs such as le.
+func (d *desc) labels(labelValues []string, extra ...string) string {
+	pairs := make([]string, 0, len(labelValues)+len(extra)/2)
+
+	for index, value := range labelValues {
+		pairs = append(pairs, d.labelNames[index]+"="+strconv.Quote(value))
+	}
+
+	for index := 0; index+1 < len(extra); index += 2 {
+		pairs = append(pairs, extra[index]+"="+strconv.Quote(extra[index+1]))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a monotonically increasing value per label set.
+type Counter struct {
+	desc
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+func (counter *Counter) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+func (counter *Counter) Add(delta float64, labelValues ...string) {
+	key := counter.key(labelValues)
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	value, ok := counter.values[key]
+	if !ok {
+		value = &counterValue{labelValues: append([]string(nil), labelValues...)}
+		counter.values[key] = value
+	}
+
+	value.value += delta
+}
+
+func (counter *Counter) write(writer io.Writer) error {
+	if err := counter.writeHeader(writer, "counter"); err != nil {
+		return err
+	}
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	for _, key := range sortedKeys(counter.values) {
+		value := counter.values[key]
+
+		_, err := fmt.Fprintf(writer, "%s%s %s\n", counter.name, counter.labels(value.labelValues), formatFloat(value.value))
+		if err != nil {
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Histogram counts observations into cumulative buckets per label set.
+type Histogram struct {
+	desc
+
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+func (histogram *Histogram) Observe(observed float64, labelValues ...string) {
+	key := histogram.key(labelValues)
+
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	value, ok := histogram.values[key]
+	if !ok {
+		value = &histogramValue{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(histogram.buckets)),
+		}
+		histogram.values[key] = value
+	}
+
+	for index, bound := range histogram.buckets {
+		if observed <= bound {
+			value.counts[index]++
+		}
+	}
+
+	value.count++
+	value.sum += observed
+}
+
+func (histogram *Histogram) write(writer io.Writer) error {
+	if err := histogram.writeHeader(writer, "histogram"); err != nil {
+		return err
+	}
+
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	for _, key := range sortedKeys(histogram.values) {
+		value := histogram.values[key]
+
+		for index, bound := range histogram.buckets {
+			_, err := fmt.Fprintf(writer, "%s_bucket%s %d\n", histogram.name,
+				histogram.labels(value.labelValues, "le", formatFloat(bound)), value.counts[index])
+			if err != nil {
+
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintf(writer, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			histogram.name, histogram.labels(value.labelValues, "le", "+Inf"), value.count,
+			histogram.name, histogram.labels(value.labelValues), formatFloat(value.sum),
+			histogram.name, histogram.labels(value.labelValues), value.count)
+		if err != nil {
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,111 @@
+package telemetry
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderTraceparent = "traceparent"
+
+	traceparentVersion = "00"
+	traceparentSampled = "01"
+)
+
+// ParseTraceparent reads a W3C trace context header, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || parts[0] != traceparentVersion {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != len(sc.TraceID) {
+		return SpanContext{}, false
+	}
+
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != len(sc.SpanID) {
+		return SpanContext{}, false
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+
+	return sc, sc.IsValid()
+}
+
+func FormatTraceparent(sc SpanContext) string {
+	return strings.Join([]string{traceparentVersion, sc.TraceID.String(), sc.SpanID.String(), traceparentSampled}, "-")
+}
+
+var httpRequestDuration = DefaultRegistry.NewHistogram(
+	"http_server_request_duration_seconds",
+	"Duration of HTTP requests by route and status code.",
+	DefaultDurationBuckets,
+	"method", "route", "status",
+)
+
+// Handler traces the requests of one route and measures their duration. The
+// route, rather than the request path, labels the metrics so that path
+// parameters do not create a series per value. A traceparent header on the
+// request makes the span a child of the caller's span.
+func Handler(route string, next http.Handler) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		if parent, ok := ParseTraceparent(req.Header.Get(HeaderTraceparent)); ok {
+			ctx = ContextWithRemoteParent(ctx, parent)
+		}
+
+		ctx, span := Start(ctx, "HTTP "+route,
+			String("http.method", req.Method),
+			String("http.route", route),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: resp, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(recorder, req.WithContext(ctx))
+
+		status := strconv.Itoa(recorder.status)
+		span.SetAttributes(String("http.status_code", status))
+		httpRequestDuration.Observe(time.Since(start).Seconds(), req.Method, route, status)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(content []byte) (int, error) {
+	recorder.wroteHeader = true
+
+	return recorder.ResponseWriter.Write(content)
+}
+
+// Flush lets streaming handlers, such as server-sent events, flush through
+// the recorder.
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
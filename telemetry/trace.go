@@ -0,0 +1,229 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is one timed operation of a trace. A span is safe for use by
+// concurrent goroutines; it is exported once, when End is first called.
+type Span struct {
+	Name        string
+	SpanContext SpanContext
+	ParentID    SpanID
+	StartTime   time.Time
+
+	mu         sync.Mutex
+	endTime    time.Time
+	attributes []Attribute
+	err        error
+	ended      bool
+}
+
+func (span *Span) SetAttributes(attributes ...Attribute) {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	span.attributes = append(span.attributes, attributes...)
+}
+
+// RecordError marks the span as failed. A nil error is ignored, so the result
+// of an operation can be recorded unconditionally.
+func (span *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	span.err = err
+}
+
+func (span *Span) End() {
+	span.mu.Lock()
+
+	if span.ended {
+		span.mu.Unlock()
+
+		return
+	}
+
+	span.ended = true
+	span.endTime = time.Now()
+	span.mu.Unlock()
+
+	if exporter := loadExporter(); exporter != nil {
+		exporter.ExportSpan(span)
+	}
+}
+
+func (span *Span) Attributes() []Attribute {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	return append([]Attribute(nil), span.attributes...)
+}
+
+func (span *Span) Err() error {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	return span.err
+}
+
+func (span *Span) Duration() time.Duration {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	if !span.ended {
+		return time.Since(span.StartTime)
+	}
+
+	return span.endTime.Sub(span.StartTime)
+}
+
+// SpanExporter receives every span when it ends. Implementations must not
+// block, as spans end inline with the traced operation.
+type SpanExporter interface {
+	ExportSpan(span *Span)
+}
+
+type exporterHolder struct {
+	exporter SpanExporter
+}
+
+var globalExporter atomic.Pointer[exporterHolder]
+
+// SetExporter sets the exporter of all spans. Without an exporter spans are
+// still created, so that trace context keeps propagating, but are dropped.
+func SetExporter(exporter SpanExporter) {
+	globalExporter.Store(&exporterHolder{exporter: exporter})
+}
+
+func loadExporter() SpanExporter {
+	holder := globalExporter.Load()
+	if holder == nil {
+		return nil
+	}
+
+	return holder.exporter
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// Start begins a span as a child of the span in ctx, or of a remote parent
+// set with ContextWithRemoteParent, or else as the root of a new trace.
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	span := &Span{
+		Name:       name,
+		StartTime:  time.Now(),
+		attributes: attributes,
+	}
+
+	var parent SpanContext
+
+	if parentSpan := SpanFromContext(ctx); parentSpan != nil {
+		parent = parentSpan.SpanContext
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		parent = remote
+	}
+
+	if parent.IsValid() {
+		span.SpanContext.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else {
+		_, _ = rand.Read(span.SpanContext.TraceID[:])
+	}
+
+	_, _ = rand.Read(span.SpanContext.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+
+	return span
+}
+
+// ContextWithRemoteParent makes spans started from ctx continue a trace that
+// began in another process.
+func ContextWithRemoteParent(ctx context.Context, parent SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, parent)
+}
+
+// LogExporter writes finished spans to slog.
+type LogExporter struct {
+	Level slog.Level
+}
+
+var _ SpanExporter = (*LogExporter)(nil)
+
+func (exporter *LogExporter) ExportSpan(span *Span) {
+	args := []any{
+		"trace_id", span.SpanContext.TraceID.String(),
+		"span_id", span.SpanContext.SpanID.String(),
+		"duration", span.Duration(),
+	}
+
+	if span.ParentID.IsValid() {
+		args = append(args, "parent_id", span.ParentID.String())
+	}
+
+	for _, attribute := range span.Attributes() {
+		args = append(args, attribute.Key, attribute.Value)
+	}
+
+	if err := span.Err(); err != nil {
+		args = append(args, "err", err)
+	}
+
+	slog.Log(context.Background(), exporter.Level, "span "+span.Name, args...)
+}
@@ -0,0 +1,297 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+	"github.com/ivxivx/demo-blockchain/domain"
+	"github.com/ivxivx/demo-blockchain/telemetry"
+)
+
+const (
+	DefaultMaxAttempts    = 5
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute
+	DefaultRequestTimeout = 10 * time.Second
+)
+
+// Delivery is one event sent to one subscription.
+type Delivery = domain.WebhookDelivery
+
+// DispatcherClosedError reports a delivery that arrived after Close. It is
+// dead-lettered rather than sent.
+type DispatcherClosedError struct{}
+
+func (e DispatcherClosedError) Error() string {
+	return "webhook dispatcher is closed"
+}
+
+type Config struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	RequestTimeout time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MaxAttempts:    DefaultMaxAttempts,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+		RequestTimeout: DefaultRequestTimeout,
+	}
+}
+
+// Dispatcher delivers transfer lifecycle events to webhook subscriptions.
+// Deliveries are retried with exponential backoff and moved to the dead-letter
+// repo once Config.MaxAttempts is exhausted, from where they can be replayed.
+type Dispatcher struct {
+	subscriptionRepo domain.WebhookSubscriptionRepo
+	deadLetterRepo   domain.WebhookDeadLetterRepo
+	client           *http.Client
+	config           Config
+
+	// mutex orders start against Close, so that no attempt is added to the
+	// wait group once Close waits on it.
+	mutex  sync.Mutex
+	closed bool
+
+	waitGroup sync.WaitGroup
+	done      chan struct{}
+}
+
+var _ transaction.Notifier = (*Dispatcher)(nil)
+
+func NewDispatcher(
+	subscriptionRepo domain.WebhookSubscriptionRepo,
+	deadLetterRepo domain.WebhookDeadLetterRepo,
+	config Config,
+) *Dispatcher {
+	return &Dispatcher{
+		subscriptionRepo: subscriptionRepo,
+		deadLetterRepo:   deadLetterRepo,
+		client:           &http.Client{Timeout: config.RequestTimeout},
+		config:           config,
+		done:             make(chan struct{}),
+	}
+}
+
+func (dispatcher *Dispatcher) Notify(ctx context.Context, event *transaction.Event) {
+	subscriptions, err := dispatcher.subscriptionRepo.GetSubscriptionsByEventType(ctx, string(event.Type))
+	if err != nil {
+		slog.Log(ctx, slog.LevelError, "failed to retrieve webhook subscriptions:", "err", err)
+
+		return
+	}
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(NewMessage(event))
+	if err != nil {
+		slog.Log(ctx, slog.LevelError, "failed to marshall webhook message:", "err", err)
+
+		return
+	}
+
+	// the span that emitted the event has ended by the time the delivery is
+	// sent, so the attempts link to it
+	var trace telemetry.SpanContext
+	if span := telemetry.SpanFromContext(ctx); span != nil {
+		trace = span.SpanContext
+	}
+
+	for _, subscription := range subscriptions {
+		delivery := &Delivery{
+			ID:             uuid.Must(uuid.NewV7()),
+			SubscriptionID: subscription.ID,
+			EventType:      string(event.Type),
+			Body:           body,
+			CreatedAt:      time.Now().UTC(),
+		}
+
+		if err := dispatcher.start(subscription, delivery, trace); err != nil {
+			delivery.LastError = err.Error()
+			dispatcher.deadLetter(delivery)
+		}
+	}
+}
+
+func (dispatcher *Dispatcher) DeadLetters(ctx context.Context) ([]*Delivery, error) {
+	return dispatcher.deadLetterRepo.GetDeadLetters(ctx)
+}
+
+// Replay takes a dead-lettered delivery out of the repo and schedules it again
+// with a fresh attempt budget.
+func (dispatcher *Dispatcher) Replay(ctx context.Context, deliveryID uuid.UUID) (*Delivery, error) {
+	delivery, err := dispatcher.deadLetterRepo.TakeDeadLetter(ctx, deliveryID)
+	if err != nil {
+
+		return nil, err
+	}
+
+	subscription, err := dispatcher.subscriptionRepo.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		dispatcher.store(delivery)
+
+		return nil, err
+	}
+
+	replayed := *delivery
+	replayed.Attempts = 0
+	replayed.LastError = ""
+	replayed.DeadLetteredAt = nil
+
+	if err := dispatcher.start(subscription, &replayed, telemetry.SpanContext{}); err != nil {
+		dispatcher.store(delivery)
+
+		return nil, err
+	}
+
+	return &replayed, nil
+}
+
+// Close stops scheduling retries and waits for in-flight attempts to finish.
+// Deliveries that arrive afterwards are dead-lettered.
+func (dispatcher *Dispatcher) Close() {
+	dispatcher.mutex.Lock()
+	if !dispatcher.closed {
+		dispatcher.closed = true
+		close(dispatcher.done)
+	}
+	dispatcher.mutex.Unlock()
+
+	dispatcher.waitGroup.Wait()
+}
+
+func (dispatcher *Dispatcher) start(
+	subscription *domain.WebhookSubscription,
+	delivery *Delivery,
+	trace telemetry.SpanContext,
+) error {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	if dispatcher.closed {
+		return DispatcherClosedError{}
+	}
+
+	dispatcher.waitGroup.Add(1)
+
+	go func() {
+		defer dispatcher.waitGroup.Done()
+
+		dispatcher.run(subscription, delivery, trace)
+	}()
+
+	return nil
+}
+
+func (dispatcher *Dispatcher) run(
+	subscription *domain.WebhookSubscription,
+	delivery *Delivery,
+	trace telemetry.SpanContext,
+) {
+	for {
+		err := dispatcher.send(subscription, delivery, trace)
+		delivery.Attempts++
+
+		if err == nil {
+			return
+		}
+
+		delivery.LastError = err.Error()
+
+		slog.Log(context.Background(), slog.LevelWarn, "webhook delivery failed:",
+			"delivery", delivery.ID, "attempt", delivery.Attempts, "err", err)
+
+		if delivery.Attempts >= dispatcher.config.MaxAttempts {
+			dispatcher.deadLetter(delivery)
+
+			return
+		}
+
+		timer := time.NewTimer(dispatcher.backoff(delivery.Attempts))
+
+		select {
+		case <-dispatcher.done:
+			timer.Stop()
+			dispatcher.deadLetter(delivery)
+
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (dispatcher *Dispatcher) send(
+	subscription *domain.WebhookSubscription,
+	delivery *Delivery,
+	trace telemetry.SpanContext,
+) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dispatcher.config.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, delivery.Body))
+
+	if trace.IsValid() {
+		req.Header.Set(telemetry.HeaderTraceparent, telemetry.FormatTraceparent(trace))
+	}
+
+	resp, err := dispatcher.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (dispatcher *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := dispatcher.config.InitialBackoff << (attempts - 1)
+	if backoff <= 0 || backoff > dispatcher.config.MaxBackoff {
+		return dispatcher.config.MaxBackoff
+	}
+
+	return backoff
+}
+
+func (dispatcher *Dispatcher) deadLetter(delivery *Delivery) {
+	now := time.Now().UTC()
+	delivery.DeadLetteredAt = &now
+
+	dispatcher.store(delivery)
+}
+
+func (dispatcher *Dispatcher) store(delivery *Delivery) {
+	if err := dispatcher.deadLetterRepo.PutDeadLetter(context.Background(), delivery); err != nil {
+		slog.Log(context.Background(), slog.LevelError, "failed to store dead-lettered webhook delivery:",
+			"delivery", delivery.ID, "err", err)
+	}
+}
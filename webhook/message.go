@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/ivxivx/demo-blockchain/blockchain/transaction"
+)
+
+// Message mirrors the demo's TransactionUpdatedMessage so that SSE and webhook
+// consumers can share the same decoder, and adds the full transfer details.
+type Message struct {
+	Status               string    `json:"status"`
+	NetworkTransactionID string    `json:"network_transaction_id,omitempty"`
+	Transfer             *Transfer `json:"transfer,omitempty"`
+	Error                string    `json:"error,omitempty"`
+	OccurredAt           time.Time `json:"occurred_at"`
+}
+
+type Transfer struct {
+	SourceAddress      string `json:"source_address"`
+	DestinationAddress string `json:"destination_address"`
+	Amount             string `json:"amount"`
+	NetworkCurrencyID  string `json:"network_currency_id"`
+	SourceWalletID     string `json:"source_wallet_id,omitempty"`
+	ProviderID         string `json:"provider_id,omitempty"`
+	NFT                *NFT   `json:"nft,omitempty"`
+}
+
+type NFT struct {
+	Standard string `json:"standard"`
+	Contract string `json:"contract"`
+	TokenID  string `json:"token_id"`
+	Amount   string `json:"amount"`
+}
+
+func NewMessage(event *transaction.Event) *Message {
+	message := &Message{
+		Status:     string(event.Type),
+		OccurredAt: event.OccurredAt,
+	}
+
+	if event.Err != nil {
+		message.Error = event.Err.Error()
+	}
+
+	if event.Payload == nil {
+		return message
+	}
+
+	message.NetworkTransactionID = event.Payload.ID
+
+	if event.Payload.Req != nil {
+		message.Transfer = &Transfer{
+			SourceAddress:      event.Payload.Req.SourceAddress,
+			DestinationAddress: event.Payload.Req.DestinationAddress,
+			Amount:             event.Payload.Req.Amount.String(),
+			NetworkCurrencyID:  event.Payload.Req.NetworkCurrencyID,
+			SourceWalletID:     event.Payload.SourceWalletID,
+			ProviderID:         event.Payload.ProviderID,
+		}
+
+		if nft := event.Payload.Req.NFT; nft != nil {
+			message.Transfer.NFT = &NFT{
+				Standard: string(nft.Asset.Standard),
+				Contract: nft.Asset.Contract,
+				TokenID:  nft.Asset.TokenID.String(),
+				Amount:   nft.Quantity().String(),
+			}
+		}
+	}
+
+	return message
+}